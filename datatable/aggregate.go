@@ -0,0 +1,193 @@
+package datatable
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// aggregateFunc reduces the non null values of a group; params are the
+// constant arguments following the value, like the 0.9 of
+// percentile(x, 0.9).
+type aggregateFunc struct {
+	params int
+	fn     func(values []any, params []any) any
+}
+
+var aggregates = map[string]aggregateFunc{
+	"count": {0, func(values []any, _ []any) any { return len(values) }},
+	"sum": {0, func(values []any, _ []any) any {
+		sum := 0.0
+		for _, v := range values {
+			n, _ := toNumber(v)
+			sum += n
+		}
+		return sum
+	}},
+	"avg": {0, func(values []any, _ []any) any {
+		sum, count := 0.0, 0
+		for _, v := range values {
+			if n, ok := toNumber(v); ok {
+				sum += n
+				count++
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		return sum / float64(count)
+	}},
+	"min": {0, func(values []any, _ []any) any { return extreme(values, -1) }},
+	"max": {0, func(values []any, _ []any) any { return extreme(values, 1) }},
+	"first": {0, func(values []any, _ []any) any {
+		if len(values) == 0 {
+			return nil
+		}
+		return values[0]
+	}},
+	"last": {0, func(values []any, _ []any) any {
+		if len(values) == 0 {
+			return nil
+		}
+		return values[len(values)-1]
+	}},
+	"distinct_count": {0, func(values []any, _ []any) any {
+		seen := make(map[string]bool, len(values))
+		for _, v := range values {
+			seen[valueKey(v)] = true
+		}
+		return len(seen)
+	}},
+	"percentile": {1, func(values []any, params []any) any {
+		p, ok := toNumber(params[0])
+		if !ok || p < 0 || p > 1 {
+			return nil
+		}
+		return percentile(values, p)
+	}},
+}
+
+// extreme returns the smallest value for sign -1, the largest for 1.
+func extreme(values []any, sign int) any {
+	var res any
+	for _, v := range values {
+		if res == nil || compareTyped(v, res)*sign > 0 {
+			res = v
+		}
+	}
+	return res
+}
+
+// percentile interpolates linearly between the numeric values closest to
+// rank p of them.
+func percentile(values []any, p float64) any {
+	numbers := make([]float64, 0, len(values))
+	for _, v := range values {
+		if n, ok := toNumber(v); ok {
+			numbers = append(numbers, n)
+		}
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+	sort.Float64s(numbers)
+	rank := p * float64(len(numbers)-1)
+	low := math.Floor(rank)
+	high := math.Ceil(rank)
+	return numbers[int(low)] + (numbers[int(high)]-numbers[int(low)])*(rank-low)
+}
+
+// groupRows splits rows by their key, in order of appearance. Keys are
+// compared value by value, so ("a b", "c") and ("a", "b c") are different
+// groups.
+func groupRows(rows []map[string]any, key func(map[string]any) []any) [][]map[string]any {
+	var groups [][]map[string]any
+	index := map[string]int{}
+	for _, row := range rows {
+		values := key(row)
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = valueKey(value)
+		}
+		k := strings.Join(parts, "\x00")
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups
+}
+
+// Aggregation is a column computed over the rows of a group by GroupBy.
+type Aggregation struct {
+	fn     string
+	column string
+	alias  string
+	params []any
+}
+
+// Count counts the rows of a group.
+func Count() Aggregation { return Aggregation{fn: "count"} }
+
+// Sum adds up the numeric values of column.
+func Sum(column string) Aggregation { return Aggregation{fn: "sum", column: column} }
+
+// Avg averages the numeric values of column.
+func Avg(column string) Aggregation { return Aggregation{fn: "avg", column: column} }
+
+// Min returns the smallest value of column.
+func Min(column string) Aggregation { return Aggregation{fn: "min", column: column} }
+
+// Max returns the largest value of column.
+func Max(column string) Aggregation { return Aggregation{fn: "max", column: column} }
+
+// First returns the first non null value of column.
+func First(column string) Aggregation { return Aggregation{fn: "first", column: column} }
+
+// Last returns the last non null value of column.
+func Last(column string) Aggregation { return Aggregation{fn: "last", column: column} }
+
+// DistinctCount counts the different non null values of column.
+func DistinctCount(column string) Aggregation {
+	return Aggregation{fn: "distinct_count", column: column}
+}
+
+// Percentile returns the p-th percentile, for p from 0 to 1, of the numeric
+// values of column, interpolating between the two closest values.
+func Percentile(column string, p float64) Aggregation {
+	return Aggregation{fn: "percentile", column: column, params: []any{p}}
+}
+
+// As names the result column, which defaults to the function name followed
+// by the column name, like sum_amount.
+func (a Aggregation) As(name string) Aggregation {
+	a.alias = name
+	return a
+}
+
+// Name returns the name of the result column.
+func (a Aggregation) Name() string {
+	switch {
+	case a.alias != "":
+		return a.alias
+	case a.column == "":
+		return a.fn
+	}
+	return a.fn + "_" + a.column
+}
+
+func (a Aggregation) apply(rows []map[string]any) any {
+	if a.column == "" {
+		return len(rows)
+	}
+	values := make([]any, 0, len(rows))
+	for _, row := range rows {
+		if value := row[a.column]; value != nil {
+			values = append(values, value)
+		}
+	}
+	return aggregates[a.fn].fn(values, a.params)
+}
@@ -0,0 +1,45 @@
+package datatable
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByAggregations(t *testing.T) {
+	rows := []map[string]any{
+		{"a": "x y", "b": "z", "n": 1, "tag": "p"},
+		{"a": "x", "b": "y z", "n": 2, "tag": "p"},
+		{"a": "x y", "b": "z", "n": 3, "tag": "q"},
+		{"a": "x y", "b": "z", "n": nil, "tag": "q"},
+		{"a": "x", "b": "y z", "n": 4, "tag": "p"},
+		{"a": "x y", "b": "z", "n": 10, "tag": "r"},
+	}
+	got := GroupBy(rows, "a, b",
+		Count(), Sum("n"), Avg("n").As("mean"), Min("n"), Max("n"), First("tag"), Last("n"),
+		DistinctCount("tag"), Percentile("n", 0.5).As("median"))
+	want := []map[string]any{
+		{"a": "x y", "b": "z", "count": 4, "sum_n": 14.0, "mean": 14.0 / 3, "min_n": 1, "max_n": 10,
+			"first_tag": "p", "last_n": 10, "distinct_count_tag": 3, "median": 3.0},
+		{"a": "x", "b": "y z", "count": 2, "sum_n": 6.0, "mean": 3.0, "min_n": 2, "max_n": 4,
+			"first_tag": "p", "last_n": 4, "distinct_count_tag": 1, "median": 3.0},
+	}
+	if !reflect.DeepEqual(got.Rows, want) || got.Count != 2 {
+		t.Errorf("got %v, want %v", got.Rows, want)
+	}
+	if len(got.Columns) != 11 || got.Columns[0].Name != "a" || got.Columns[10].Name != "median" {
+		t.Errorf("unexpected columns %v", got.Columns)
+	}
+
+	total := New(rows).GroupBy("", Count(), Percentile("n", 0.25))
+	if want := []map[string]any{{"count": 6, "percentile_n": 2.0}}; !reflect.DeepEqual(total.Rows, want) {
+		t.Errorf("got %v, want %v", total.Rows, want)
+	}
+
+	res, err := New(rows).Query("SELECT a, distinct_count(tag) tags, percentile(n, 1) top FROM t GROUP BY a, b ORDER BY a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"a": "x", "tags": 1, "top": 4.0}, {"a": "x y", "tags": 3, "top": 10.0}}; !reflect.DeepEqual(res.Rows, want) {
+		t.Errorf("got %v, want %v", res.Rows, want)
+	}
+}
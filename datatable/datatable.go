@@ -22,14 +22,12 @@
 
 package datatable
 
+// New returns a table of the rows, with the columns inferred from all of
+// them by InferSchema.
 func New(rows []map[string]any) *DataTable {
 	dt := &DataTable{}
 	dt.Count = len(rows)
-	if dt.Count > 0 {
-		for k := range rows[0] {
-			dt.Columns = append(dt.Columns, &Column{Name: k})
-		}
-	}
+	dt.Columns = InferSchema(rows)
 	dt.Rows = rows
 	return dt
 }
@@ -43,9 +41,12 @@ const (
 )
 
 type Column struct {
-	Name   string
+	Name string
+	// Type is one of the Type constants, or empty when unknown.
 	Type   string
 	Length int64
+	// Nullable reports whether some row misses the column or holds nil.
+	Nullable bool
 }
 
 type Field struct {
@@ -59,9 +60,13 @@ type DataTable struct {
 	Rows    []map[string]any
 	Count   int
 	mode    findKind
+	index   *indexSet
+	// version changes when the rows are reordered or their values changed,
+	// to invalidate the indexes.
+	version int
 }
 
-func GroupBy(data []map[string]any, query string) *DataTable {
+func GroupBy(data []map[string]any, query string, aggs ...Aggregation) *DataTable {
 	table := New(data)
-	return table.GroupBy(query)
+	return table.GroupBy(query, aggs...)
 }
@@ -28,7 +28,15 @@ import (
 	"github.com/oarkflow/pkg/str"
 )
 
-func (dt *DataTable) GroupBy(query string) *DataTable {
+// GroupBy groups the rows by the comma separated columns of query. Given
+// aggregations, it returns a flat row per group holding the values of the
+// group columns and of each aggregation, in order of first appearance; with
+// no group columns the whole table is one group. Without aggregations each
+// row maps the group value to the rows of the group.
+func (dt *DataTable) GroupBy(query string, aggs ...Aggregation) *DataTable {
+	if len(aggs) > 0 {
+		return dt.aggregate(query, aggs)
+	}
 	if dt.Count == 0 {
 		return dt
 	}
@@ -82,3 +90,49 @@ func (dt *DataTable) GroupBy(query string) *DataTable {
 	}
 	return dataTable
 }
+
+func (dt *DataTable) aggregate(query string, aggs []Aggregation) *DataTable {
+	var keys []string
+	for _, group := range ParseExpr([]byte(query)).GroupExpr {
+		if group.Name != "" {
+			keys = append(keys, group.Name)
+		}
+	}
+	dataTable := &DataTable{Name: dt.Name}
+	for _, key := range keys {
+		column := &Column{Name: key}
+		for _, c := range dt.Columns {
+			if c.Name == key {
+				column = c
+				break
+			}
+		}
+		dataTable.Columns = append(dataTable.Columns, column)
+	}
+	for _, agg := range aggs {
+		dataTable.Columns = append(dataTable.Columns, &Column{Name: agg.Name()})
+	}
+
+	groups := [][]map[string]any{dt.Rows}
+	if len(keys) > 0 {
+		groups = groupRows(dt.Rows, func(row map[string]any) []any {
+			values := make([]any, len(keys))
+			for i, key := range keys {
+				values[i] = row[key]
+			}
+			return values
+		})
+	}
+	for _, group := range groups {
+		row := make(map[string]any, len(keys)+len(aggs))
+		for _, key := range keys {
+			row[key] = group[0][key]
+		}
+		for _, agg := range aggs {
+			row[agg.Name()] = agg.apply(group)
+		}
+		dataTable.Rows = append(dataTable.Rows, row)
+	}
+	dataTable.Count = len(dataTable.Rows)
+	return dataTable
+}
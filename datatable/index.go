@@ -0,0 +1,462 @@
+package datatable
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IndexKind selects the structure of an index.
+type IndexKind int
+
+const (
+	// HashIndex serves equality and IN predicates.
+	HashIndex IndexKind = iota
+	// SortedIndex also serves range and BETWEEN predicates.
+	SortedIndex
+)
+
+func (k IndexKind) String() string {
+	switch k {
+	case HashIndex:
+		return "hash"
+	case SortedIndex:
+		return "sorted"
+	}
+	return fmt.Sprintf("IndexKind(%d)", int(k))
+}
+
+// indexSet holds the indexes of a table. Indexes are built on first use and
+// rebuilt once the rows of the table were replaced, appended to or
+// reordered.
+type indexSet struct {
+	mu      sync.Mutex
+	indexes map[string]*index
+}
+
+type index struct {
+	column string
+	kind   IndexKind
+	// rows, count and version identify the rows the index was built from.
+	rows    *map[string]any
+	count   int
+	version int
+	built   bool
+
+	// hash maps the key of each value to the positions of its rows.
+	hash map[string][]int
+	// numbers and texts sort the positions of the rows by the numeric and
+	// text values of the column; others lists the positions of the values
+	// which are not numbers.
+	numbers []numberEntry
+	texts   []textEntry
+	others  []int
+	// exact tells the text of every value is what ToString gives, so that
+	// the comparisons of Where can use the index.
+	exact bool
+}
+
+type numberEntry struct {
+	n   float64
+	pos int
+}
+
+type textEntry struct {
+	s   string
+	pos int
+}
+
+// CreateIndex indexes the values of column to speed up Where and Query,
+// which use the index for equality, IN and, with a sorted index, range and
+// BETWEEN predicates. The index is built now and rebuilt when it is used
+// after the rows were replaced, appended to or sorted; call
+// InvalidateIndexes after changing values in place. Tables derived by
+// Where, Like, Find and Query have the same indexes, built on first use.
+func (dt *DataTable) CreateIndex(column string, kind IndexKind) error {
+	if kind != HashIndex && kind != SortedIndex {
+		return fmt.Errorf("datatable: unknown index kind %s", kind)
+	}
+	if dt.index == nil {
+		dt.index = &indexSet{}
+	}
+	dt.index.mu.Lock()
+	defer dt.index.mu.Unlock()
+	if dt.index.indexes == nil {
+		dt.index.indexes = map[string]*index{}
+	}
+	idx := &index{column: column, kind: kind}
+	idx.build(dt)
+	dt.index.indexes[column] = idx
+	return nil
+}
+
+// DropIndex removes the index of column.
+func (dt *DataTable) DropIndex(column string) {
+	if dt.index == nil {
+		return
+	}
+	dt.index.mu.Lock()
+	defer dt.index.mu.Unlock()
+	delete(dt.index.indexes, column)
+}
+
+// InvalidateIndexes makes the indexes be rebuilt on their next use.
+func (dt *DataTable) InvalidateIndexes() {
+	dt.version++
+}
+
+// Indexes returns the kind of index of each indexed column.
+func (dt *DataTable) Indexes() map[string]IndexKind {
+	kinds := map[string]IndexKind{}
+	if dt.index == nil {
+		return kinds
+	}
+	dt.index.mu.Lock()
+	defer dt.index.mu.Unlock()
+	for column, idx := range dt.index.indexes {
+		kinds[column] = idx.kind
+	}
+	return kinds
+}
+
+// inheritIndexes gives dt, derived from another table, the indexes of that
+// table on the columns of dt, to be built on first use.
+func (dt *DataTable) inheritIndexes(from *DataTable) {
+	kinds := from.Indexes()
+	if len(kinds) == 0 {
+		return
+	}
+	dt.index = &indexSet{indexes: map[string]*index{}}
+	for _, column := range dt.Columns {
+		if kind, ok := kinds[column.Name]; ok {
+			dt.index.indexes[column.Name] = &index{column: column.Name, kind: kind}
+		}
+	}
+}
+
+// lookup returns the index of column, up to date with the rows of dt, or
+// nil.
+func (dt *DataTable) lookup(column string) *index {
+	if dt.index == nil {
+		return nil
+	}
+	dt.index.mu.Lock()
+	defer dt.index.mu.Unlock()
+	idx := dt.index.indexes[column]
+	if idx != nil && !idx.valid(dt) {
+		idx.build(dt)
+	}
+	return idx
+}
+
+func (idx *index) valid(dt *DataTable) bool {
+	return idx.built && idx.count == len(dt.Rows) && idx.version == dt.version &&
+		(len(dt.Rows) == 0 || idx.rows == &dt.Rows[0])
+}
+
+func (idx *index) build(dt *DataTable) {
+	column := idx.column
+	*idx = index{column: column, kind: idx.kind, count: len(dt.Rows), version: dt.version, built: true, exact: true}
+	if len(dt.Rows) > 0 {
+		idx.rows = &dt.Rows[0]
+	}
+	if idx.kind == HashIndex {
+		idx.hash = map[string][]int{}
+	}
+	for pos, row := range dt.Rows {
+		value := row[column]
+		if value == nil {
+			continue
+		}
+		switch value.(type) {
+		case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		default:
+			idx.exact = false
+		}
+		n, numeric := toNumber(value)
+		if idx.kind == HashIndex {
+			key := "s:" + fmt.Sprint(value)
+			if numeric {
+				key = numberKey(n)
+			}
+			idx.hash[key] = append(idx.hash[key], pos)
+			continue
+		}
+		if numeric {
+			idx.numbers = append(idx.numbers, numberEntry{n, pos})
+		} else {
+			idx.others = append(idx.others, pos)
+		}
+		idx.texts = append(idx.texts, textEntry{fmt.Sprint(value), pos})
+	}
+	sort.SliceStable(idx.numbers, func(i, j int) bool { return idx.numbers[i].n < idx.numbers[j].n })
+	sort.SliceStable(idx.texts, func(i, j int) bool { return idx.texts[i].s < idx.texts[j].s })
+}
+
+func numberKey(n float64) string {
+	return "n:" + strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+// candidates returns the positions, in ascending order, of a superset of
+// the rows whose value compares to literal by op as compareValues does; op
+// is one of =, <, <=, > and >=. It returns false if the index can not tell.
+func (idx *index) candidates(op string, literal any) ([]int, bool) {
+	if literal == nil {
+		return nil, true
+	}
+	n, numeric := toNumber(literal)
+	text := fmt.Sprint(literal)
+	var positions []int
+	switch {
+	case idx.kind == HashIndex && op == "=":
+		key := "s:" + text
+		if numeric {
+			key = numberKey(n)
+		}
+		positions = slices.Clone(idx.hash[key])
+	case idx.kind == HashIndex:
+		return nil, false
+	case numeric:
+		lo, hi := searchRange(len(idx.numbers), op, func(i int) int {
+			return compareFloat(idx.numbers[i].n, n)
+		})
+		for _, entry := range idx.numbers[lo:hi] {
+			positions = append(positions, entry.pos)
+		}
+		if op != "=" {
+			// Values which are not numbers compare by their text.
+			positions = append(positions, idx.others...)
+		}
+	default:
+		lo, hi := searchRange(len(idx.texts), op, func(i int) int {
+			return strings.Compare(idx.texts[i].s, text)
+		})
+		for _, entry := range idx.texts[lo:hi] {
+			positions = append(positions, entry.pos)
+		}
+	}
+	slices.Sort(positions)
+	return positions, true
+}
+
+// searchRange returns the bounds of the entries comparing to a value by op,
+// given cmp comparing the i-th entry of the sorted entries to it.
+func searchRange(n int, op string, cmp func(i int) int) (int, int) {
+	first := sort.Search(n, func(i int) bool { return cmp(i) >= 0 })
+	after := sort.Search(n, func(i int) bool { return cmp(i) > 0 })
+	switch op {
+	case "=":
+		return first, after
+	case "<":
+		return 0, first
+	case "<=":
+		return 0, after
+	case ">":
+		return after, n
+	}
+	return first, n
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// intersect returns the positions found in both sorted lists.
+func intersect(a, b []int) []int {
+	var res []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+	return res
+}
+
+// union returns the positions found in either sorted list.
+func union(a, b []int) []int {
+	res := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			res = append(res, a[i])
+			i++
+		case a[i] > b[j]:
+			res = append(res, b[j])
+			j++
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+	res = append(res, a[i:]...)
+	return append(res, b[j:]...)
+}
+
+// indexedRows returns the rows at positions, in order.
+func (dt *DataTable) indexedRows(positions []int) []map[string]any {
+	rows := make([]map[string]any, len(positions))
+	for i, pos := range positions {
+		rows[i] = dt.Rows[pos]
+	}
+	return rows
+}
+
+// candidateRows returns a superset of the rows matching where, using the
+// indexes of the columns compared to constants by the conjuncts of where.
+// It returns all the rows if no index applies.
+func (dt *DataTable) candidateRows(where *sqlNode) []map[string]any {
+	if where == nil || dt.index == nil {
+		return dt.Rows
+	}
+	var positions []int
+	found := false
+	for _, cond := range conjuncts(where) {
+		if p, ok := dt.indexedPositions(cond); ok {
+			if found {
+				positions = intersect(positions, p)
+			} else {
+				positions, found = p, true
+			}
+		}
+	}
+	if !found {
+		return dt.Rows
+	}
+	return dt.indexedRows(positions)
+}
+
+func conjuncts(node *sqlNode) []*sqlNode {
+	if node.kind == nodeBinary && node.op == "and" {
+		return append(conjuncts(node.args[0]), conjuncts(node.args[1])...)
+	}
+	return []*sqlNode{node}
+}
+
+// flipped gives the operator comparing the operands the other way round.
+var flipped = map[string]string{"=": "=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+func (dt *DataTable) indexedPositions(cond *sqlNode) ([]int, bool) {
+	if len(cond.args) == 0 || cond.not {
+		return nil, false
+	}
+	switch cond.kind {
+	case nodeBinary:
+		op := cond.op
+		if _, ok := flipped[op]; !ok {
+			return nil, false
+		}
+		column, literal := cond.args[0], cond.args[1]
+		if column.kind != nodeColumn {
+			column, literal = literal, column
+			op = flipped[op]
+		}
+		value, ok := literalValue(literal)
+		if column.kind != nodeColumn || !ok {
+			return nil, false
+		}
+		if idx := dt.lookup(column.name); idx != nil {
+			return idx.candidates(op, value)
+		}
+	case nodeIn:
+		idx := dt.columnIndex(cond.args[0])
+		if idx == nil {
+			return nil, false
+		}
+		var positions []int
+		for _, item := range cond.args[1:] {
+			value, ok := literalValue(item)
+			if !ok {
+				return nil, false
+			}
+			p, ok := idx.candidates("=", value)
+			if !ok {
+				return nil, false
+			}
+			positions = union(positions, p)
+		}
+		return positions, true
+	case nodeBetween:
+		idx := dt.columnIndex(cond.args[0])
+		low, ok1 := literalValue(cond.args[1])
+		high, ok2 := literalValue(cond.args[2])
+		if idx == nil || !ok1 || !ok2 {
+			return nil, false
+		}
+		from, ok1 := idx.candidates(">=", low)
+		to, ok2 := idx.candidates("<=", high)
+		if ok1 && ok2 {
+			return intersect(from, to), true
+		}
+	}
+	return nil, false
+}
+
+func (dt *DataTable) columnIndex(node *sqlNode) *index {
+	if node.kind != nodeColumn {
+		return nil
+	}
+	return dt.lookup(node.name)
+}
+
+// literalValue returns the value of a constant, negated numbers included.
+func literalValue(node *sqlNode) (any, bool) {
+	switch {
+	case node.kind == nodeLiteral:
+		return node.value, true
+	case node.kind == nodeUnary && node.op == "-" && node.args[0].kind == nodeLiteral:
+		if n, ok := node.args[0].value.(float64); ok {
+			return -n, true
+		}
+	}
+	return nil, false
+}
+
+// whereCandidates returns a superset of the rows of dt matching the
+// comparison of Where of column to input by op.
+func (dt *DataTable) whereCandidates(column, op, input string) []map[string]any {
+	if dt.mode != normal {
+		return dt.Rows
+	}
+	idx := dt.lookup(column)
+	if idx == nil {
+		return dt.Rows
+	}
+	switch op {
+	case "=", "==":
+		// Missing and nil values have the empty text.
+		if !idx.exact || input == "" {
+			return dt.Rows
+		}
+		if positions, ok := idx.candidates("=", input); ok {
+			return dt.indexedRows(positions)
+		}
+	case ">", "<", ">=", "<=":
+		// Where only compares numbers.
+		n, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil
+		}
+		if positions, ok := idx.candidates(op, n); ok {
+			return dt.indexedRows(positions)
+		}
+	}
+	return dt.Rows
+}
@@ -0,0 +1,116 @@
+package datatable
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func indexRows() []map[string]any {
+	var rows []map[string]any
+	for i := 0; i < 200; i++ {
+		row := map[string]any{"id": i, "team": fmt.Sprintf("g%d", i%7), "score": float64(i%13) / 2}
+		switch i % 50 {
+		case 0:
+			row["score"] = nil
+		case 1:
+			row["score"] = "3"
+		case 2:
+			row["score"] = "n/a"
+		case 3:
+			delete(row, "team")
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestIndexes(t *testing.T) {
+	plain := New(indexRows())
+	indexed := New(indexRows())
+	if err := indexed.CreateIndex("team", HashIndex); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexed.CreateIndex("score", SortedIndex); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexed.CreateIndex("id", IndexKind(7)); err == nil {
+		t.Error("expected an error for an unknown index kind")
+	}
+
+	for _, where := range []string{
+		"team = 'g3'",
+		"'g3' = team",
+		"3 < score",
+		"3 <= score",
+		"4.5 > score",
+		"2.5 >= score",
+		"'g2' = team AND 1 < score",
+		"team IN ('g1', 'g5', 'none')",
+		"team = 'g2' AND score >= 3",
+		"score > 4.5",
+		"score <= -1",
+		"score < 'b'",
+		"score BETWEEN 1 AND 2.5",
+		"score = 3",
+		"score = '3'",
+		"score IS NULL OR team = 'g0'",
+		"NOT team = 'g1'",
+		"team NOT IN ('g1')",
+	} {
+		query := "SELECT id FROM t WHERE " + where
+		want, err := plain.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := indexed.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Rows, want.Rows) {
+			t.Errorf("%s: got %v, want %v", where, got.Rows, want.Rows)
+		}
+	}
+
+	stmt, _ := parseSelect("SELECT id FROM t WHERE team = 'g3' AND score > 5")
+	if n := len(indexed.candidateRows(stmt.where)); n == 0 || n > 10 {
+		t.Errorf("expected the indexes to narrow the rows, got %d candidates", n)
+	}
+
+	for _, where := range []string{"team = g4", "score > 5", "score <= 1", "score = 3"} {
+		want := plain.Where(where).Rows
+		got := indexed.Where(where).Rows
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Where %s: got %d rows, want %d", where, len(got), len(want))
+		}
+	}
+
+	// Derived tables rebuild the indexes they inherit.
+	derived, err := indexed.Query("SELECT id, team, score FROM t WHERE id >= 100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := derived.Indexes(); !reflect.DeepEqual(kinds, map[string]IndexKind{"team": HashIndex, "score": SortedIndex}) {
+		t.Errorf("unexpected indexes %v", kinds)
+	}
+	got, _ := derived.Query("SELECT id FROM t WHERE team = 'g0' AND score < 1")
+	want, _ := plain.Query("SELECT id FROM t WHERE team = 'g0' AND score < 1 AND id >= 100")
+	if !reflect.DeepEqual(got.Rows, want.Rows) {
+		t.Errorf("derived: got %v, want %v", got.Rows, want.Rows)
+	}
+
+	// Appending, sorting and invalidating rebuild the indexes.
+	indexed.Rows = append(indexed.Rows, map[string]any{"id": 1000, "team": "g3"})
+	if res, _ := indexed.Query("SELECT id FROM t WHERE team = 'g3' AND id = 1000"); res.Count != 1 {
+		t.Errorf("appended row not found: %v", res.Rows)
+	}
+	indexed.OrderBy("id desc")
+	if res, _ := indexed.Query("SELECT id FROM t WHERE team = 'g3' LIMIT 1"); res.Rows[0]["id"] != 1000 {
+		t.Errorf("sorted rows not reindexed: %v", res.Rows)
+	}
+	indexed.Rows[0]["team"] = "moved"
+	indexed.InvalidateIndexes()
+	if res, _ := indexed.Query("SELECT id FROM t WHERE team = 'moved'"); res.Count != 1 {
+		t.Errorf("changed value not reindexed: %v", res.Rows)
+	}
+}
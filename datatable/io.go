@@ -0,0 +1,486 @@
+package datatable
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/pkg/dateparse"
+	"github.com/oarkflow/pkg/xopen"
+)
+
+// ReadOptions configures FromCSV, FromJSONLines and FromXLSX.
+type ReadOptions struct {
+	// Comma is the CSV field separator, ',' by default.
+	Comma rune
+	// Comment starts CSV lines to skip.
+	Comment rune
+	// NoHeader tells the first CSV or XLSX row is data; the columns are then
+	// named column1, column2 and so on.
+	NoHeader bool
+	// Sheet is the name of the XLSX sheet to read, the first by default.
+	Sheet string
+	// Header renames the columns: the keys are the names in the file.
+	Header map[string]string
+	// Types converts the values of the named columns to the given type, one
+	// of the Type constants. Names are the ones after Header.
+	Types map[string]string
+	// InferTypes converts the text of other CSV and XLSX columns to int,
+	// float, bool or time when all their values are of that type.
+	InferTypes bool
+	// NullValues are the texts read as nil from CSV and XLSX, the empty
+	// string if unset.
+	NullValues []string
+}
+
+// WriteOptions configures WriteCSV, WriteJSONLines and WriteXLSX.
+type WriteOptions struct {
+	// Comma is the CSV field separator, ',' by default.
+	Comma rune
+	// NoHeader leaves out the header row of CSV and XLSX.
+	NoHeader bool
+	// Header renames the columns: the keys are the names in the table.
+	Header map[string]string
+	// TimeFormat formats times in CSV, time.RFC3339 by default.
+	TimeFormat string
+}
+
+func readOptions(opts []ReadOptions) ReadOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return ReadOptions{}
+}
+
+func writeOptions(opts []WriteOptions) WriteOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return WriteOptions{}
+}
+
+// headerNames maps the header of a file to column names, numbering empty
+// and repeated ones.
+func (o ReadOptions) headerNames(header []string) []string {
+	names := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, h := range header {
+		name := strings.TrimSpace(h)
+		if mapped, ok := o.Header[name]; ok {
+			name = mapped
+		}
+		if name == "" {
+			name = "column" + strconv.Itoa(i+1)
+		}
+		for base, n := name, 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+func (o ReadOptions) columnName(i int) string {
+	name := "column" + strconv.Itoa(i+1)
+	if mapped, ok := o.Header[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+func (o ReadOptions) isNull(s string) bool {
+	if o.NullValues == nil {
+		return s == ""
+	}
+	return slices.Contains(o.NullValues, s)
+}
+
+// tableReader collects the rows read from a file and types their values.
+type tableReader struct {
+	opts ReadOptions
+	rows []map[string]any
+	// text lists the columns holding text to type once all rows are read.
+	text map[string]bool
+	// names are the columns in file order.
+	names []string
+}
+
+func newTableReader(opts ReadOptions) *tableReader {
+	return &tableReader{opts: opts, text: map[string]bool{}}
+}
+
+// add adds a row; text values are typed by finish.
+func (t *tableReader) add(row map[string]any) {
+	for name, value := range row {
+		if s, ok := value.(string); ok {
+			if t.opts.isNull(s) {
+				row[name] = nil
+			} else {
+				t.text[name] = true
+			}
+		}
+	}
+	t.rows = append(t.rows, row)
+}
+
+func (t *tableReader) finish() (*DataTable, error) {
+	types := map[string]string{}
+	for name, typ := range t.opts.Types {
+		types[name] = typ
+	}
+	if t.opts.InferTypes {
+		for name := range t.text {
+			if _, ok := types[name]; !ok {
+				types[name] = t.inferType(name)
+			}
+		}
+	}
+	for i, row := range t.rows {
+		for name, typ := range types {
+			value, ok := row[name]
+			if !ok || value == nil {
+				continue
+			}
+			converted, err := convertValue(value, typ)
+			if err != nil {
+				return nil, fmt.Errorf("datatable: row %d, column %s: %w", i+1, name, err)
+			}
+			row[name] = converted
+		}
+	}
+	dt := New(t.rows)
+	columns := make([]*Column, 0, len(dt.Columns))
+	for _, name := range t.names {
+		column := dt.column(name)
+		if !slices.Contains(dt.Columns, column) {
+			column.Nullable = true
+		}
+		columns = append(columns, column)
+	}
+	for _, column := range dt.Columns {
+		if !slices.Contains(t.names, column.Name) {
+			columns = append(columns, column)
+		}
+	}
+	for _, column := range columns {
+		if column.Type == "" {
+			column.Type = types[column.Name]
+		}
+	}
+	dt.Columns = columns
+	return dt, nil
+}
+
+// inferType returns the narrowest type of the text values of a column.
+func (t *tableReader) inferType(name string) string {
+	typ := ""
+	for _, row := range t.rows {
+		s, ok := row[name].(string)
+		if !ok {
+			continue
+		}
+		typ = unionType(typ, textType(s))
+		if typ == TypeMixed {
+			return TypeString
+		}
+	}
+	if typ == TypeMixed {
+		return TypeString
+	}
+	return typ
+}
+
+// timeLayouts are the layouts of the text recognized as times by
+// InferTypes.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func textType(s string) string {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return TypeInt
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return TypeFloat
+	}
+	if strings.EqualFold(s, "true") || strings.EqualFold(s, "false") {
+		return TypeBool
+	}
+	for _, layout := range timeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return TypeTime
+		}
+	}
+	return TypeString
+}
+
+// convertValue converts a value read from a file to typ.
+func convertValue(value any, typ string) (any, error) {
+	if valueType(value) == typ {
+		return value, nil
+	}
+	s := strings.TrimSpace(formatValue(value, time.RFC3339Nano))
+	switch typ {
+	case TypeInt:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == float64(int64(f)) {
+			return int64(f), nil
+		}
+	case TypeFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	case TypeBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	case TypeTime:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		if t, err := dateparse.ParseAny(s); err == nil {
+			return t, nil
+		}
+	case TypeString:
+		if _, ok := value.(string); !ok {
+			return formatValue(value, time.RFC3339), nil
+		}
+		return value, nil
+	case TypeMixed, "":
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unknown type %s", typ)
+	}
+	return nil, fmt.Errorf("can not convert %q to %s", s, typ)
+}
+
+// FromCSV reads a table from CSV, gzipped or not, with a header row unless
+// NoHeader is set.
+func FromCSV(r io.Reader, opts ...ReadOptions) (*DataTable, error) {
+	o := readOptions(opts)
+	buf, err := xopen.Buf(r)
+	if err == xopen.ErrNoContent {
+		return New(nil), nil
+	} else if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(buf)
+	if o.Comma != 0 {
+		reader.Comma = o.Comma
+	}
+	reader.Comment = o.Comment
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var names []string
+	if !o.NoHeader {
+		header, err := reader.Read()
+		if err == io.EOF {
+			return New(nil), nil
+		} else if err != nil {
+			return nil, err
+		}
+		names = o.headerNames(header)
+	}
+	table := newTableReader(o)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(record))
+		for i, field := range record {
+			if i >= len(names) {
+				names = append(names, o.columnName(i))
+			}
+			row[names[i]] = field
+		}
+		table.add(row)
+	}
+	table.names = names
+	return table.finish()
+}
+
+// FromJSONLines reads a table from JSON Lines, gzipped or not: a JSON
+// object per line. Integers are read as int64 and other numbers as
+// float64; the Header and Types options apply.
+func FromJSONLines(r io.Reader, opts ...ReadOptions) (*DataTable, error) {
+	o := readOptions(opts)
+	buf, err := xopen.Buf(r)
+	if err == xopen.ErrNoContent {
+		return New(nil), nil
+	} else if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(buf)
+	decoder.UseNumber()
+	var rows []map[string]any
+	for line := 1; ; line++ {
+		var row map[string]any
+		if err := decoder.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("datatable: record %d: %w", line, err)
+		}
+		typed := make(map[string]any, len(row))
+		for name, value := range row {
+			if mapped, ok := o.Header[name]; ok {
+				name = mapped
+			}
+			typed[name] = jsonValue(value)
+		}
+		rows = append(rows, typed)
+	}
+	table := newTableReader(ReadOptions{Types: o.Types})
+	table.rows = rows
+	return table.finish()
+}
+
+// jsonValue replaces the json.Numbers of a decoded value by int64 and
+// float64.
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return value
+}
+
+// header returns the names of the columns to write and their headers.
+func (dt *DataTable) header(opts WriteOptions) (names, header []string) {
+	names = dt.columnNames()
+	header = make([]string, len(names))
+	for i, name := range names {
+		header[i] = name
+		if mapped, ok := opts.Header[name]; ok {
+			header[i] = mapped
+		}
+	}
+	return names, header
+}
+
+// formatValue formats a value for CSV.
+func formatValue(value any, timeFormat string) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(timeFormat)
+	case json.Number:
+		return v.String()
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, bool, []byte:
+		return ToString(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	if b, err := json.Marshal(value); err == nil {
+		return string(bytes.TrimSpace(b))
+	}
+	return fmt.Sprint(value)
+}
+
+// WriteCSV writes the table as CSV with a header row, a row at a time.
+// Arrays and objects are written as JSON.
+func (dt *DataTable) WriteCSV(w io.Writer, opts ...WriteOptions) error {
+	o := writeOptions(opts)
+	if o.TimeFormat == "" {
+		o.TimeFormat = time.RFC3339
+	}
+	writer := csv.NewWriter(w)
+	if o.Comma != 0 {
+		writer.Comma = o.Comma
+	}
+	names, header := dt.header(o)
+	if !o.NoHeader {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(names))
+	for _, row := range dt.Rows {
+		for i, name := range names {
+			record[i] = formatValue(row[name], o.TimeFormat)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSONLines writes the table as JSON Lines, a row at a time.
+func (dt *DataTable) WriteJSONLines(w io.Writer, opts ...WriteOptions) error {
+	o := writeOptions(opts)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, row := range dt.Rows {
+		record := row
+		if len(o.Header) > 0 {
+			record = make(map[string]any, len(row))
+			for name, value := range row {
+				if mapped, ok := o.Header[name]; ok {
+					name = mapped
+				}
+				record[name] = value
+			}
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenCSV reads a table from a CSV file, a URL or "-" for stdin, gzipped or
+// not.
+func OpenCSV(path string, opts ...ReadOptions) (*DataTable, error) {
+	return open(path, func(r io.Reader) (*DataTable, error) { return FromCSV(r, opts...) })
+}
+
+// OpenJSONLines reads a table from a JSON Lines file, a URL or "-" for
+// stdin, gzipped or not.
+func OpenJSONLines(path string, opts ...ReadOptions) (*DataTable, error) {
+	return open(path, func(r io.Reader) (*DataTable, error) { return FromJSONLines(r, opts...) })
+}
+
+// OpenXLSX reads a table from an XLSX file, a URL or "-" for stdin.
+func OpenXLSX(path string, opts ...ReadOptions) (*DataTable, error) {
+	return open(path, func(r io.Reader) (*DataTable, error) { return FromXLSX(r, opts...) })
+}
+
+func open(path string, read func(io.Reader) (*DataTable, error)) (*DataTable, error) {
+	r, err := xopen.Ropen(path)
+	if err == xopen.ErrNoContent {
+		return New(nil), nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return read(r)
+}
@@ -0,0 +1,146 @@
+package datatable
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSV(t *testing.T) {
+	input := "Order ID,amount,paid,created,note\n" +
+		"1,10.5,true,2024-01-02,\"a, b\"\n" +
+		"2,3,false,2024-01-03T10:00:00Z,\n" +
+		"3,,TRUE,2024-01-04,x,extra\n"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(input))
+	zw.Close()
+
+	dt, err := FromCSV(&gz, ReadOptions{Header: map[string]string{"Order ID": "id"}, Types: map[string]string{"note": TypeString}, InferTypes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"id": int64(1), "amount": 10.5, "paid": true, "created": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "note": "a, b"},
+		{"id": int64(2), "amount": 3.0, "paid": false, "created": time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), "note": nil},
+		{"id": int64(3), "amount": nil, "paid": true, "created": time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), "note": "x", "column6": "extra"},
+	}
+	if !reflect.DeepEqual(dt.Rows, want) {
+		t.Errorf("got %v, want %v", dt.Rows, want)
+	}
+	wantColumns := []*Column{
+		{Name: "id", Type: TypeInt},
+		{Name: "amount", Type: TypeFloat, Nullable: true},
+		{Name: "paid", Type: TypeBool},
+		{Name: "created", Type: TypeTime},
+		{Name: "note", Type: TypeString, Length: 4, Nullable: true},
+		{Name: "column6", Type: TypeString, Length: 5, Nullable: true},
+	}
+	if !reflect.DeepEqual(dt.Columns, wantColumns) {
+		for _, column := range dt.Columns {
+			t.Logf("%+v", *column)
+		}
+		t.Error("unexpected columns")
+	}
+
+	var out bytes.Buffer
+	if err := dt.WriteCSV(&out, WriteOptions{Comma: ';', Header: map[string]string{"id": "Order ID"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "Order ID;amount;paid;created;note;column6\n"+
+		"1;10.5;true;2024-01-02T00:00:00Z;a, b;\n"+
+		"2;3;false;2024-01-03T10:00:00Z;;\n"+
+		"3;;true;2024-01-04T00:00:00Z;x;extra\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := FromCSV(strings.NewReader("n\nx\n"), ReadOptions{Types: map[string]string{"n": TypeInt}}); err == nil {
+		t.Error("expected a conversion error")
+	}
+	if dt, err := FromCSV(strings.NewReader("n\nx\n")); err != nil || dt.Rows[0]["n"] != "x" {
+		t.Errorf("without options: got %v, %v", dt, err)
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	input := `{"id": 1, "price": 2.5, "tags": ["a"], "at": "2024-05-06T07:08:09Z"}` + "\n\n" +
+		`{"id": 12345678901234567, "meta": {"n": 2}, "at": null}` + "\n"
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rows.jsonl.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte(input))
+	zw.Close()
+	f.Close()
+
+	dt, err := OpenJSONLines(path, ReadOptions{Types: map[string]string{"at": TypeTime}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"id": int64(1), "price": 2.5, "tags": []any{"a"}, "at": time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)},
+		{"id": int64(12345678901234567), "meta": map[string]any{"n": int64(2)}, "at": nil},
+	}
+	if !reflect.DeepEqual(dt.Rows, want) {
+		t.Errorf("got %v, want %v", dt.Rows, want)
+	}
+
+	var out bytes.Buffer
+	if err := dt.WriteJSONLines(&out, WriteOptions{Header: map[string]string{"id": "ID"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `{"ID":1,"at":"2024-05-06T07:08:09Z","price":2.5,"tags":["a"]}`+"\n"+
+		`{"ID":12345678901234567,"at":null,"meta":{"n":2}}`+"\n"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	rows := []map[string]any{
+		{"name": "ram <&>", "qty": 3, "price": 2.25, "ok": true, "at": time.Date(2024, 2, 29, 13, 30, 0, 0, time.UTC)},
+		{"name": "sita", "qty": int64(-1), "ok": false, "tags": []any{"x"}},
+	}
+	dt := New(rows)
+	dt.Name = "sales/2024"
+	var out bytes.Buffer
+	if err := dt.WriteXLSX(&out, WriteOptions{Header: map[string]string{"qty": "Quantity"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromXLSX(&out, ReadOptions{Sheet: "sales_2024", Header: map[string]string{"Quantity": "qty"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"name": "ram <&>", "qty": int64(3), "price": 2.25, "ok": true, "at": time.Date(2024, 2, 29, 13, 30, 0, 0, time.UTC)},
+		{"name": "sita", "qty": int64(-1), "ok": false, "tags": `["x"]`},
+	}
+	if !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("got %v, want %v", got.Rows, want)
+	}
+	var names []string
+	for _, column := range got.Columns {
+		names = append(names, column.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"at", "name", "ok", "price", "qty", "tags"}) {
+		t.Errorf("unexpected columns %v", names)
+	}
+
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "XFD1": 16383} {
+		if got, err := cellColumn(ref); err != nil || got != want || columnLetters(want)+ref[len(columnLetters(want)):] != ref {
+			t.Errorf("%s: got %d, %v", ref, got, err)
+		}
+	}
+	for code, want := range map[string]bool{"yyyy-mm-dd": true, `0.00"d"`: false, "[Red]0.0": false, "General": false, "h:mm AM/PM": true} {
+		if isDateFormat(code) != want {
+			t.Errorf("%s: expected %v", code, want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package datatable
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oarkflow/pkg/str"
+)
+
+// JoinKind selects the rows Join keeps.
+type JoinKind int
+
+const (
+	// InnerJoin keeps the pairs of matching rows.
+	InnerJoin JoinKind = iota
+	// LeftJoin also keeps the rows of the left table without a match.
+	LeftJoin
+	// RightJoin also keeps the rows of the right table without a match.
+	RightJoin
+	// FullJoin keeps the rows of both tables without a match.
+	FullJoin
+	// AntiJoin keeps only the rows of the left table without a match.
+	AntiJoin
+)
+
+func (k JoinKind) String() string {
+	switch k {
+	case InnerJoin:
+		return "inner"
+	case LeftJoin:
+		return "left"
+	case RightJoin:
+		return "right"
+	case FullJoin:
+		return "full"
+	case AntiJoin:
+		return "anti"
+	}
+	return fmt.Sprintf("JoinKind(%d)", int(k))
+}
+
+type joinKey struct {
+	left, right string
+}
+
+// parseJoinOn parses comma separated conditions, each either "left = right"
+// or a column name of both tables.
+func parseJoinOn(on string) ([]joinKey, error) {
+	var keys []joinKey
+	for _, cond := range strings.Split(on, ",") {
+		left, right, found := strings.Cut(cond, "=")
+		left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+		if !found {
+			right = left
+		}
+		if left == "" || right == "" {
+			return nil, fmt.Errorf("datatable: invalid join condition %q", strings.TrimSpace(cond))
+		}
+		keys = append(keys, joinKey{left, right})
+	}
+	return keys, nil
+}
+
+// Join combines the rows of dt with the rows of other whose values are equal
+// on each condition of on, like "customer_id = id, region". Rows with a null
+// key match nothing. Columns joined under the same name are merged, other
+// columns found in both tables are prefixed with the name of their table,
+// or left_ and right_ for unnamed tables and tables sharing a name, and
+// given a numeric suffix when the prefixed name is taken. The rows come in
+// the order of dt,
+// followed for right and full joins by the unmatched rows of other.
+func (dt *DataTable) Join(other *DataTable, on string, kind JoinKind) (*DataTable, error) {
+	if kind < InnerJoin || kind > AntiJoin {
+		return nil, fmt.Errorf("datatable: unknown join kind %s", kind)
+	}
+	keys, err := parseJoinOn(on)
+	if err != nil {
+		return nil, err
+	}
+	leftNames, rightNames := dt.columnNames(), other.columnNames()
+	for _, key := range keys {
+		if len(leftNames) > 0 && !str.Contains(leftNames, key.left) {
+			return nil, fmt.Errorf("datatable: unknown join column %s in %s", key.left, tableName(dt, "left"))
+		}
+		if len(rightNames) > 0 && !str.Contains(rightNames, key.right) {
+			return nil, fmt.Errorf("datatable: unknown join column %s in %s", key.right, tableName(other, "right"))
+		}
+	}
+
+	index := map[string][]int{}
+	for i, row := range other.Rows {
+		if k, ok := joinKeyOf(row, keys, false); ok {
+			index[k] = append(index[k], i)
+		}
+	}
+
+	j := newJoiner(dt, other, keys, kind)
+	result := &DataTable{Name: dt.Name, Columns: j.columns}
+	matched := make([]bool, len(other.Rows))
+	for _, row := range dt.Rows {
+		var matches []int
+		if k, ok := joinKeyOf(row, keys, true); ok {
+			matches = index[k]
+		}
+		switch {
+		case kind == AntiJoin:
+			if len(matches) == 0 {
+				result.Rows = append(result.Rows, j.merge(row, nil))
+			}
+		case len(matches) == 0:
+			if kind == LeftJoin || kind == FullJoin {
+				result.Rows = append(result.Rows, j.merge(row, nil))
+			}
+		default:
+			for _, i := range matches {
+				matched[i] = true
+				result.Rows = append(result.Rows, j.merge(row, other.Rows[i]))
+			}
+		}
+	}
+	if kind == RightJoin || kind == FullJoin {
+		for i, row := range other.Rows {
+			if !matched[i] {
+				result.Rows = append(result.Rows, j.merge(nil, row))
+			}
+		}
+	}
+	result.Count = len(result.Rows)
+	return result, nil
+}
+
+func joinKeyOf(row map[string]any, keys []joinKey, left bool) (string, bool) {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		name := key.right
+		if left {
+			name = key.left
+		}
+		value := row[name]
+		if value == nil {
+			return "", false
+		}
+		parts[i] = valueKey(value)
+	}
+	return strings.Join(parts, "\x00"), true
+}
+
+// joiner maps the columns of both tables to the columns of the result.
+type joiner struct {
+	columns []*Column
+	left    map[string]string
+	right   map[string]string
+	// merged lists the right columns joined to a left column of the same
+	// name, which takes their value when the left row is missing.
+	merged map[string]bool
+}
+
+func newJoiner(dt, other *DataTable, keys []joinKey, kind JoinKind) *joiner {
+	j := &joiner{left: map[string]string{}, right: map[string]string{}, merged: map[string]bool{}}
+	for _, key := range keys {
+		if key.left == key.right {
+			j.merged[key.right] = true
+		}
+	}
+	leftNames, rightNames := dt.columnNames(), other.columnNames()
+	if kind == AntiJoin {
+		rightNames = nil
+	}
+	leftPrefix, rightPrefix := tableName(dt, "left"), tableName(other, "right")
+	if leftPrefix == rightPrefix {
+		leftPrefix, rightPrefix = "left", "right"
+	}
+	// names kept as they are take precedence over the prefixed ones
+	used := map[string]bool{}
+	// the side outer joins may leave out holds nil in the rows it's missing from
+	leftOuter, rightOuter := kind == RightJoin || kind == FullJoin, kind == LeftJoin || kind == FullJoin
+	for _, name := range leftNames {
+		if !str.Contains(rightNames, name) || j.merged[name] {
+			used[name] = true
+		}
+	}
+	for _, name := range rightNames {
+		if !str.Contains(leftNames, name) {
+			used[name] = true
+		}
+	}
+	unique := func(prefix, name string) string {
+		out := prefix + "_" + name
+		for i := 2; used[out]; i++ {
+			out = fmt.Sprintf("%s_%s_%d", prefix, name, i)
+		}
+		used[out] = true
+		return out
+	}
+	for _, name := range leftNames {
+		out := name
+		if str.Contains(rightNames, name) && !j.merged[name] {
+			out = unique(leftPrefix, name)
+		}
+		j.left[name] = out
+		column := renamed(dt.column(name), out)
+		column.Nullable = column.Nullable || leftOuter && !j.merged[name]
+		j.columns = append(j.columns, column)
+	}
+	for _, name := range rightNames {
+		if j.merged[name] {
+			continue
+		}
+		out := name
+		if str.Contains(leftNames, name) {
+			out = unique(rightPrefix, name)
+		}
+		j.right[name] = out
+		column := renamed(other.column(name), out)
+		column.Nullable = column.Nullable || rightOuter
+		j.columns = append(j.columns, column)
+	}
+	return j
+}
+
+// merge builds a result row from a left and a right row, either of which
+// may be nil.
+func (j *joiner) merge(left, right map[string]any) map[string]any {
+	row := make(map[string]any, len(j.columns))
+	for name, out := range j.left {
+		if left != nil {
+			row[out] = left[name]
+		} else if j.merged[name] {
+			row[out] = right[name]
+		} else {
+			row[out] = nil
+		}
+	}
+	for name, out := range j.right {
+		if right != nil {
+			row[out] = right[name]
+		} else {
+			row[out] = nil
+		}
+	}
+	return row
+}
+
+func tableName(dt *DataTable, fallback string) string {
+	if dt.Name != "" {
+		return dt.Name
+	}
+	return fallback
+}
+
+// column returns the metadata of the named column.
+func (dt *DataTable) column(name string) *Column {
+	for _, column := range dt.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return &Column{Name: name}
+}
+
+func renamed(column *Column, name string) *Column {
+	copied := *column
+	copied.Name = name
+	return &copied
+}
@@ -0,0 +1,133 @@
+package datatable
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	orders := New([]map[string]any{
+		{"id": 1, "customer_id": 10, "region": "east", "total": 5.0},
+		{"id": 2, "customer_id": 11, "region": "west", "total": 7.5},
+		{"id": 3, "customer_id": 10, "region": "west", "total": 1.0},
+		{"id": 4, "customer_id": nil, "region": "east", "total": 2.0},
+	})
+	orders.Name = "orders"
+	customers := New([]map[string]any{
+		{"id": int64(10), "region": "east", "name": "ram"},
+		{"id": 11, "region": "west", "name": "sita"},
+		{"id": 12, "region": "east", "name": "hari"},
+	})
+	customers.Name = "customers"
+
+	for kind, want := range map[JoinKind][]map[string]any{
+		InnerJoin: {
+			{"orders_id": 1, "customer_id": 10, "region": "east", "total": 5.0, "customers_id": int64(10), "name": "ram"},
+			{"orders_id": 2, "customer_id": 11, "region": "west", "total": 7.5, "customers_id": 11, "name": "sita"},
+		},
+		LeftJoin: {
+			{"orders_id": 1, "customer_id": 10, "region": "east", "total": 5.0, "customers_id": int64(10), "name": "ram"},
+			{"orders_id": 2, "customer_id": 11, "region": "west", "total": 7.5, "customers_id": 11, "name": "sita"},
+			{"orders_id": 3, "customer_id": 10, "region": "west", "total": 1.0, "customers_id": nil, "name": nil},
+			{"orders_id": 4, "customer_id": nil, "region": "east", "total": 2.0, "customers_id": nil, "name": nil},
+		},
+		RightJoin: {
+			{"orders_id": 1, "customer_id": 10, "region": "east", "total": 5.0, "customers_id": int64(10), "name": "ram"},
+			{"orders_id": 2, "customer_id": 11, "region": "west", "total": 7.5, "customers_id": 11, "name": "sita"},
+			{"orders_id": nil, "customer_id": nil, "region": "east", "total": nil, "customers_id": 12, "name": "hari"},
+		},
+		FullJoin: {
+			{"orders_id": 1, "customer_id": 10, "region": "east", "total": 5.0, "customers_id": int64(10), "name": "ram"},
+			{"orders_id": 2, "customer_id": 11, "region": "west", "total": 7.5, "customers_id": 11, "name": "sita"},
+			{"orders_id": 3, "customer_id": 10, "region": "west", "total": 1.0, "customers_id": nil, "name": nil},
+			{"orders_id": 4, "customer_id": nil, "region": "east", "total": 2.0, "customers_id": nil, "name": nil},
+			{"orders_id": nil, "customer_id": nil, "region": "east", "total": nil, "customers_id": 12, "name": "hari"},
+		},
+		AntiJoin: {
+			{"id": 3, "customer_id": 10, "region": "west", "total": 1.0},
+			{"id": 4, "customer_id": nil, "region": "east", "total": 2.0},
+		},
+	} {
+		got, err := orders.Join(customers, "customer_id = id, region", kind)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !reflect.DeepEqual(got.Rows, want) || got.Count != len(want) {
+			t.Errorf("%s: got %v, want %v", kind, got.Rows, want)
+		}
+		if kind == AntiJoin {
+			continue
+		}
+		var names []string
+		for _, column := range got.Columns {
+			names = append(names, column.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, []string{"customer_id", "customers_id", "name", "orders_id", "region", "total"}) {
+			t.Errorf("%s: unexpected columns %v", kind, names)
+		}
+	}
+
+	for _, on := range []string{"customer_id = ", "customer = id", "customer_id = cid"} {
+		if _, err := orders.Join(customers, on, InnerJoin); err == nil {
+			t.Errorf("%q: expected an error", on)
+		}
+	}
+}
+
+func TestSelfJoin(t *testing.T) {
+	employees := New([]map[string]any{
+		{"id": 1, "name": "ram", "manager_id": nil, "left_name": "x"},
+		{"id": 2, "name": "sita", "manager_id": 1, "left_name": "y"},
+	})
+	employees.Name = "employees"
+	got, err := employees.Join(employees, "manager_id = id", LeftJoin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"left_id": 1, "left_name": "ram", "left_manager_id": nil, "left_left_name": "x",
+			"right_id": nil, "right_name": nil, "right_manager_id": nil, "right_left_name": nil},
+		{"left_id": 2, "left_name": "sita", "left_manager_id": 1, "left_left_name": "y",
+			"right_id": 1, "right_name": "ram", "right_manager_id": nil, "right_left_name": "x"},
+	}
+	if !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("got %v, want %v", got.Rows, want)
+	}
+	seen := map[string]bool{}
+	for _, column := range got.Columns {
+		if seen[column.Name] {
+			t.Errorf("duplicate column %s", column.Name)
+		}
+		seen[column.Name] = true
+	}
+	if len(seen) != 8 {
+		t.Errorf("unexpected columns %v", got.Columns)
+	}
+	for _, column := range got.Columns {
+		if want := column.Name != "left_id" && column.Name != "left_name" && column.Name != "left_left_name"; column.Nullable != want {
+			t.Errorf("column %s: nullable %v, want %v", column.Name, column.Nullable, want)
+		}
+	}
+
+	// prefixed names give way to the columns kept as they are
+	a := New([]map[string]any{{"id": 1, "name": "a", "right_name": "kept"}})
+	b := New([]map[string]any{{"id": 1, "name": "b"}})
+	got, err = a.Join(b, "id", InnerJoin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"id": 1, "left_name": "a", "right_name": "kept", "right_name_2": "b"}}; !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("got %v, want %v", got.Rows, want)
+	}
+	got, err = a.Join(b, "id", RightJoin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range got.Columns {
+		if want := column.Name == "left_name" || column.Name == "right_name"; column.Nullable != want {
+			t.Errorf("right join column %s: nullable %v, want %v", column.Name, column.Nullable, want)
+		}
+	}
+}
@@ -38,24 +38,14 @@ func (dt *DataTable) OrderBy(query string) *DataTable {
 	var less = make([]lessFunc, count)
 	for i, item := range exp.OrderExpr {
 		less[i] = func(c1, c2 map[string]any) bool {
-			v1, ok1 := FormatFloat(c1[item.Name])
-			v2, ok2 := FormatFloat(c2[item.Name])
-			if ok1 && ok2 {
-				if item.Op == "desc" {
-					return v1 > v2
-				} else {
-					return v1 < v2
-				}
-			} else {
-				if item.Op == "desc" {
-					return ToString(c1[item.Name]) > ToString(c2[item.Name])
-				} else {
-					return ToString(c1[item.Name]) < ToString(c2[item.Name])
-				}
+			if item.Op == "desc" {
+				return compareTyped(c1[item.Name], c2[item.Name]) > 0
 			}
+			return compareTyped(c1[item.Name], c2[item.Name]) < 0
 		}
 	}
 	fn(less...).sorts(dt.Rows)
+	dt.version++
 	return dt
 }
 
@@ -68,7 +58,7 @@ type multiSorter struct {
 
 func (ms *multiSorter) sorts(changes []map[string]any) {
 	ms.changes = changes
-	sort.Sort(ms)
+	sort.Stable(ms)
 }
 
 func fn(less ...lessFunc) *multiSorter {
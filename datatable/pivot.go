@@ -0,0 +1,137 @@
+package datatable
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Pivot returns a crosstab of the rows: a row for each combination of the
+// values of rowKeys, in order of first appearance, and a column for each
+// value of colKey, in ascending order, holding the aggregate aggFn, one of
+// count, sum, avg, min, max, first, last and distinct_count, of the values
+// of valueField. Cells without rows are nil. A column named like one of
+// rowKeys is prefixed with colKey and an underscore, and values formatting
+// alike, such as 1 and "1", get a numeric suffix after the first.
+func (dt *DataTable) Pivot(rowKeys []string, colKey, valueField, aggFn string) (*DataTable, error) {
+	agg, ok := aggregates[aggFn]
+	if !ok {
+		return nil, fmt.Errorf("datatable: unknown aggregate %s", aggFn)
+	}
+	if agg.params > 0 {
+		return nil, fmt.Errorf("datatable: aggregate %s needs parameters", aggFn)
+	}
+
+	var pivots []any
+	seen := map[string]bool{}
+	for _, row := range dt.Rows {
+		value := row[colKey]
+		if key := valueKey(value); !seen[key] {
+			seen[key] = true
+			pivots = append(pivots, value)
+		}
+	}
+	sort.SliceStable(pivots, func(i, j int) bool { return compareTyped(pivots[i], pivots[j]) < 0 })
+	names := make(map[string]string, len(pivots))
+	used := map[string]bool{}
+	result := &DataTable{Name: dt.Name}
+	for _, key := range rowKeys {
+		used[key] = true
+		result.Columns = append(result.Columns, renamed(dt.column(key), key))
+	}
+	for _, pivot := range pivots {
+		name := formatValue(pivot, "2006-01-02")
+		if pivot == nil {
+			name = "null"
+		}
+		if slices.Contains(rowKeys, name) {
+			name = colKey + "_" + name
+		}
+		out := name
+		for i := 2; used[out]; i++ {
+			out = fmt.Sprintf("%s_%d", name, i)
+		}
+		used[out] = true
+		names[valueKey(pivot)] = out
+		result.Columns = append(result.Columns, &Column{Name: out, Nullable: true})
+	}
+
+	groups := groupRows(dt.Rows, func(row map[string]any) []any {
+		values := make([]any, len(rowKeys))
+		for i, key := range rowKeys {
+			values[i] = row[key]
+		}
+		return values
+	})
+	for _, group := range groups {
+		row := make(map[string]any, len(rowKeys)+len(pivots))
+		for _, key := range rowKeys {
+			row[key] = group[0][key]
+		}
+		values := map[string][]any{}
+		for _, r := range group {
+			name := names[valueKey(r[colKey])]
+			if _, ok := values[name]; !ok {
+				values[name] = []any{}
+			}
+			if value := r[valueField]; value != nil {
+				values[name] = append(values[name], value)
+			}
+		}
+		for _, column := range result.Columns[len(rowKeys):] {
+			name := column.Name
+			if cell, ok := values[name]; ok {
+				row[name] = agg.fn(cell, nil)
+			} else {
+				row[name] = nil
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.Count = len(result.Rows)
+	return result, nil
+}
+
+// Unpivot turns columns into rows: each row of dt gives a row per column of
+// valueColumns it has, holding the idColumns, the name of the column under
+// nameColumn and its value under valueColumn. With no valueColumns, all the
+// columns but idColumns are unpivoted.
+func (dt *DataTable) Unpivot(idColumns, valueColumns []string, nameColumn, valueColumn string) *DataTable {
+	if len(valueColumns) == 0 {
+		for _, name := range dt.columnNames() {
+			if !slices.Contains(idColumns, name) {
+				valueColumns = append(valueColumns, name)
+			}
+		}
+	}
+	result := &DataTable{Name: dt.Name}
+	for _, id := range idColumns {
+		result.Columns = append(result.Columns, renamed(dt.column(id), id))
+	}
+	values := &Column{Name: valueColumn}
+	for _, name := range valueColumns {
+		column := dt.column(name)
+		if column.Type != "" {
+			values.Type = unionType(values.Type, column.Type)
+		}
+		values.Nullable = values.Nullable || column.Nullable
+	}
+	result.Columns = append(result.Columns, &Column{Name: nameColumn, Type: TypeString}, values)
+	for _, row := range dt.Rows {
+		for _, name := range valueColumns {
+			value, ok := row[name]
+			if !ok {
+				continue
+			}
+			r := make(map[string]any, len(idColumns)+2)
+			for _, id := range idColumns {
+				r[id] = row[id]
+			}
+			r[nameColumn] = name
+			r[valueColumn] = value
+			result.Rows = append(result.Rows, r)
+		}
+	}
+	result.Count = len(result.Rows)
+	return result
+}
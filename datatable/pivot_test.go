@@ -0,0 +1,92 @@
+package datatable
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sales() *DataTable {
+	return New([]map[string]any{
+		{"region": "east", "month": "2024-02", "revenue": 10},
+		{"region": "west", "month": "2024-01", "revenue": 5},
+		{"region": "east", "month": "2024-01", "revenue": 7},
+		{"region": "east", "month": "2024-02", "revenue": 3},
+		{"region": "west", "month": "2024-03", "revenue": nil},
+	})
+}
+
+func TestPivot(t *testing.T) {
+	got, err := sales().Pivot([]string{"region"}, "month", "revenue", "sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"region": "east", "2024-01": 7.0, "2024-02": 13.0, "2024-03": nil},
+		{"region": "west", "2024-01": 5.0, "2024-02": nil, "2024-03": 0.0},
+	}
+	if !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("got %v, want %v", got.Rows, want)
+	}
+	var names []string
+	for _, column := range got.Columns {
+		names = append(names, column.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"region", "2024-01", "2024-02", "2024-03"}) {
+		t.Errorf("unexpected columns %v", names)
+	}
+	alike := New([]map[string]any{
+		{"region": "east", "code": 1, "revenue": 1},
+		{"region": "east", "code": "1", "revenue": 2},
+	})
+	codes, err := alike.Pivot([]string{"region"}, "code", "revenue", "sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"region": "east", "1": 1.0, "1_2": 2.0}}; !reflect.DeepEqual(codes.Rows, want) || len(codes.Columns) != 3 {
+		t.Errorf("got %v, want %v", codes.Rows, want)
+	}
+	if _, err := sales().Pivot([]string{"region"}, "month", "revenue", "percentile"); err == nil {
+		t.Error("expected an error for an aggregate with parameters")
+	}
+
+	long := got.Unpivot([]string{"region"}, []string{"2024-01", "2024-02"}, "month", "revenue")
+	wantLong := []map[string]any{
+		{"region": "east", "month": "2024-01", "revenue": 7.0},
+		{"region": "east", "month": "2024-02", "revenue": 13.0},
+		{"region": "west", "month": "2024-01", "revenue": 5.0},
+		{"region": "west", "month": "2024-02", "revenue": nil},
+	}
+	if !reflect.DeepEqual(long.Rows, wantLong) || long.Count != 4 {
+		t.Errorf("got %v, want %v", long.Rows, wantLong)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	dt := sales().Window([]string{"region"}, "month, revenue desc",
+		RowNumber(), Rank(), DenseRank(), RunningSum("revenue").As("total"), RunningAvg("revenue"),
+		Lag("revenue", 1, 0), Lead("month", 1, nil))
+	want := []map[string]any{
+		{"region": "east", "month": "2024-02", "revenue": 10, "row_number": 2, "rank": 2, "dense_rank": 2,
+			"total": 17.0, "running_avg_revenue": 8.5, "lag_revenue": 7, "lead_month": "2024-02"},
+		{"region": "west", "month": "2024-01", "revenue": 5, "row_number": 1, "rank": 1, "dense_rank": 1,
+			"total": 5.0, "running_avg_revenue": 5.0, "lag_revenue": 0, "lead_month": "2024-03"},
+		{"region": "east", "month": "2024-01", "revenue": 7, "row_number": 1, "rank": 1, "dense_rank": 1,
+			"total": 7.0, "running_avg_revenue": 7.0, "lag_revenue": 0, "lead_month": "2024-02"},
+		{"region": "east", "month": "2024-02", "revenue": 3, "row_number": 3, "rank": 3, "dense_rank": 3,
+			"total": 20.0, "running_avg_revenue": 20.0 / 3, "lag_revenue": 10, "lead_month": nil},
+		{"region": "west", "month": "2024-03", "revenue": nil, "row_number": 2, "rank": 2, "dense_rank": 2,
+			"total": 5.0, "running_avg_revenue": 5.0, "lag_revenue": 5, "lead_month": nil},
+	}
+	if !reflect.DeepEqual(dt.Rows, want) {
+		t.Errorf("got %v, want %v", dt.Rows, want)
+	}
+
+	ties := New([]map[string]any{{"n": 3}, {"n": 1}, {"n": 3}, {"n": 2}}).Window(nil, "n desc", Rank(), DenseRank())
+	var ranks [][2]any
+	for _, row := range ties.Rows {
+		ranks = append(ranks, [2]any{row["rank"], row["dense_rank"]})
+	}
+	if want := [][2]any{{1, 1}, {4, 3}, {1, 1}, {3, 2}}; !reflect.DeepEqual(ranks, want) {
+		t.Errorf("got %v, want %v", ranks, want)
+	}
+}
@@ -0,0 +1,306 @@
+package datatable
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Query runs a SQL SELECT statement over the rows and returns a new table
+// of the selected columns:
+//
+//	SELECT [DISTINCT] * | expr [[AS] alias], ...
+//	[FROM name]
+//	[WHERE cond]
+//	[GROUP BY expr, ...] [HAVING cond]
+//	[ORDER BY expr [ASC | DESC], ...]
+//	[LIMIT n] [OFFSET n]
+//
+// Expressions support arithmetic, comparisons, AND, OR, NOT, [NOT] LIKE,
+// [NOT] IN, [NOT] BETWEEN, IS [NOT] NULL, the functions lower, upper, trim,
+// length, coalesce, abs and round, and the aggregates count, sum, avg, min,
+// max, first, last, distinct_count and percentile(expr, p). HAVING and ORDER BY may refer to the aliases of selected
+// columns. The table name after FROM is not checked. Errors are
+// *QueryError values holding the position of the problem.
+func (dt *DataTable) Query(query string) (*DataTable, error) {
+	stmt, err := parseSelect(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := stmt.validate(dt.columnNames()); err != nil {
+		return nil, err
+	}
+	return stmt.run(dt), nil
+}
+
+// columnNames lists the columns of the table, then the keys of the rows
+// missing from them.
+func (dt *DataTable) columnNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, column := range dt.Columns {
+		if !seen[column.Name] {
+			seen[column.Name] = true
+			names = append(names, column.Name)
+		}
+	}
+	var extra []string
+	for _, row := range dt.Rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				extra = append(extra, name)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+func (stmt *selectStmt) aggregating() bool {
+	if len(stmt.groupBy) > 0 || stmt.having.hasAggregate() {
+		return true
+	}
+	for _, field := range stmt.fields {
+		if field.expr.hasAggregate() {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks column references and the placement of aggregates.
+func (stmt *selectStmt) validate(columns []string) error {
+	known := make(map[string]bool, len(columns))
+	for _, name := range columns {
+		known[name] = true
+	}
+	aliases := map[string]bool{}
+	for _, field := range stmt.fields {
+		if field.alias != "" {
+			aliases[field.alias] = true
+		}
+	}
+	var err error
+	check := func(expr *sqlNode, allowAliases, allowAggregates bool) {
+		expr.walk(func(node *sqlNode) bool {
+			switch {
+			case err != nil:
+			case node.kind == nodeColumn && len(known) > 0 && !known[node.name] && !(allowAliases && aliases[node.name]):
+				err = &QueryError{Pos: node.pos, Msg: fmt.Sprintf("unknown column %s", node.name)}
+			case node.kind == nodeAggregate && !allowAggregates:
+				err = &QueryError{Pos: node.pos, Msg: fmt.Sprintf("aggregate %s not allowed here", node.op)}
+			case node.kind == nodeAggregate:
+				for _, arg := range node.args {
+					if arg.hasAggregate() {
+						err = &QueryError{Pos: arg.pos, Msg: "aggregates can not be nested"}
+					}
+				}
+			}
+			return err == nil
+		})
+	}
+	for _, field := range stmt.fields {
+		if field.expr != nil {
+			check(field.expr, false, true)
+		}
+	}
+	check(stmt.where, false, false)
+	for _, expr := range stmt.groupBy {
+		check(expr, false, false)
+	}
+	check(stmt.having, true, true)
+	for _, field := range stmt.orderBy {
+		check(field.expr, true, true)
+	}
+	return err
+}
+
+// result is an output row, with the environment it was computed in for
+// HAVING and ORDER BY.
+type result struct {
+	row map[string]any
+	env *sqlEnv
+}
+
+func (stmt *selectStmt) run(dt *DataTable) *DataTable {
+	var rows []map[string]any
+	for _, row := range dt.candidateRows(stmt.where) {
+		if stmt.where == nil || truthy(stmt.where.eval(&sqlEnv{row: row})) {
+			rows = append(rows, row)
+		}
+	}
+
+	var envs []*sqlEnv
+	if stmt.aggregating() {
+		for _, group := range stmt.groups(rows) {
+			env := &sqlEnv{group: group, row: map[string]any{}}
+			if len(group) > 0 {
+				env.row = group[0]
+			}
+			envs = append(envs, env)
+		}
+	} else {
+		for _, row := range rows {
+			envs = append(envs, &sqlEnv{row: row})
+		}
+	}
+
+	columns := stmt.columns(dt)
+	starWidth := len(dt.columnNames())
+	results := make([]result, 0, len(envs))
+	seen := map[string]bool{}
+	for _, env := range envs {
+		row := stmt.project(env, columns, starWidth)
+		env.aliases = row
+		if stmt.having != nil && !truthy(stmt.having.eval(env)) {
+			continue
+		}
+		if stmt.distinct {
+			key := make([]string, len(columns))
+			for i, column := range columns {
+				key[i] = valueKey(row[column.Name])
+			}
+			if k := strings.Join(key, "\x00"); seen[k] {
+				continue
+			} else {
+				seen[k] = true
+			}
+		}
+		results = append(results, result{row: row, env: env})
+	}
+
+	if len(stmt.orderBy) > 0 {
+		keys := make([][]any, len(results))
+		for i, res := range results {
+			keys[i] = make([]any, len(stmt.orderBy))
+			for j, field := range stmt.orderBy {
+				keys[i][j] = stmt.orderKey(field.expr, res, columns)
+			}
+		}
+		order := make([]int, len(results))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			for j, field := range stmt.orderBy {
+				c := compareTyped(keys[order[a]][j], keys[order[b]][j])
+				if field.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+		sorted := make([]result, len(results))
+		for i, idx := range order {
+			sorted[i] = results[idx]
+		}
+		results = sorted
+	}
+
+	start := min(stmt.offset, len(results))
+	end := len(results)
+	if stmt.limit >= 0 {
+		end = min(start+stmt.limit, end)
+	}
+	table := &DataTable{Name: dt.Name, Columns: columns, Rows: make([]map[string]any, 0, end-start)}
+	for _, res := range results[start:end] {
+		table.Rows = append(table.Rows, res.row)
+	}
+	table.Count = len(table.Rows)
+	table.inheritIndexes(dt)
+	return table
+}
+
+// groups splits the rows by the GROUP BY values, in order of appearance.
+// Without GROUP BY all rows are one group, even if there are none.
+func (stmt *selectStmt) groups(rows []map[string]any) [][]map[string]any {
+	if len(stmt.groupBy) == 0 {
+		return [][]map[string]any{rows}
+	}
+	return groupRows(rows, func(row map[string]any) []any {
+		env := &sqlEnv{row: row}
+		key := make([]any, len(stmt.groupBy))
+		for i, expr := range stmt.groupBy {
+			key[i] = expr.eval(env)
+		}
+		return key
+	})
+}
+
+// columns returns the output columns; selected table columns keep their
+// metadata.
+func (stmt *selectStmt) columns(dt *DataTable) []*Column {
+	byName := map[string]*Column{}
+	for _, column := range dt.Columns {
+		byName[column.Name] = column
+	}
+	var columns []*Column
+	for _, field := range stmt.fields {
+		if field.star {
+			for _, name := range dt.columnNames() {
+				if column, ok := byName[name]; ok {
+					columns = append(columns, column)
+				} else {
+					columns = append(columns, &Column{Name: name})
+				}
+			}
+			continue
+		}
+		column := &Column{Name: stmt.fieldName(field)}
+		if field.expr.kind == nodeColumn {
+			if source, ok := byName[field.expr.name]; ok {
+				copied := *source
+				copied.Name = column.Name
+				column = &copied
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// fieldName names a selected column by its alias, its column or else the
+// text of its expression.
+func (stmt *selectStmt) fieldName(field *selectField) string {
+	switch {
+	case field.alias != "":
+		return field.alias
+	case field.expr.kind == nodeColumn:
+		return field.expr.name
+	}
+	return stmt.query[field.expr.pos:field.expr.end]
+}
+
+func (stmt *selectStmt) project(env *sqlEnv, columns []*Column, starWidth int) map[string]any {
+	row := make(map[string]any, len(columns))
+	i := 0
+	for _, field := range stmt.fields {
+		if !field.star {
+			row[columns[i].Name] = field.expr.eval(env)
+			i++
+			continue
+		}
+		for _, column := range columns[i : i+starWidth] {
+			if value, ok := env.row[column.Name]; ok {
+				row[column.Name] = value
+			}
+		}
+		i += starWidth
+	}
+	return row
+}
+
+// orderKey evaluates an ORDER BY expression; a number n refers to the n-th
+// selected column.
+func (stmt *selectStmt) orderKey(expr *sqlNode, res result, columns []*Column) any {
+	if expr.kind == nodeLiteral {
+		if n, ok := expr.value.(float64); ok && n == float64(int(n)) && n >= 1 && int(n) <= len(columns) {
+			return res.row[columns[int(n)-1].Name]
+		}
+	}
+	return expr.eval(res.env)
+}
@@ -0,0 +1,70 @@
+package datatable
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	var rows []map[string]any
+	for i, country := range []string{"np", "in", "np", "us", "np", "in", "in", "np"} {
+		rows = append(rows, map[string]any{"name": string(rune('a' + i)), "country": country, "age": 15 + 3*i})
+	}
+	dt := New(rows)
+
+	got, err := dt.Query("SELECT country, count(*) AS n, avg(age) FROM t WHERE age > 18 GROUP BY country HAVING n > 1 ORDER BY n DESC LIMIT 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"country": "np", "n": 3, "avg(age)": 28.0},
+		{"country": "in", "n": 2, "avg(age)": 31.5},
+	}
+	if !reflect.DeepEqual(got.Rows, want) || got.Count != 2 {
+		t.Errorf("got %v, want %v", got.Rows, want)
+	}
+	var names []string
+	for _, column := range got.Columns {
+		names = append(names, column.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"country", "n", "avg(age)"}) {
+		t.Errorf("unexpected columns %v", names)
+	}
+
+	for query, want := range map[string][]map[string]any{
+		"SELECT name FROM t WHERE country IN ('us', 'in') AND NOT age BETWEEN 20 AND 30 ORDER BY 1 DESC": {
+			{"name": "g"}, {"name": "b"},
+		},
+		"SELECT DISTINCT upper(country) c FROM t WHERE name LIKE '_' ORDER BY c LIMIT 2 OFFSET 1": {
+			{"c": "NP"}, {"c": "US"},
+		},
+		"SELECT sum(age) total, max(name) FROM t WHERE age > 100": {
+			{"total": 0.0, "max(name)": nil},
+		},
+	} {
+		got, err := dt.Query(query)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+		} else if !reflect.DeepEqual(got.Rows, want) {
+			t.Errorf("%s: got %v, want %v", query, got.Rows, want)
+		}
+	}
+
+	for query, pos := range map[string]int{
+		"SELECT name FROM t WHERE":                   24,
+		"SELECT nme FROM t":                          7,
+		"SELECT name FROM t WHERE count(*) > 1":      25,
+		"SELECT sum(avg(age)) FROM t":                11,
+		"SELECT name FROM t LIMIT -1":                25,
+		"SELECT name FROM t WHERE name = 'unclosed":  32,
+		"SELECT name, FROM t":                        13,
+		"SELECT name FROM t ORDER BY age DESC extra": 37,
+	} {
+		_, err := dt.Query(query)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) || queryErr.Pos != pos {
+			t.Errorf("%s: expected an error at %d, got %v", query, pos, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package datatable
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Column types set by InferSchema.
+const (
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+	TypeString = "string"
+	// TypeMixed is the type of columns holding values of several types.
+	TypeMixed = "mixed"
+)
+
+// InferSchema returns the columns of the rows: every key found in any row,
+// in order of first appearance, with its type and whether some row misses
+// it or holds nil. Integers mixed with floats make a float column, other
+// mixes a mixed one. Columns with only nil values have no type. Length is
+// the length in characters of the longest string.
+func InferSchema(rows []map[string]any) []*Column {
+	var columns []*Column
+	index := map[string]*Column{}
+	for _, row := range rows {
+		var added []string
+		for name := range row {
+			if _, ok := index[name]; !ok {
+				added = append(added, name)
+			}
+		}
+		sort.Strings(added)
+		for _, name := range added {
+			column := &Column{Name: name}
+			index[name] = column
+			columns = append(columns, column)
+		}
+	}
+	for _, column := range columns {
+		for _, row := range rows {
+			value, ok := row[column.Name]
+			typ := valueType(value)
+			if !ok || typ == "" {
+				column.Nullable = true
+				continue
+			}
+			column.Type = unionType(column.Type, typ)
+			if s, ok := value.(string); ok {
+				column.Length = max(column.Length, int64(utf8.RuneCountInString(s)))
+			}
+		}
+	}
+	return columns
+}
+
+// InferSchema replaces the columns of dt with the ones inferred from its
+// rows.
+func (dt *DataTable) InferSchema() *DataTable {
+	dt.Columns = InferSchema(dt.Rows)
+	return dt
+}
+
+func valueType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInt
+	case float32, float64:
+		return TypeFloat
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return TypeInt
+		}
+		return TypeFloat
+	case bool:
+		return TypeBool
+	case time.Time:
+		return TypeTime
+	case string:
+		return TypeString
+	}
+	return TypeMixed
+}
+
+func unionType(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case (a == TypeInt || a == TypeFloat) && (b == TypeInt || b == TypeFloat):
+		return TypeFloat
+	}
+	return TypeMixed
+}
+
+// typeRank orders the values of a mixed column: nil, bools, numbers, times,
+// strings and others.
+func typeRank(typ string) int {
+	switch typ {
+	case "":
+		return 0
+	case TypeBool:
+		return 1
+	case TypeInt, TypeFloat:
+		return 2
+	case TypeTime:
+		return 3
+	case TypeString:
+		return 4
+	}
+	return 5
+}
+
+// compareTyped orders two values by type, then by value: numbers
+// numerically whatever their Go type, times chronologically, false before
+// true and strings and others by their text.
+func compareTyped(a, b any) int {
+	ta, tb := valueType(a), valueType(b)
+	if ra, rb := typeRank(ta), typeRank(tb); ra != rb {
+		return ra - rb
+	}
+	switch ta {
+	case "":
+		return 0
+	case TypeInt, TypeFloat:
+		x, _ := toNumber(a)
+		y, _ := toNumber(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case TypeTime:
+		return a.(time.Time).Compare(b.(time.Time))
+	case TypeBool:
+		x, y := a.(bool), b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case y:
+			return -1
+		}
+		return 1
+	case TypeString:
+		return strings.Compare(a.(string), b.(string))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
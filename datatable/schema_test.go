@@ -0,0 +1,75 @@
+package datatable
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInferSchema(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	dt := New([]map[string]any{
+		{"id": 1, "name": "ram", "at": day(3), "score": 2},
+		{"id": 2, "name": "sita", "at": day(1), "score": 1.5, "active": true},
+		{"id": json.Number("3"), "name": nil, "at": day(2), "score": "n/a", "note": "late"},
+	})
+	want := []*Column{
+		{Name: "at", Type: TypeTime},
+		{Name: "id", Type: TypeInt},
+		{Name: "name", Type: TypeString, Length: 4, Nullable: true},
+		{Name: "score", Type: TypeMixed, Length: 3},
+		{Name: "active", Type: TypeBool, Nullable: true},
+		{Name: "note", Type: TypeString, Length: 4, Nullable: true},
+	}
+	if !reflect.DeepEqual(dt.Columns, want) {
+		for _, column := range dt.Columns {
+			t.Logf("%+v", *column)
+		}
+		t.Fatal("unexpected columns")
+	}
+
+	ids := func(dt *DataTable) []any {
+		var ids []any
+		for _, row := range dt.Rows {
+			ids = append(ids, row["id"])
+		}
+		return ids
+	}
+	if got := ids(dt.OrderBy("at")); !reflect.DeepEqual(got, []any{2, json.Number("3"), 1}) {
+		t.Errorf("order by time: got %v", got)
+	}
+	if got := ids(dt.OrderBy("score desc")); !reflect.DeepEqual(got, []any{json.Number("3"), 1, 2}) {
+		t.Errorf("order by mixed: got %v", got)
+	}
+	if got := ids(dt.OrderBy("name, id desc")); !reflect.DeepEqual(got, []any{json.Number("3"), 1, 2}) {
+		t.Errorf("order by nullable: got %v", got)
+	}
+	if got := ids(dt.OrderBy("id desc")); !reflect.DeepEqual(got, []any{json.Number("3"), 2, 1}) {
+		t.Errorf("order by numbers: got %v", got)
+	}
+
+	// every ordering sorts a mixed column alike
+	mixed := New([]map[string]any{
+		{"id": 1, "v": "b"}, {"id": 2, "v": 10}, {"id": 3, "v": nil}, {"id": 4, "v": true},
+		{"id": 5, "v": "9"}, {"id": 6, "v": 2.5}, {"id": 7, "v": day(1)},
+	})
+	order := []any{3, 4, 6, 2, 7, 5, 1}
+	if got := ids(mixed.OrderBy("v")); !reflect.DeepEqual(got, order) {
+		t.Errorf("OrderBy mixed: got %v, want %v", got, order)
+	}
+	res, err := mixed.Query("SELECT id FROM t ORDER BY v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res); !reflect.DeepEqual(got, order) {
+		t.Errorf("ORDER BY mixed: got %v, want %v", got, order)
+	}
+	res, err = mixed.Query("SELECT MIN(v) AS low, MAX(v) AS high FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if low, high := res.Rows[0]["low"], res.Rows[0]["high"]; low != true || high != "b" {
+		t.Errorf("MIN and MAX mixed: got %v and %v", low, high)
+	}
+}
@@ -0,0 +1,962 @@
+package datatable
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryError is a syntax or semantic error in a query; Pos is the byte
+// offset of the offending token.
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("datatable: %s at position %d", e.Msg, e.Pos)
+}
+
+type sqlTokenKind uint
+
+const (
+	sqlEOF sqlTokenKind = iota
+	sqlIdent
+	sqlQuotedIdent
+	sqlNumber
+	sqlString
+	sqlSymbol
+)
+
+type sqlToken struct {
+	kind     sqlTokenKind
+	text     string
+	pos, end int
+}
+
+func (t sqlToken) String() string {
+	switch t.kind {
+	case sqlEOF:
+		return "end of query"
+	case sqlString:
+		return "'" + t.text + "'"
+	}
+	return strconv.Quote(t.text)
+}
+
+func lexSQL(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)) || c >= 0x80:
+			start := i
+			for i < len(query) && (query[i] == '_' || query[i] == '.' || query[i] == '$' || query[i] >= 0x80 ||
+				unicode.IsLetter(rune(query[i])) || unicode.IsDigit(rune(query[i]))) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: query[start:i], pos: start, end: i})
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			start := i
+			for i < len(query) && (query[i] >= '0' && query[i] <= '9' || query[i] == '.') {
+				i++
+			}
+			if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+				i++
+				if i < len(query) && (query[i] == '+' || query[i] == '-') {
+					i++
+				}
+				for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+					i++
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: query[start:i], pos: start, end: i})
+		case c == '\'' || c == '"' || c == '`':
+			start := i
+			var text strings.Builder
+			for i++; ; i++ {
+				if i >= len(query) {
+					return nil, &QueryError{Pos: start, Msg: "unterminated quote"}
+				}
+				if query[i] == c {
+					// a doubled quote is an escaped quote
+					if i+1 < len(query) && query[i+1] == c {
+						text.WriteByte(c)
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				text.WriteByte(query[i])
+			}
+			kind := sqlString
+			if c != '\'' {
+				kind = sqlQuotedIdent
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: text.String(), pos: start, end: i})
+		default:
+			if i+1 < len(query) {
+				switch two := query[i : i+2]; two {
+				case "<=", ">=", "<>", "!=", "==", "||":
+					tokens = append(tokens, sqlToken{kind: sqlSymbol, text: two, pos: i, end: i + 2})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("=<>+-*/%(),", rune(c)) {
+				return nil, &QueryError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: string(c), pos: i, end: i + 1})
+			i++
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlEOF, pos: len(query), end: len(query)}), nil
+}
+
+// sqlKeywords can not be used as bare column names or aliases.
+var sqlKeywords = map[string]bool{
+	"select": true, "distinct": true, "from": true, "where": true, "group": true, "by": true,
+	"having": true, "order": true, "asc": true, "desc": true, "limit": true, "offset": true,
+	"as": true, "and": true, "or": true, "not": true, "in": true, "like": true, "between": true,
+	"is": true, "null": true, "true": true, "false": true,
+}
+
+type sqlNodeKind uint
+
+const (
+	nodeLiteral sqlNodeKind = iota
+	nodeColumn
+	nodeUnary
+	nodeBinary
+	nodeCall
+	nodeAggregate
+	nodeIn
+	nodeBetween
+	nodeIsNull
+	nodeLike
+)
+
+// sqlNode is an expression of a query.
+type sqlNode struct {
+	kind     sqlNodeKind
+	op       string // operator, or lower case function name
+	value    any    // literal value
+	name     string // column name
+	args     []*sqlNode
+	not      bool // NOT IN, NOT LIKE, NOT BETWEEN, IS NOT NULL
+	star     bool // count(*)
+	distinct bool // aggregate of distinct values
+	pos, end int  // source span
+	pattern  *regexp.Regexp
+}
+
+type selectField struct {
+	expr  *sqlNode
+	alias string
+	star  bool
+}
+
+type orderField struct {
+	expr *sqlNode
+	desc bool
+}
+
+type selectStmt struct {
+	query    string
+	distinct bool
+	fields   []*selectField
+	from     string
+	where    *sqlNode
+	groupBy  []*sqlNode
+	having   *sqlNode
+	orderBy  []*orderField
+	limit    int
+	offset   int
+}
+
+type sqlParser struct {
+	query  string
+	tokens []sqlToken
+	next   int
+}
+
+func parseSelect(query string) (*selectStmt, error) {
+	tokens, err := lexSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{query: query, tokens: tokens}
+	stmt, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != sqlEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return stmt, nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.next]
+}
+
+func (p *sqlParser) advance() sqlToken {
+	tok := p.tokens[p.next]
+	if tok.kind != sqlEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *sqlParser) errorf(tok sqlToken, format string, args ...any) *QueryError {
+	return &QueryError{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *sqlParser) isKeyword(offset int, word string) bool {
+	if p.next+offset >= len(p.tokens) {
+		return false
+	}
+	tok := p.tokens[p.next+offset]
+	return tok.kind == sqlIdent && strings.EqualFold(tok.text, word)
+}
+
+// keyword consumes the keywords if they come next.
+func (p *sqlParser) keyword(words ...string) bool {
+	for i, word := range words {
+		if !p.isKeyword(i, word) {
+			return false
+		}
+	}
+	p.next += len(words)
+	return true
+}
+
+func (p *sqlParser) expectKeyword(words ...string) error {
+	if !p.keyword(words...) {
+		return p.errorf(p.peek(), "expected %s, found %s", strings.ToUpper(strings.Join(words, " ")), p.peek())
+	}
+	return nil
+}
+
+func (p *sqlParser) symbol(text string) bool {
+	if tok := p.peek(); tok.kind == sqlSymbol && tok.text == text {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expectSymbol(text string) error {
+	if !p.symbol(text) {
+		return p.errorf(p.peek(), "expected %q, found %s", text, p.peek())
+	}
+	return nil
+}
+
+// identifier consumes a column name or alias.
+func (p *sqlParser) identifier() (string, bool) {
+	tok := p.peek()
+	if tok.kind == sqlQuotedIdent || tok.kind == sqlIdent && !sqlKeywords[strings.ToLower(tok.text)] {
+		p.next++
+		return tok.text, true
+	}
+	return "", false
+}
+
+func (p *sqlParser) parseSelect() (*selectStmt, error) {
+	stmt := &selectStmt{query: p.query, limit: -1}
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+	stmt.distinct = p.keyword("distinct")
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		stmt.fields = append(stmt.fields, field)
+		if !p.symbol(",") {
+			break
+		}
+	}
+	if p.keyword("from") {
+		name, ok := p.identifier()
+		if !ok {
+			return nil, p.errorf(p.peek(), "expected table name, found %s", p.peek())
+		}
+		stmt.from = name
+	}
+	var err error
+	if p.keyword("where") {
+		if stmt.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("group", "by") {
+		if stmt.groupBy, err = p.parseList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("having") {
+		if stmt.having, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("order", "by") {
+		for {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			field := &orderField{expr: expr}
+			if p.keyword("desc") {
+				field.desc = true
+			} else {
+				p.keyword("asc")
+			}
+			stmt.orderBy = append(stmt.orderBy, field)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("limit") {
+		if stmt.limit, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("offset") {
+		if stmt.offset, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+func (p *sqlParser) parseField() (*selectField, error) {
+	if p.symbol("*") {
+		return &selectField{star: true}, nil
+	}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	field := &selectField{expr: expr}
+	if p.keyword("as") {
+		alias, ok := p.identifier()
+		if !ok {
+			return nil, p.errorf(p.peek(), "expected alias, found %s", p.peek())
+		}
+		field.alias = alias
+	} else if alias, ok := p.identifier(); ok {
+		field.alias = alias
+	}
+	return field, nil
+}
+
+func (p *sqlParser) parseList() ([]*sqlNode, error) {
+	var list []*sqlNode
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, expr)
+		if !p.symbol(",") {
+			return list, nil
+		}
+	}
+}
+
+func (p *sqlParser) parseCount() (int, error) {
+	tok := p.advance()
+	n, err := strconv.Atoi(tok.text)
+	if tok.kind != sqlNumber || err != nil || n < 0 {
+		return 0, p.errorf(tok, "expected a non negative integer, found %s", tok)
+	}
+	return n, nil
+}
+
+func (p *sqlParser) node(kind sqlNodeKind, op string, start int, args ...*sqlNode) *sqlNode {
+	return &sqlNode{kind: kind, op: op, args: args, pos: start, end: p.tokens[p.next-1].end}
+}
+
+func (p *sqlParser) parseExpr() (*sqlNode, error) {
+	return p.parseBinary(0)
+}
+
+// sqlPrecedence lists the binary operators from the loosest binding.
+var sqlPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	nil, // NOT and comparisons
+	{"+", "-", "||"},
+	{"*", "/", "%"},
+}
+
+func (p *sqlParser) parseBinary(level int) (*sqlNode, error) {
+	switch level {
+	case 2:
+		return p.parseNot()
+	case len(sqlPrecedence):
+		return p.parseUnary()
+	}
+	start := p.peek().pos
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range sqlPrecedence[level] {
+			if p.symbol(candidate) || p.keyword(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = p.node(nodeBinary, op, start, left, right)
+	}
+}
+
+func (p *sqlParser) parseNot() (*sqlNode, error) {
+	start := p.peek().pos
+	if p.keyword("not") {
+		arg, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return p.node(nodeUnary, "not", start, arg), nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (*sqlNode, error) {
+	start := p.peek().pos
+	left, err := p.parseBinary(3)
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind == sqlSymbol {
+		switch tok.text {
+		case "=", "==", "!=", "<>", "<", "<=", ">", ">=":
+			p.next++
+			right, err := p.parseBinary(3)
+			if err != nil {
+				return nil, err
+			}
+			op := map[string]string{"==": "=", "<>": "!="}[tok.text]
+			if op == "" {
+				op = tok.text
+			}
+			return p.node(nodeBinary, op, start, left, right), nil
+		}
+		return left, nil
+	}
+	if p.keyword("is") {
+		not := p.keyword("not")
+		if err := p.expectKeyword("null"); err != nil {
+			return nil, err
+		}
+		node := p.node(nodeIsNull, "is null", start, left)
+		node.not = not
+		return node, nil
+	}
+	not := p.isKeyword(0, "not") && (p.isKeyword(1, "like") || p.isKeyword(1, "in") || p.isKeyword(1, "between"))
+	if not {
+		p.next++
+	}
+	var node *sqlNode
+	switch {
+	case p.keyword("like"):
+		pattern, err := p.parseBinary(3)
+		if err != nil {
+			return nil, err
+		}
+		node = p.node(nodeLike, "like", start, left, pattern)
+		if pattern.kind == nodeLiteral {
+			node.pattern = likePattern(ToString(pattern.value))
+		}
+	case p.keyword("in"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		node = p.node(nodeIn, "in", start, append([]*sqlNode{left}, list...)...)
+	case p.keyword("between"):
+		low, err := p.parseBinary(3)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("and"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseBinary(3)
+		if err != nil {
+			return nil, err
+		}
+		node = p.node(nodeBetween, "between", start, left, low, high)
+	default:
+		return left, nil
+	}
+	node.not = not
+	return node, nil
+}
+
+func (p *sqlParser) parseUnary() (*sqlNode, error) {
+	start := p.peek().pos
+	if p.symbol("-") || p.symbol("+") {
+		op := p.tokens[p.next-1].text
+		arg, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return arg, nil
+		}
+		return p.node(nodeUnary, "-", start, arg), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (*sqlNode, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case sqlNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %s", tok)
+		}
+		node := p.node(nodeLiteral, "", tok.pos)
+		node.value = value
+		return node, nil
+	case sqlString:
+		node := p.node(nodeLiteral, "", tok.pos)
+		node.value = tok.text
+		return node, nil
+	case sqlQuotedIdent:
+		node := p.node(nodeColumn, "", tok.pos)
+		node.name = tok.text
+		return node, nil
+	case sqlSymbol:
+		if tok.text == "(" {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expectSymbol(")")
+		}
+	case sqlIdent:
+		word := strings.ToLower(tok.text)
+		switch word {
+		case "null", "true", "false":
+			node := p.node(nodeLiteral, "", tok.pos)
+			node.value = map[string]any{"null": nil, "true": true, "false": false}[word]
+			return node, nil
+		}
+		if p.peek().kind == sqlSymbol && p.peek().text == "(" {
+			return p.parseCall(tok)
+		}
+		if !sqlKeywords[word] {
+			node := p.node(nodeColumn, "", tok.pos)
+			node.name = tok.text
+			return node, nil
+		}
+	}
+	return nil, p.errorf(tok, "unexpected %s", tok)
+}
+
+func (p *sqlParser) parseCall(name sqlToken) (*sqlNode, error) {
+	p.next++ // (
+	fn := strings.ToLower(name.text)
+	node := &sqlNode{kind: nodeCall, op: fn, pos: name.pos}
+	_, aggregate := aggregates[fn]
+	scalar, isScalar := sqlFunctions[fn]
+	switch {
+	case aggregate:
+		node.kind = nodeAggregate
+		node.distinct = p.keyword("distinct")
+		if fn == "count" && p.symbol("*") {
+			node.star = true
+		}
+	case !isScalar:
+		return nil, p.errorf(name, "unknown function %s", name.text)
+	}
+	if !node.star && !(p.peek().kind == sqlSymbol && p.peek().text == ")") {
+		args, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		node.args = args
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	node.end = p.tokens[p.next-1].end
+	count := len(node.args)
+	if aggregate {
+		params := aggregates[fn].params
+		if !node.star && count != 1+params {
+			return nil, p.errorf(name, "%s expects %d arguments, found %d", fn, 1+params, count)
+		}
+		for _, param := range node.args[min(count, 1):] {
+			if param.kind != nodeLiteral {
+				return nil, &QueryError{Pos: param.pos, Msg: fmt.Sprintf("%s expects a constant parameter", fn)}
+			}
+		}
+	} else if count < scalar.min || scalar.max >= 0 && count > scalar.max {
+		return nil, p.errorf(name, "%s expects %s arguments, found %d", fn, scalar.arity(), count)
+	}
+	return node, nil
+}
+
+type sqlFunction struct {
+	min, max int // max < 0 for any number of arguments
+	fn       func(args []any) any
+}
+
+func (f sqlFunction) arity() string {
+	switch {
+	case f.max < 0:
+		return fmt.Sprintf("at least %d", f.min)
+	case f.min == f.max:
+		return strconv.Itoa(f.min)
+	}
+	return fmt.Sprintf("%d to %d", f.min, f.max)
+}
+
+var sqlFunctions = map[string]sqlFunction{
+	"lower":  {1, 1, func(args []any) any { return strings.ToLower(ToString(args[0])) }},
+	"upper":  {1, 1, func(args []any) any { return strings.ToUpper(ToString(args[0])) }},
+	"trim":   {1, 1, func(args []any) any { return strings.TrimSpace(ToString(args[0])) }},
+	"length": {1, 1, func(args []any) any { return len([]rune(ToString(args[0]))) }},
+	"coalesce": {1, -1, func(args []any) any {
+		for _, arg := range args {
+			if arg != nil {
+				return arg
+			}
+		}
+		return nil
+	}},
+	"abs": {1, 1, func(args []any) any {
+		if n, ok := toNumber(args[0]); ok {
+			return math.Abs(n)
+		}
+		return nil
+	}},
+	"round": {1, 2, func(args []any) any {
+		n, ok := toNumber(args[0])
+		if !ok {
+			return nil
+		}
+		scale := 1.0
+		if len(args) == 2 {
+			places, _ := toNumber(args[1])
+			scale = math.Pow(10, math.Trunc(places))
+		}
+		return math.Round(n*scale) / scale
+	}},
+}
+
+// sqlEnv is what expressions are evaluated against: a row, and the rows of
+// its group when aggregating. Aliases of the selected columns are visible
+// in HAVING and ORDER BY.
+type sqlEnv struct {
+	row     map[string]any
+	group   []map[string]any
+	aliases map[string]any
+}
+
+func (n *sqlNode) eval(env *sqlEnv) any {
+	switch n.kind {
+	case nodeLiteral:
+		return n.value
+	case nodeColumn:
+		if value, ok := env.aliases[n.name]; ok {
+			return value
+		}
+		return env.row[n.name]
+	case nodeUnary:
+		arg := n.args[0].eval(env)
+		if n.op == "not" {
+			return !truthy(arg)
+		}
+		if x, ok := toNumber(arg); ok {
+			return -x
+		}
+		return nil
+	case nodeBinary:
+		return n.binary(env)
+	case nodeCall:
+		args := make([]any, len(n.args))
+		for i, arg := range n.args {
+			args[i] = arg.eval(env)
+		}
+		return sqlFunctions[n.op].fn(args)
+	case nodeAggregate:
+		return n.aggregate(env)
+	case nodeIn:
+		value := n.args[0].eval(env)
+		found := false
+		for _, item := range n.args[1:] {
+			if c, ok := compareValues(value, item.eval(env)); ok && c == 0 {
+				found = true
+				break
+			}
+		}
+		return found != n.not
+	case nodeBetween:
+		value := n.args[0].eval(env)
+		low, ok1 := compareValues(value, n.args[1].eval(env))
+		high, ok2 := compareValues(value, n.args[2].eval(env))
+		return (ok1 && ok2 && low >= 0 && high <= 0) != n.not
+	case nodeIsNull:
+		return (n.args[0].eval(env) == nil) != n.not
+	case nodeLike:
+		value := n.args[0].eval(env)
+		if value == nil {
+			return false
+		}
+		pattern := n.pattern
+		if pattern == nil {
+			pattern = likePattern(ToString(n.args[1].eval(env)))
+		}
+		return pattern.MatchString(ToString(value)) != n.not
+	}
+	return nil
+}
+
+func (n *sqlNode) binary(env *sqlEnv) any {
+	switch n.op {
+	case "and":
+		return truthy(n.args[0].eval(env)) && truthy(n.args[1].eval(env))
+	case "or":
+		return truthy(n.args[0].eval(env)) || truthy(n.args[1].eval(env))
+	}
+	a, b := n.args[0].eval(env), n.args[1].eval(env)
+	switch n.op {
+	case "=", "!=", "<", "<=", ">", ">=":
+		c, ok := compareValues(a, b)
+		if !ok {
+			return n.op == "!=" && (a == nil) != (b == nil)
+		}
+		switch n.op {
+		case "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		}
+		return c >= 0
+	case "||":
+		if a == nil || b == nil {
+			return nil
+		}
+		return ToString(a) + ToString(b)
+	}
+	x, ok1 := toNumber(a)
+	y, ok2 := toNumber(b)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	switch n.op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		if y == 0 {
+			return nil
+		}
+		return x / y
+	case "%":
+		if y == 0 {
+			return nil
+		}
+		return math.Mod(x, y)
+	}
+	return nil
+}
+
+func (n *sqlNode) aggregate(env *sqlEnv) any {
+	agg := aggregates[n.op]
+	if n.star {
+		return len(env.group)
+	}
+	values := make([]any, 0, len(env.group))
+	seen := map[string]bool{}
+	for _, row := range env.group {
+		value := n.args[0].eval(&sqlEnv{row: row})
+		if value == nil {
+			continue
+		}
+		if n.distinct {
+			key := valueKey(value)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		values = append(values, value)
+	}
+	params := make([]any, len(n.args)-1)
+	for i, param := range n.args[1:] {
+		params[i] = param.value
+	}
+	return agg.fn(values, params)
+}
+
+// walk calls visit on n and its descendants, stopping at nodes for which
+// visit returns false.
+func (n *sqlNode) walk(visit func(*sqlNode) bool) {
+	if n == nil || !visit(n) {
+		return
+	}
+	for _, arg := range n.args {
+		arg.walk(visit)
+	}
+}
+
+func (n *sqlNode) hasAggregate() bool {
+	found := false
+	n.walk(func(node *sqlNode) bool {
+		found = found || node.kind == nodeAggregate
+		return !found
+	})
+	return found
+}
+
+// likePattern converts a LIKE pattern, where % matches any run of
+// characters and _ a single one, to a regular expression.
+func likePattern(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			expr.WriteString(".*")
+		case '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if n, ok := FormatFloat(value); ok {
+		return n != 0
+	}
+	return true
+}
+
+// toNumber converts numbers and numeric strings to float64.
+func toNumber(value any) (float64, bool) {
+	if n, ok := FormatFloat(value); ok {
+		return n, true
+	}
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	case []byte:
+		n, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// compareValues orders two values: numerically if both are numbers or
+// numeric strings, chronologically if both are times, and as strings
+// otherwise. Nil is not comparable.
+func compareValues(a, b any) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+	}
+	if x, ok := a.(bool); ok {
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)), true
+}
+
+// valueKey identifies a value for grouping: numbers equal in value share a
+// key whatever their Go type.
+func valueKey(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "s:" + v
+	case time.Time:
+		return "t:" + v.UTC().Format(time.RFC3339Nano)
+	}
+	if n, ok := FormatFloat(value); ok {
+		return "n:" + strconv.FormatFloat(n, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%T:%v", value, value)
+}
@@ -39,6 +39,7 @@ func (dt *DataTable) wheres(query string) *DataTable {
 	}
 	dataTable.Name = dt.Name
 	dataTable.Columns = dt.Columns
+	dataTable.inheritIndexes(dt)
 	return dataTable
 }
 
@@ -117,7 +118,7 @@ func (dt *DataTable) contrast(w *Wheres) *DataTable {
 		regx = true
 	}
 	input := rhStr
-	for _, row := range dt.Rows {
+	for _, row := range dt.whereCandidates(lhStr, w.Op, rhStr) {
 		if value, yes := row[lhStr]; yes {
 			if like {
 				var ok bool
@@ -0,0 +1,176 @@
+package datatable
+
+import (
+	"slices"
+	"sort"
+)
+
+// WindowFunc is a column computed by Window for each row from the rows of
+// its partition.
+type WindowFunc struct {
+	fn     string
+	column string
+	alias  string
+	offset int
+	def    any
+}
+
+// RowNumber numbers the rows of a partition from 1.
+func RowNumber() WindowFunc { return WindowFunc{fn: "row_number"} }
+
+// Rank ranks the rows of a partition from 1, giving rows equal by the order
+// the same rank and skipping the ranks after them.
+func Rank() WindowFunc { return WindowFunc{fn: "rank"} }
+
+// DenseRank is Rank without gaps after rows of equal rank.
+func DenseRank() WindowFunc { return WindowFunc{fn: "dense_rank"} }
+
+// RunningSum adds up the numeric values of column up to the row.
+func RunningSum(column string) WindowFunc {
+	return WindowFunc{fn: "running_sum", column: column}
+}
+
+// RunningAvg averages the numeric values of column up to the row.
+func RunningAvg(column string) WindowFunc {
+	return WindowFunc{fn: "running_avg", column: column}
+}
+
+// Lag returns the value of column offset rows before the row, or def.
+func Lag(column string, offset int, def any) WindowFunc {
+	return WindowFunc{fn: "lag", column: column, offset: offset, def: def}
+}
+
+// Lead returns the value of column offset rows after the row, or def.
+func Lead(column string, offset int, def any) WindowFunc {
+	return WindowFunc{fn: "lead", column: column, offset: offset, def: def}
+}
+
+// As names the result column, which defaults to the function name followed
+// by the column name, like running_sum_amount.
+func (f WindowFunc) As(name string) WindowFunc {
+	f.alias = name
+	return f
+}
+
+// Name returns the name of the result column.
+func (f WindowFunc) Name() string {
+	switch {
+	case f.alias != "":
+		return f.alias
+	case f.column == "":
+		return f.fn
+	}
+	return f.fn + "_" + f.column
+}
+
+func (f WindowFunc) resultColumn() *Column {
+	switch f.fn {
+	case "row_number", "rank", "dense_rank":
+		return &Column{Name: f.Name(), Type: TypeInt}
+	case "running_sum", "running_avg":
+		return &Column{Name: f.Name(), Type: TypeFloat, Nullable: f.fn == "running_avg"}
+	}
+	return &Column{Name: f.Name(), Nullable: true}
+}
+
+// apply computes the column of f for the rows of a partition, sorted by
+// the order, into out; peer reports whether a row is equal by the order to
+// the previous one.
+func (f WindowFunc) apply(rows []map[string]any, out []any, peer func(i int) bool) {
+	rank, dense := 0, 0
+	sum, count := 0.0, 0
+	for i, row := range rows {
+		switch f.fn {
+		case "row_number":
+			out[i] = i + 1
+		case "rank", "dense_rank":
+			if i == 0 || !peer(i) {
+				rank = i + 1
+				dense++
+			}
+			if f.fn == "rank" {
+				out[i] = rank
+			} else {
+				out[i] = dense
+			}
+		case "running_sum", "running_avg":
+			if n, ok := toNumber(row[f.column]); ok {
+				sum += n
+				count++
+			}
+			if f.fn == "running_sum" {
+				out[i] = sum
+			} else if count > 0 {
+				out[i] = sum / float64(count)
+			} else {
+				out[i] = nil
+			}
+		case "lag", "lead":
+			j := i - f.offset
+			if f.fn == "lead" {
+				j = i + f.offset
+			}
+			if j >= 0 && j < len(rows) {
+				out[i] = rows[j][f.column]
+			} else {
+				out[i] = f.def
+			}
+		}
+	}
+}
+
+// Window adds a column per function to the rows of dt, computed within the
+// partitions of rows with equal values of partitionBy, taken in the order
+// of orderBy, written like for OrderBy. The rows keep their order. Lag and
+// lead read the values of the rows before the functions are applied.
+func (dt *DataTable) Window(partitionBy []string, orderBy string, fns ...WindowFunc) *DataTable {
+	order := OrderBy([]byte(orderBy)).OrderExpr
+	compare := func(a, b map[string]any) int {
+		for _, item := range order {
+			c := compareTyped(a[item.Name], b[item.Name])
+			if item.Op == "desc" {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	partitions := [][]map[string]any{dt.Rows}
+	if len(partitionBy) > 0 {
+		partitions = groupRows(dt.Rows, func(row map[string]any) []any {
+			values := make([]any, len(partitionBy))
+			for i, name := range partitionBy {
+				values[i] = row[name]
+			}
+			return values
+		})
+	}
+	for _, partition := range partitions {
+		sorted := make([]map[string]any, len(partition))
+		copy(sorted, partition)
+		sort.SliceStable(sorted, func(i, j int) bool { return compare(sorted[i], sorted[j]) < 0 })
+		peer := func(i int) bool { return compare(sorted[i-1], sorted[i]) == 0 }
+		computed := make([][]any, len(fns))
+		for k, f := range fns {
+			computed[k] = make([]any, len(sorted))
+			f.apply(sorted, computed[k], peer)
+		}
+		for k, f := range fns {
+			for i, row := range sorted {
+				row[f.Name()] = computed[k][i]
+			}
+		}
+	}
+	dt.version++
+	for _, f := range fns {
+		column := f.resultColumn()
+		if i := slices.IndexFunc(dt.Columns, func(c *Column) bool { return c.Name == column.Name }); i >= 0 {
+			dt.Columns[i] = column
+		} else {
+			dt.Columns = append(dt.Columns, column)
+		}
+	}
+	return dt
+}
@@ -0,0 +1,479 @@
+package datatable
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is the text of a shared or inline string, which may be split in
+// runs of rich text.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var s strings.Builder
+	for _, run := range t.Runs {
+		s.WriteString(run.T)
+	}
+	return s.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxCell struct {
+	Ref    string    `xml:"r,attr"`
+	Type   string    `xml:"t,attr"`
+	Style  int       `xml:"s,attr"`
+	Value  string    `xml:"v"`
+	Inline *xlsxText `xml:"is"`
+}
+
+// xlsxFile is a workbook being read.
+type xlsxFile struct {
+	files   map[string]*zip.File
+	strings []string
+	// dates lists the styles formatting numbers as dates.
+	dates map[int]bool
+	epoch time.Time
+}
+
+func (x *xlsxFile) decode(name string, v any) error {
+	f, ok := x.files[name]
+	if !ok {
+		return fmt.Errorf("datatable: xlsx part %s missing", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// FromXLSX reads a table from the first sheet of an XLSX workbook, or the
+// one named by the Sheet option, with a header row unless NoHeader is set.
+// Numbers formatted as dates are read as times.
+func FromXLSX(r io.Reader, opts ...ReadOptions) (*DataTable, error) {
+	o := readOptions(opts)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("datatable: reading xlsx: %w", err)
+	}
+	x := &xlsxFile{files: map[string]*zip.File{}, dates: map[int]bool{}}
+	for _, f := range zr.File {
+		x.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := x.decode("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	x.epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if workbook.Properties.Date1904 == "1" || workbook.Properties.Date1904 == "true" {
+		x.epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	sheetID := ""
+	for _, sheet := range workbook.Sheets {
+		if o.Sheet == "" || sheet.Name == o.Sheet {
+			sheetID = sheet.ID
+			break
+		}
+	}
+	if sheetID == "" {
+		return nil, fmt.Errorf("datatable: xlsx sheet %q not found", o.Sheet)
+	}
+	var rels xlsxRelationships
+	if err := x.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == sheetID {
+			sheetPath = strings.TrimPrefix(rel.Target, "/")
+			if !strings.HasPrefix(sheetPath, "xl/") {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+
+	if _, ok := x.files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := x.decode("xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for i := range sst.Items {
+			x.strings = append(x.strings, sst.Items[i].String())
+		}
+	}
+	if _, ok := x.files["xl/styles.xml"]; ok {
+		var styles xlsxStyles
+		if err := x.decode("xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+		custom := map[int]string{}
+		for _, f := range styles.NumFmts {
+			custom[f.ID] = f.Code
+		}
+		for i, xf := range styles.CellXfs {
+			code, ok := custom[xf.NumFmtID]
+			x.dates[i] = ok && isDateFormat(code) || !ok && isDateFormatID(xf.NumFmtID)
+		}
+	}
+	return x.readSheet(sheetPath, o)
+}
+
+func (x *xlsxFile) readSheet(name string, o ReadOptions) (*DataTable, error) {
+	f, ok := x.files[name]
+	if !ok {
+		return nil, fmt.Errorf("datatable: xlsx part %s missing", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	table := newTableReader(o)
+	var names []string
+	header := !o.NoHeader
+	var cells map[int]any
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("datatable: reading xlsx: %w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "row":
+				cells = map[int]any{}
+			case "c":
+				var cell xlsxCell
+				if err := decoder.DecodeElement(&cell, &tok); err != nil {
+					return nil, fmt.Errorf("datatable: reading xlsx: %w", err)
+				}
+				column := len(cells)
+				if cell.Ref != "" {
+					if column, err = cellColumn(cell.Ref); err != nil {
+						return nil, err
+					}
+				}
+				value, err := x.value(&cell)
+				if err != nil {
+					return nil, err
+				}
+				cells[column] = value
+			}
+		case xml.EndElement:
+			if tok.Name.Local != "row" {
+				continue
+			}
+			width := 0
+			for column := range cells {
+				width = max(width, column+1)
+			}
+			if header {
+				text := make([]string, width)
+				for column, value := range cells {
+					text[column] = formatValue(value, time.RFC3339)
+				}
+				names = o.headerNames(text)
+				header = false
+				continue
+			}
+			row := make(map[string]any, len(cells))
+			for column := 0; column < width; column++ {
+				for column >= len(names) {
+					names = append(names, o.columnName(len(names)))
+				}
+				if value, ok := cells[column]; ok {
+					row[names[column]] = value
+				}
+			}
+			table.add(row)
+		}
+	}
+	table.names = names
+	return table.finish()
+}
+
+func (x *xlsxFile) value(cell *xlsxCell) (any, error) {
+	switch cell.Type {
+	case "s":
+		i, err := strconv.Atoi(cell.Value)
+		if err != nil || i < 0 || i >= len(x.strings) {
+			return nil, fmt.Errorf("datatable: xlsx cell %s: invalid shared string %q", cell.Ref, cell.Value)
+		}
+		return x.strings[i], nil
+	case "inlineStr":
+		if cell.Inline == nil {
+			return "", nil
+		}
+		return cell.Inline.String(), nil
+	case "str", "e":
+		return cell.Value, nil
+	case "b":
+		return cell.Value == "1", nil
+	}
+	if cell.Value == "" {
+		return nil, nil
+	}
+	if !x.dates[cell.Style] {
+		if n, err := strconv.ParseInt(cell.Value, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	f, err := strconv.ParseFloat(cell.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("datatable: xlsx cell %s: invalid number %q", cell.Ref, cell.Value)
+	}
+	if x.dates[cell.Style] {
+		days := math.Floor(f)
+		return x.epoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round((f-days)*86400e3)) * time.Millisecond), nil
+	}
+	return f, nil
+}
+
+// cellColumn returns the index of the column of a cell reference like AB12.
+func cellColumn(ref string) (int, error) {
+	column := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		column = column*26 + int(ref[i]-'A') + 1
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("datatable: invalid xlsx cell reference %q", ref)
+	}
+	return column - 1, nil
+}
+
+func columnLetters(column int) string {
+	var letters []byte
+	for column++; column > 0; column = (column - 1) / 26 {
+		letters = append([]byte{byte('A' + (column-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// isDateFormatID reports whether a built-in number format shows dates.
+func isDateFormatID(id int) bool {
+	return id >= 14 && id <= 22 || id >= 27 && id <= 36 || id >= 45 && id <= 47 || id >= 50 && id <= 58
+}
+
+// isDateFormat reports whether a custom number format shows dates: it has
+// date or time parts outside quoted text, escapes and brackets.
+func isDateFormat(code string) bool {
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			for i++; i < len(code) && code[i] != '"'; i++ {
+			}
+		case '[':
+			for i++; i < len(code) && code[i] != ']'; i++ {
+			}
+		case '\\', '_', '*':
+			i++
+		case 'y', 'Y', 'm', 'M', 'd', 'D', 'h', 'H', 's', 'S':
+			if strings.HasPrefix(strings.ToLower(code[i:]), "general") {
+				i += len("general") - 1
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+const xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`},
+}
+
+// xlsxDateStyle is the index of the date style of xl/styles.xml.
+const xlsxDateStyle = 1
+
+// WriteXLSX writes the table as an XLSX workbook of one sheet named after
+// the table, a row at a time. Times are written as dates in their own
+// time zone; arrays and objects are written as JSON text.
+func (dt *DataTable) WriteXLSX(w io.Writer, opts ...WriteOptions) error {
+	o := writeOptions(opts)
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writeZipPart(zw, part.name, part.content); err != nil {
+			return err
+		}
+	}
+	var name bytes.Buffer
+	xml.EscapeText(&name, []byte(sheetName(dt.Name)))
+	workbook := `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writeZipPart(zw, "xl/workbook.xml", workbook); err != nil {
+		return err
+	}
+
+	part, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sheet := bufio.NewWriter(part)
+	sheet.WriteString(xlsxHeader + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	names, header := dt.header(o)
+	line := 1
+	if !o.NoHeader {
+		values := make(map[string]any, len(names))
+		for i, name := range names {
+			values[name] = header[i]
+		}
+		writeXLSXRow(sheet, line, names, values)
+		line++
+	}
+	for _, row := range dt.Rows {
+		writeXLSXRow(sheet, line, names, row)
+		line++
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+	if err := sheet.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZipPart(zw *zip.Writer, name, content string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, xlsxHeader+content)
+	return err
+}
+
+func writeXLSXRow(w *bufio.Writer, line int, names []string, row map[string]any) {
+	fmt.Fprintf(w, `<row r="%d">`, line)
+	for i, name := range names {
+		value := row[name]
+		if value == nil {
+			continue
+		}
+		ref := columnLetters(i) + strconv.Itoa(line)
+		switch v := value.(type) {
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(w, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+			continue
+		case time.Time:
+			fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxDateStyle, strconv.FormatFloat(excelSerial(v), 'f', -1, 64))
+			continue
+		}
+		if n, ok := FormatFloat(value); ok && !math.IsInf(n, 0) && !math.IsNaN(n) {
+			fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, ToString(value))
+			continue
+		}
+		fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xml.EscapeText(w, []byte(formatValue(value, time.RFC3339)))
+		w.WriteString(`</t></is></c>`)
+	}
+	w.WriteString(`</row>`)
+}
+
+// excelSerial returns the days since the 1900 epoch of Excel to the wall
+// clock time of t.
+func excelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	seconds := wall.Unix() - time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Unix()
+	return float64(seconds)/86400 + float64(wall.Nanosecond())/86400e9
+}
+
+// sheetName makes a valid sheet name of a table name.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
@@ -347,14 +347,17 @@ func getReflectValue(value reflect.Value, attribute string, sep string, toSet bo
 		case reflect.Struct:
 			field, ok := value.Type().FieldByName(fieldName)
 			if !ok {
-				return value, "", ErrNotFound
+				// fall back to the name used in the json tag
+				if field, ok = fieldByJSONName(value.Type(), fieldName); !ok {
+					return value, "", ErrNotFound
+				}
 			}
 			// Check if field is unexported (method IsExported() was introduced in Go 1.17)
 			if field.PkgPath != "" {
 				return value, "", ErrUnexported
 			}
 
-			value = value.FieldByName(fieldName)
+			value = value.FieldByIndex(field.Index)
 
 		case reflect.Slice, reflect.Array:
 			sliceIndex, err := strconv.Atoi(fieldName)
@@ -374,3 +377,17 @@ func getReflectValue(value reflect.Value, attribute string, sep string, toSet bo
 
 	return value, fieldName, nil
 }
+
+// fieldByJSONName finds the exported field whose json tag names it name.
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, field := range reflect.VisibleFields(t) {
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
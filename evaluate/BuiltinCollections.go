@@ -0,0 +1,336 @@
+package evaluate
+
+import (
+	"math/bits"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/oarkflow/pkg/decimal"
+)
+
+// CollectionOperators work on arrays. Those taking a lambda call it with
+// the item and its index, e.g. map(items, x => x.price * x.qty) or
+// reduce(items, (sum, x) => sum + x, 0).
+func CollectionOperators() map[string]Operator {
+	return map[string]Operator{
+		"map":    builtinMap,
+		"filter": builtinFilter,
+		"reduce": builtinReduce,
+		"any":    builtinAny,
+		"all":    builtinAll,
+		"sum":    builtinSumOf,
+		"avg":    builtinAvg,
+		"sort":   builtinSort,
+		"unique": builtinUnique,
+	}
+}
+
+// sliceAndLambda reads an array and a lambda, the lambda being optional
+// when optional is set.
+func sliceAndLambda(ctx EvalContext, optional bool) ([]interface{}, *Lambda, error) {
+	if optional {
+		if err := ctx.checkArgRange(1, 2); err != nil {
+			return nil, nil, err
+		}
+	} else if err := ctx.CheckArgCount(2); err != nil {
+		return nil, nil, err
+	}
+	items, err := ctx.SliceArg(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ctx.ArgCount() == 1 {
+		return items, nil, nil
+	}
+	fn, err := ctx.LambdaArg(1)
+	return items, fn, err
+}
+
+// each calls fn on every item and passes the result to visit, which stops
+// the iteration by returning false.
+func each(ctx EvalContext, items []interface{}, fn *Lambda, visit func(item, result interface{}) bool) error {
+	if err := ctx.Charge(int64(len(items)), 0); err != nil {
+		return err
+	}
+	for i, item := range items {
+		result := normalize(item)
+		if fn != nil {
+			var err error
+			if result, err = fn.Call(item, float64(i)); err != nil {
+				return err
+			}
+		}
+		if !visit(item, result) {
+			break
+		}
+	}
+	return nil
+}
+
+func builtinMap(ctx EvalContext) (interface{}, error) {
+	items, fn, err := sliceAndLambda(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	mapped := make([]interface{}, 0, len(items))
+	err = each(ctx, items, fn, func(_, result interface{}) bool {
+		mapped = append(mapped, result)
+		return true
+	})
+	return mapped, err
+}
+
+// predicate calls fn for every item and fails on non boolean results.
+func predicate(ctx EvalContext, items []interface{}, fn *Lambda, visit func(item interface{}, ok bool) bool) error {
+	var typeErr error
+	err := each(ctx, items, fn, func(item, result interface{}) bool {
+		ok, isBool := result.(bool)
+		if !isBool {
+			typeErr = formatArgError(ctx.expr, 1, "is not boolean: %v", result)
+			return false
+		}
+		return visit(item, ok)
+	})
+	if err != nil {
+		return err
+	}
+	return typeErr
+}
+
+func builtinFilter(ctx EvalContext) (interface{}, error) {
+	items, fn, err := sliceAndLambda(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]interface{}, 0)
+	err = predicate(ctx, items, fn, func(item interface{}, ok bool) bool {
+		if ok {
+			filtered = append(filtered, item)
+		}
+		return true
+	})
+	return filtered, err
+}
+
+func builtinAny(ctx EvalContext) (interface{}, error) {
+	items, fn, err := sliceAndLambda(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	err = predicate(ctx, items, fn, func(_ interface{}, ok bool) bool {
+		found = ok
+		return !ok
+	})
+	return found, err
+}
+
+func builtinAll(ctx EvalContext) (interface{}, error) {
+	items, fn, err := sliceAndLambda(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	all := true
+	err = predicate(ctx, items, fn, func(_ interface{}, ok bool) bool {
+		all = ok
+		return ok
+	})
+	return all, err
+}
+
+// builtinReduce folds the items with a (accumulator, item) lambda, starting
+// from the optional initial value or the first item.
+func builtinReduce(ctx EvalContext) (interface{}, error) {
+	if err := ctx.checkArgRange(2, 3); err != nil {
+		return nil, err
+	}
+	items, err := ctx.SliceArg(0)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := ctx.LambdaArg(1)
+	if err != nil {
+		return nil, err
+	}
+	var acc interface{}
+	if ctx.ArgCount() == 3 {
+		if acc, err = ctx.Arg(2); err != nil {
+			return nil, err
+		}
+	} else if len(items) > 0 {
+		acc, items = normalize(items[0]), items[1:]
+	}
+	for i, item := range items {
+		if acc, err = fn.Call(acc, item, float64(i)); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// numbers collects the items, or the lambda results, as numbers.
+func numbers(ctx EvalContext) ([]interface{}, error) {
+	items, fn, err := sliceAndLambda(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(items))
+	var typeErr error
+	err = each(ctx, items, fn, func(_, result interface{}) bool {
+		switch result.(type) {
+		case float64, decimal.Decimal:
+			values = append(values, result)
+			return true
+		}
+		typeErr = formatArgError(ctx.expr, ctx.ArgCount()-1, "has a non numeric item: %v", result)
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, typeErr
+}
+
+// total adds numbers, exactly in decimal mode.
+func total(ctx EvalContext, values []interface{}) interface{} {
+	if ctx.params.Decimal != nil {
+		sum := decimal.Zero
+		for _, v := range values {
+			d, _ := toDecimal(v)
+			sum = sum.Add(d)
+		}
+		return sum
+	}
+	sum := 0.0
+	for _, v := range values {
+		switch v := v.(type) {
+		case float64:
+			sum += v
+		case decimal.Decimal:
+			sum += v.InexactFloat64()
+		}
+	}
+	return sum
+}
+
+func builtinSumOf(ctx EvalContext) (interface{}, error) {
+	values, err := numbers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return total(ctx, values), nil
+}
+
+func builtinAvg(ctx EvalContext) (interface{}, error) {
+	values, err := numbers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ctx.FormatError("average of an empty array")
+	}
+	sum := total(ctx, values)
+	if d, ok := sum.(decimal.Decimal); ok {
+		return ctx.params.Decimal.div(d, decimal.NewFromInt(int64(len(values)))), nil
+	}
+	return sum.(float64) / float64(len(values)), nil
+}
+
+// builtinSort sorts numbers, strings or dates in ascending order, by the
+// optional key lambda. The sort is stable.
+func builtinSort(ctx EvalContext) (interface{}, error) {
+	items, fn, err := sliceAndLambda(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, 0, len(items))
+	if err := each(ctx, items, fn, func(_, result interface{}) bool {
+		if d, ok := result.(decimal.Decimal); ok {
+			result = d.InexactFloat64()
+		}
+		keys = append(keys, result)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if reflect.TypeOf(key) != reflect.TypeOf(keys[0]) {
+			return nil, formatArgError(ctx.expr, ctx.ArgCount()-1, "has items of different types: %v, %v", keys[0], key)
+		}
+		switch key.(type) {
+		case float64, string, time.Time:
+		default:
+			return nil, formatArgError(ctx.expr, ctx.ArgCount()-1, "has an item that can not be sorted: %v", key)
+		}
+	}
+	// n log n comparisons
+	if err := ctx.Charge(int64(len(items))*int64(bits.Len(uint(len(items)))), 0); err != nil {
+		return nil, err
+	}
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		switch a := keys[order[i]].(type) {
+		case float64:
+			return a < keys[order[j]].(float64)
+		case string:
+			return a < keys[order[j]].(string)
+		case time.Time:
+			return a.Before(keys[order[j]].(time.Time))
+		}
+		return false
+	})
+	sorted := make([]interface{}, len(items))
+	for i, idx := range order {
+		sorted[i] = items[idx]
+	}
+	return sorted, nil
+}
+
+// builtinUnique removes duplicates, keeping the first occurrence.
+func builtinUnique(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(1); err != nil {
+		return nil, err
+	}
+	items, err := ctx.SliceArg(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Charge(int64(len(items)), 0); err != nil {
+		return nil, err
+	}
+	seen := make(map[interface{}]bool, len(items))
+	var others []interface{}
+	unique := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		key := normalize(item)
+		if d, ok := key.(decimal.Decimal); ok {
+			key = d.InexactFloat64()
+		}
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			if err := ctx.Charge(int64(len(others)), 0); err != nil {
+				return nil, err
+			}
+			duplicate := false
+			for _, other := range others {
+				if reflect.DeepEqual(other, item) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				others = append(others, item)
+				unique = append(unique, item)
+			}
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, item)
+		}
+	}
+	return unique, nil
+}
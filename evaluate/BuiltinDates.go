@@ -0,0 +1,183 @@
+package evaluate
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/pkg/dateparse"
+	"github.com/oarkflow/pkg/decimal"
+)
+
+func DateOperators() map[string]Operator {
+	return map[string]Operator{
+		"parse_date":  builtinParseDate,
+		"date_add":    builtinDateAdd,
+		"date_sub":    builtinDateSub,
+		"date_diff":   builtinDateDiff,
+		"date_format": builtinDateFormat,
+		"weekday":     builtinWeekday,
+	}
+}
+
+// DateArg accepts time.Time, date strings in any layout dateparse knows and
+// unix timestamps in seconds.
+func (ctx EvalContext) DateArg(idx int) (time.Time, error) {
+	val, err := ctx.Arg(idx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch v := val.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := dateparse.ParseAny(v)
+		if err != nil {
+			return time.Time{}, formatArgError(ctx.expr, idx, "is not a date: %v", err)
+		}
+		return t, nil
+	case float64:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case decimal.Decimal:
+		return time.Unix(v.IntPart(), 0).UTC(), nil
+	}
+	return time.Time{}, formatArgError(ctx.expr, idx, "is not a date: %v", val)
+}
+
+// builtinParseDate parses a date with dateparse, or with the optional Go
+// layout.
+func builtinParseDate(ctx EvalContext) (interface{}, error) {
+	if err := ctx.checkArgRange(1, 2); err != nil {
+		return nil, err
+	}
+	if ctx.ArgCount() == 1 {
+		return ctx.DateArg(0)
+	}
+	str, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(layout, str)
+	if err != nil {
+		return nil, formatArgError(ctx.expr, 0, "is not a date: %v", err)
+	}
+	return t, nil
+}
+
+var dayUnits = regexp.MustCompile(`([-+]?[0-9]*\.?[0-9]+)([dw])`)
+
+// parseDuration extends time.ParseDuration with days (d) and weeks (w).
+func parseDuration(str string) (time.Duration, error) {
+	var total time.Duration
+	rest := dayUnits.ReplaceAllStringFunc(str, func(match string) string {
+		parts := dayUnits.FindStringSubmatch(match)
+		n, _ := strconv.ParseFloat(parts[1], 64)
+		if parts[2] == "w" {
+			n *= 7
+		}
+		total += time.Duration(n * float64(24*time.Hour))
+		return ""
+	})
+	if rest = strings.TrimSpace(rest); rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, err
+		}
+		total += d
+	}
+	return total, nil
+}
+
+func (ctx EvalContext) durationArg(idx int) (time.Duration, error) {
+	val, err := ctx.Arg(idx)
+	if err != nil {
+		return 0, err
+	}
+	switch v := val.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := parseDuration(v)
+		if err != nil {
+			return 0, formatArgError(ctx.expr, idx, "is not a duration: %v", err)
+		}
+		return d, nil
+	}
+	return 0, formatArgError(ctx.expr, idx, "is not a duration: %v", val)
+}
+
+func dateShift(ctx EvalContext, sign time.Duration) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	t, err := ctx.DateArg(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ctx.durationArg(1)
+	if err != nil {
+		return nil, err
+	}
+	return t.Add(sign * d), nil
+}
+
+// builtinDateAdd adds a duration like "1h30m" or "3d" to a date.
+func builtinDateAdd(ctx EvalContext) (interface{}, error) {
+	return dateShift(ctx, 1)
+}
+
+func builtinDateSub(ctx EvalContext) (interface{}, error) {
+	return dateShift(ctx, -1)
+}
+
+// builtinDateDiff returns the number of whole days from the second date to
+// the first.
+func builtinDateDiff(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	a, err := ctx.DateArg(0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ctx.DateArg(1)
+	if err != nil {
+		return nil, err
+	}
+	return math.Trunc(a.Sub(b).Hours() / 24), nil
+}
+
+// builtinDateFormat formats a date with a Go layout, RFC 3339 by default.
+func builtinDateFormat(ctx EvalContext) (interface{}, error) {
+	if err := ctx.checkArgRange(1, 2); err != nil {
+		return nil, err
+	}
+	t, err := ctx.DateArg(0)
+	if err != nil {
+		return nil, err
+	}
+	layout := time.RFC3339
+	if ctx.ArgCount() == 2 {
+		if layout, err = ctx.StringArg(1); err != nil {
+			return nil, err
+		}
+	}
+	return t.Format(layout), nil
+}
+
+func builtinWeekday(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(1); err != nil {
+		return nil, err
+	}
+	t, err := ctx.DateArg(0)
+	if err != nil {
+		return nil, err
+	}
+	return t.Weekday().String(), nil
+}
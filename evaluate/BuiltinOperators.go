@@ -3,11 +3,14 @@ package evaluate
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"time"
+
+	"github.com/oarkflow/pkg/decimal"
 )
 
 func BuiltinOperators() map[string]Operator {
-	return map[string]Operator{
+	operators := map[string]Operator{
 		"==": builtinEq,
 		"!=": builtinNeq,
 		"<":  builtinLt,
@@ -62,7 +65,17 @@ func BuiltinOperators() map[string]Operator {
 		"current_date": builtinNowDate,
 		"now_time":     builtinNowTime,
 		"now_datetime": builtinNowDateTime,
+
+		".":  builtinMember,
+		"?.": builtinMember,
+		"=>": builtinLambda,
+	}
+	for _, library := range []map[string]Operator{StringOperators(), DateOperators(), CollectionOperators()} {
+		for name, op := range library {
+			operators[name] = op
+		}
 	}
+	return operators
 }
 
 func builtinNowDateTime(ctx EvalContext) (interface{}, error) {
@@ -83,30 +96,50 @@ func builtinNow(ctx EvalContext) (interface{}, error) {
 
 func builtinEq(ctx EvalContext) (interface{}, error) {
 	a, b, err := binaryArgs(ctx)
-	return a == b, err
+	return valuesEqual(a, b), err
 }
 
 func builtinNeq(ctx EvalContext) (interface{}, error) {
 	a, b, err := binaryArgs(ctx)
-	return a != b, err
+	return !valuesEqual(a, b), err
 }
 
 func builtinLt(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.LessThan(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a < b, err
 }
 
 func builtinLte(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.LessThanOrEqual(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a <= b, err
 }
 
 func builtinGt(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.GreaterThan(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a > b, err
 }
 
 func builtinGte(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.GreaterThanOrEqual(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a >= b, err
 }
@@ -142,12 +175,21 @@ func builtinLogicalNot(ctx EvalContext) (interface{}, error) {
 }
 
 func builtinSum(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.Add(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a + b, err
 }
 
 func builtinMinus(ctx EvalContext) (interface{}, error) {
 	if ctx.ArgCount() == 1 {
+		if ctx.params.Decimal != nil {
+			right, err := ctx.DecimalArg(0)
+			return right.Neg(), err
+		}
 		right, err := ctx.NumericArg(0)
 		return -right, err
 	}
@@ -156,26 +198,48 @@ func builtinMinus(ctx EvalContext) (interface{}, error) {
 }
 
 func builtinSub(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.Sub(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a - b, err
 }
 
 func builtinMul(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return a.Mul(b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a * b, err
 }
 
 func builtinDiv(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalDiv(ctx)
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return a / b, err
 }
 
 func builtinMod(ctx EvalContext) (interface{}, error) {
 	a, b, err := binaryIntegerArgs(ctx)
-	return float64(a % b), err
+	if err != nil {
+		return 0.0, err
+	}
+	if b == 0 {
+		return 0.0, ctx.FormatError("division by zero")
+	}
+	return float64(a % b), nil
 }
 
 func builtinPow(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalPow(ctx)
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return math.Pow(a, b), err
 }
@@ -268,43 +332,88 @@ func builtinContains(ctx EvalContext) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Charge(int64(len(slice)), 0); err != nil {
+		return nil, err
+	}
 	for _, v := range slice {
-		if item == v {
+		if valuesEqual(item, v) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// builtinIndexer reads an item of an array, or with a string index the field
+// of an object.
 func builtinIndexer(ctx EvalContext) (interface{}, error) {
 	if err := ctx.CheckArgCount(2); err != nil {
 		return nil, err
 	}
-	slice, err := ctx.SliceArg(0)
+	receiver, err := ctx.Arg(0)
 	if err != nil {
 		return nil, err
 	}
-	index, err := ctx.IntegerArg(1)
-	if err != nil {
-		return nil, err
+	value := reflect.ValueOf(receiver)
+	switch value.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Ptr:
+		key, err := ctx.StringArg(1)
+		if err != nil {
+			return nil, err
+		}
+		return member(ctx, receiver, key)
+	case reflect.Slice, reflect.Array:
+		index, err := ctx.IntegerArg(1)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= value.Len() {
+			return nil, ctx.FormatError("index out of bounds: %d, len: %d", index, value.Len())
+		}
+		if slice, ok := receiver.([]interface{}); ok {
+			return slice[index], nil
+		}
+		return value.Index(index).Interface(), nil
 	}
-	if index < 0 || index >= len(slice) {
-		return nil, ctx.FormatError("index out of bounds: %d, len: %d", index, len(slice))
+	if receiver == nil && optionalChain(ctx.expr.Args[0]) {
+		return nil, nil
 	}
-	return slice[index], nil
+	return nil, formatArgError(ctx.expr, 0, "is not array: %v", receiver)
 }
 
 func builtinFloor(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalRound(ctx, decimal.Decimal.RoundFloor)
+	}
 	arg, err := unaryNumericArg(ctx)
 	return math.Floor(arg), err
 }
 
 func builtinCeil(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalRound(ctx, decimal.Decimal.RoundCeil)
+	}
 	arg, err := unaryNumericArg(ctx)
 	return math.Ceil(arg), err
 }
 
+// builtinRound rounds to an integer, or to the number of places given as
+// second argument.
 func builtinRound(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalRound(ctx, ctx.params.Decimal.round)
+	}
+	if ctx.ArgCount() == 2 {
+		arg, err := ctx.NumericArg(0)
+		if err != nil {
+			return 0.0, err
+		}
+		places, err := ctx.IntegerArg(1)
+		if err != nil {
+			return 0.0, err
+		}
+		scale := math.Pow(10, float64(places))
+		return math.Round(arg*scale) / scale, nil
+	}
 	arg, err := unaryNumericArg(ctx)
 	return math.Round(arg), err
 }
@@ -335,16 +444,33 @@ func builtinTanh(ctx EvalContext) (interface{}, error) {
 }
 
 func builtinMin(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return decimal.Min(a, b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return math.Min(a, b), err
 }
 
 func builtinMax(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+			return decimal.Max(a, b), nil
+		})
+	}
 	a, b, err := binaryNumericArgs(ctx)
 	return math.Max(a, b), err
 }
 
 func builtinAbs(ctx EvalContext) (interface{}, error) {
+	if ctx.params.Decimal != nil {
+		if err := ctx.CheckArgCount(1); err != nil {
+			return nil, err
+		}
+		arg, err := ctx.DecimalArg(0)
+		return arg.Abs(), err
+	}
 	arg, err := unaryNumericArg(ctx)
 	return math.Abs(arg), err
 }
@@ -0,0 +1,372 @@
+package evaluate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/oarkflow/pkg/cache/policy/lru"
+	"github.com/oarkflow/pkg/decimal"
+)
+
+func StringOperators() map[string]Operator {
+	return map[string]Operator{
+		"upper":       builtinUpper,
+		"lower":       builtinLower,
+		"trim":        builtinTrim,
+		"substr":      builtinSubstr,
+		"replace":     builtinReplace,
+		"split":       builtinSplit,
+		"join":        builtinJoin,
+		"contains":    builtinStringContains,
+		"regex_match": builtinRegexMatch,
+		"len":         builtinLen,
+		"format":      builtinFormat,
+	}
+}
+
+func (ctx EvalContext) StringArg(idx int) (string, error) {
+	val, err := ctx.Arg(idx)
+	if err != nil {
+		return "", err
+	}
+	if str, ok := val.(string); ok {
+		return str, nil
+	}
+	return "", formatArgError(ctx.expr, idx, "is not string: %v", val)
+}
+
+func unaryStringArg(ctx EvalContext) (string, error) {
+	if err := ctx.CheckArgCount(1); err != nil {
+		return "", err
+	}
+	return ctx.StringArg(0)
+}
+
+// checkArgRange validates a count of optional arguments.
+func (ctx EvalContext) checkArgRange(min, max int) error {
+	if ctx.ArgCount() < min || ctx.ArgCount() > max {
+		return ctx.FormatError("wrong number of arguments: %d, expected: %d to %d", ctx.ArgCount(), min, max)
+	}
+	return nil
+}
+
+func builtinUpper(ctx EvalContext) (interface{}, error) {
+	str, err := unaryStringArg(ctx)
+	return strings.ToUpper(str), err
+}
+
+func builtinLower(ctx EvalContext) (interface{}, error) {
+	str, err := unaryStringArg(ctx)
+	return strings.ToLower(str), err
+}
+
+// builtinTrim trims whitespace, or the characters of the optional cutset.
+func builtinTrim(ctx EvalContext) (interface{}, error) {
+	if err := ctx.checkArgRange(1, 2); err != nil {
+		return nil, err
+	}
+	str, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.ArgCount() == 1 {
+		return strings.TrimSpace(str), nil
+	}
+	cutset, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Trim(str, cutset), nil
+}
+
+// builtinSubstr returns the characters from start, up to the optional
+// length. A negative start counts from the end; bounds are clamped.
+func builtinSubstr(ctx EvalContext) (interface{}, error) {
+	if err := ctx.checkArgRange(2, 3); err != nil {
+		return nil, err
+	}
+	str, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	start, err := ctx.IntegerArg(1)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(str)
+	if start < 0 {
+		start += len(runes)
+	}
+	start = clamp(start, 0, len(runes))
+	end := len(runes)
+	if ctx.ArgCount() == 3 {
+		length, err := ctx.IntegerArg(2)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, formatArgError(ctx.expr, 2, "is negative: %d", length)
+		}
+		end = clamp(start+length, start, len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func builtinReplace(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(3); err != nil {
+		return nil, err
+	}
+	str, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	old, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := ctx.StringArg(2)
+	if err != nil {
+		return nil, err
+	}
+	if n := strings.Count(str, old); len(replacement) > len(old) {
+		if err := ctx.Charge(0, int64(n)*int64(len(replacement)-len(old))); err != nil {
+			return nil, err
+		}
+	}
+	return strings.ReplaceAll(str, old, replacement), nil
+}
+
+func builtinSplit(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	str, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(str, sep)
+	items := make([]interface{}, len(parts))
+	for i, part := range parts {
+		items[i] = part
+	}
+	return items, nil
+}
+
+func builtinJoin(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	items, err := ctx.SliceArg(0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Charge(int64(len(items)), int64(len(items))*int64(len(sep))); err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprintf("%v", item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// builtinStringContains reports whether a string contains a substring, or
+// an array contains an item.
+func builtinStringContains(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	val, err := ctx.Arg(0)
+	if err != nil {
+		return nil, err
+	}
+	if items, ok := val.([]interface{}); ok {
+		item, err := ctx.Arg(1)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Charge(int64(len(items)), 0); err != nil {
+			return nil, err
+		}
+		for _, v := range items {
+			if valuesEqual(item, normalize(v)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	str, ok := val.(string)
+	if !ok {
+		return nil, formatArgError(ctx.expr, 0, "is not string or array: %v", val)
+	}
+	sub, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(str, sub), nil
+}
+
+// regexCacheSize bounds the patterns kept compiled; patterns come from
+// expression data, so the least recently used ones are dropped.
+const regexCacheSize = 256
+
+var regexCache = struct {
+	sync.Mutex
+	*lru.Cache[string, *regexp.Regexp]
+}{Cache: lru.NewCache[string, *regexp.Regexp](lru.WithCapacity(regexCacheSize))}
+
+func builtinRegexMatch(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	str, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := ctx.StringArg(1)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Lock()
+	re, ok := regexCache.Get(pattern)
+	regexCache.Unlock()
+	if !ok {
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, formatArgError(ctx.expr, 1, "is not a valid pattern: %v", err)
+		}
+		regexCache.Lock()
+		regexCache.Set(pattern, re)
+		regexCache.Unlock()
+	}
+	return re.MatchString(str), nil
+}
+
+// builtinLen returns the number of characters of a string, or the number of
+// items of an array or object.
+func builtinLen(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(1); err != nil {
+		return nil, err
+	}
+	val, err := ctx.Arg(0)
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case nil:
+		return 0.0, nil
+	case string:
+		return float64(utf8.RuneCountInString(v)), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+	return nil, formatArgError(ctx.expr, 0, "has no length: %v", val)
+}
+
+// builtinFormat formats like fmt.Sprintf. Integral numbers passed to integer
+// verbs like %d are converted first, and decimals to float verbs.
+func builtinFormat(ctx EvalContext) (interface{}, error) {
+	if ctx.ArgCount() < 1 {
+		return nil, ctx.FormatError("wrong number of arguments: %d, expected at least: 1", ctx.ArgCount())
+	}
+	format, err := ctx.StringArg(0)
+	if err != nil {
+		return nil, err
+	}
+	// widths and precisions like %0999999d allocate before anything else
+	if err := ctx.Charge(0, formatWidths(format)); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, ctx.ArgCount()-1)
+	for i := range args {
+		if args[i], err = ctx.Arg(i + 1); err != nil {
+			return nil, err
+		}
+	}
+	for i, verb := range formatVerbs(format) {
+		if i >= len(args) {
+			break
+		}
+		integer := strings.ContainsRune("dboxXcU", verb)
+		switch v := args[i].(type) {
+		case float64:
+			if integer && v == math.Trunc(v) {
+				args[i] = int64(v)
+			}
+		case decimal.Decimal:
+			if integer && v.IsInteger() {
+				args[i] = v.IntPart()
+			} else if strings.ContainsRune("eEfFgG", verb) {
+				args[i] = v.InexactFloat64()
+			}
+		}
+	}
+	return fmt.Sprintf(format, args...), nil
+}
+
+// formatVerbs lists the verbs of a format string in order.
+func formatVerbs(format string) []rune {
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) && format[i] != '%' {
+			verb, _ := utf8.DecodeRuneInString(format[i:])
+			verbs = append(verbs, verb)
+		}
+	}
+	return verbs
+}
+
+// formatWidths sums the widths and precisions of the verbs of a format.
+func formatWidths(format string) int64 {
+	var total int64
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0; i++ {
+			if format[i] < '1' || format[i] > '9' {
+				continue
+			}
+			var n int64
+			for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+				if n < math.MaxInt32 {
+					n = n*10 + int64(format[i]-'0')
+				}
+			}
+			total += n
+			i--
+		}
+	}
+	return total
+}
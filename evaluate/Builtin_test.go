@@ -0,0 +1,126 @@
+package evaluate
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStandardLibrary(t *testing.T) {
+	vars := map[string]interface{}{
+		"name": "  Ada Lovelace ",
+		"csv":  "a,b,,c",
+		"due":  "2024-03-01",
+		"paid": time.Date(2024, 2, 20, 10, 0, 0, 0, time.UTC),
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A", "price": 2.5, "qty": 4},
+			map[string]interface{}{"sku": "B", "price": 10, "qty": 1},
+			map[string]interface{}{"sku": "C", "price": 1.25, "qty": 8},
+		},
+		"nums": []interface{}{3, 1, 2, 3, 1},
+		"rate": 2,
+	}
+	for expr, want := range map[string]interface{}{
+		`upper(trim(name))`:                          "ADA LOVELACE",
+		`lower("ÀB")`:                                "àb",
+		`trim("xxhixx", "x")`:                        "hi",
+		`substr("héllo", 1, 3)`:                      "éll",
+		`substr("hello", -3)`:                        "llo",
+		`substr("hello", 2, 100)`:                    "llo",
+		`replace("a-b-c", "-", "+")`:                 "a+b+c",
+		`split(csv, ",")`:                            []interface{}{"a", "b", "", "c"},
+		`join(split(csv, ","), "|")`:                 "a|b||c",
+		`contains(name, "Love")`:                     true,
+		`contains(nums, 2)`:                          true,
+		`regex_match("INV-0042", "^INV-[0-9]+$")`:    true,
+		`len("héllo")`:                               5.0,
+		`len(items)`:                                 3.0,
+		`format("%s owes %d (%.2f)", "Ada", 3, 2.5)`: "Ada owes 3 (2.50)",
+
+		`date_format(parse_date(due), "2006-01-02")`:                   "2024-03-01",
+		`date_format(parse_date("01/03/2024", "02/01/2006"), "Jan 2")`: "Mar 1",
+		`date_format(date_add(due, "1w2d"), "2006-01-02")`:             "2024-03-10",
+		`date_format(date_sub(paid, "36h"), "2006-01-02 15")`:          "2024-02-18 22",
+		`date_diff(due, paid)`:                                         9.0,
+		`date_diff(paid, due)`:                                         -9.0,
+		`weekday(due)`:                                                 "Friday",
+
+		`map(items, x => x.price * x.qty)`:                    []interface{}{10.0, 10.0, 10.0},
+		`map(items, (x, i) => i)`:                             []interface{}{0.0, 1.0, 2.0},
+		`map(filter(items, x => x.qty > 2), x => x.sku)`:      []interface{}{"A", "C"},
+		`reduce(items, (acc, x) => acc + x.qty, 0)`:           13.0,
+		`reduce(nums, (acc, x) => max(acc, x))`:               3.0,
+		`any(items, x => x.sku == "B")`:                       true,
+		`all(items, x => x.price > 1)`:                        true,
+		`all(items, x => x.price > 2)`:                        false,
+		`sum(nums)`:                                           10.0,
+		`sum(items, x => x.price * x.qty * rate)`:             60.0,
+		`avg(items, x => x.qty)`:                              13.0 / 3,
+		`sort(nums)`:                                          []interface{}{1, 1, 2, 3, 3},
+		`map(sort(items, x => -x.price), x => x.sku)`:         []interface{}{"B", "A", "C"},
+		`sort(["b", "a"])`:                                    []interface{}{"a", "b"},
+		`unique(nums)`:                                        []interface{}{3, 1, 2},
+		`unique([[1], [1], [2]])`:                             []interface{}{[]interface{}{1.0}, []interface{}{2.0}},
+		`sum(map(items, item => item.price * item.qty)) > 20`: true,
+	} {
+		got, err := MustParse(expr).Eval(NewEvalParams(vars))
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", expr, got, want)
+		}
+	}
+}
+
+func TestStandardLibraryErrors(t *testing.T) {
+	vars := map[string]interface{}{"items": []interface{}{1.0, "a"}}
+	for _, expr := range []string{
+		`upper(1)`,
+		`substr("abc", 0, -1)`,
+		`regex_match("a", "(")`,
+		`parse_date("not a date")`,
+		`date_add("2024-01-01", "soon")`,
+		`map(items, 1)`,
+		`filter(items, x => x)`,
+		`sum(items)`,
+		`sort(items)`,
+		`avg([])`,
+		`map(items, (a, b, c) => a)`,
+		`x => x`,
+		`items.price`,
+	} {
+		if _, err := MustParse(expr).Eval(NewEvalParams(vars)); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestLambdaSyntax(t *testing.T) {
+	expr := MustParse(`reduce(map(items, x => x.price * rate), (acc, x) => acc + x, offset)`)
+	vars := expr.Vars()
+	sort.Strings(vars)
+	if !reflect.DeepEqual(vars, []string{"items", "offset", "rate"}) {
+		t.Errorf("lambda parameters reported as variables: %v", vars)
+	}
+	printed, err := expr.Print(PrintConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printed != `reduce(map(items, x => x.price * rate), (acc, x) => acc + x, offset)` {
+		t.Errorf("unexpected print %s", printed)
+	}
+	reduced, err := expr.Reduce(NewEvalParams(map[string]interface{}{"rate": 2.0, "x": 100.0}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printed, _ = reduced.Print(PrintConfig{})
+	if printed != `reduce(map(items, x => x.price * 2), (acc, x) => acc + x, offset)` {
+		t.Errorf("lambda parameters were substituted: %s", printed)
+	}
+	if _, err := Parse(`map(items, x + 1 => x)`); err == nil {
+		t.Error("expected an error for invalid lambda parameters")
+	}
+}
@@ -0,0 +1,491 @@
+package evaluate
+
+import (
+	"fmt"
+)
+
+// DiagnosticCode classifies the problems reported by Check.
+type DiagnosticCode string
+
+const (
+	DiagnosticUndefinedVariable DiagnosticCode = "undefined_variable"
+	DiagnosticUndefinedField    DiagnosticCode = "undefined_field"
+	DiagnosticUndefinedOperator DiagnosticCode = "undefined_operator"
+	DiagnosticArgumentCount     DiagnosticCode = "argument_count"
+	DiagnosticTypeMismatch      DiagnosticCode = "type_mismatch"
+)
+
+// Diagnostic is a problem found by Check, spanning the source of the node
+// it was found at.
+type Diagnostic struct {
+	Code      DiagnosticCode `json:"code"`
+	Message   string         `json:"message"`
+	SourcePos int            `json:"sourcePos"`
+	SourceLen int            `json:"sourceLen"`
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s [pos=%d; len=%d]", d.Message, d.SourcePos, d.SourceLen)
+}
+
+// Check infers the type of the expression from the types the schema gives
+// its variables, without evaluating it. It reports undefined variables,
+// fields and operators, wrong argument counts and arguments that can not
+// have the type the operator expects. Values of unknown type are accepted
+// anywhere, as are operators added with AddCustomOperator.
+func Check(expr ExprNode, schema Schema) (*Type, []Diagnostic) {
+	c := &checker{scope: schema}
+	return c.check(expr), c.diagnostics
+}
+
+type checker struct {
+	scope       Schema
+	diagnostics []Diagnostic
+}
+
+func (c *checker) report(node ExprNode, code DiagnosticCode, msg string, msgArgs ...interface{}) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Code:      code,
+		Message:   fmt.Sprintf(msg, msgArgs...),
+		SourcePos: node.SourcePos,
+		SourceLen: node.SourceLen,
+	})
+}
+
+func (c *checker) check(expr ExprNode) *Type {
+	switch expr.Type {
+	case NodeTypeLiteral:
+		return literalType(expr.Value)
+	case NodeTypeVariable:
+		t, ok := c.scope[expr.Name]
+		if !ok {
+			c.report(expr, DiagnosticUndefinedVariable, "variable undefined: %s", expr.Name)
+			return TypeAny
+		}
+		if t == nil {
+			return TypeAny
+		}
+		return t
+	}
+	if expr.OperatorType == OperatorTypeLambda {
+		c.report(expr, DiagnosticTypeMismatch, "lambda can only be passed to a function")
+		return c.lambda(expr)
+	}
+	if rule, ok := checkRules[expr.Name]; ok {
+		return rule(c, expr)
+	}
+	if _, ok := defaultOperators.builtin[expr.Name]; !ok {
+		c.report(expr, DiagnosticUndefinedOperator, "operator undefined: %s", expr.Name)
+	}
+	for _, arg := range expr.Args {
+		if arg.OperatorType == OperatorTypeLambda {
+			c.lambda(arg)
+			continue
+		}
+		c.check(arg)
+	}
+	return TypeAny
+}
+
+func literalType(value interface{}) *Type {
+	switch value.(type) {
+	case nil:
+		return TypeNull
+	case string:
+		return TypeString
+	case bool:
+		return TypeBool
+	}
+	if _, ok := normalize(value).(float64); ok {
+		return TypeNumber
+	}
+	return TypeAny
+}
+
+// lambda checks the body of a lambda with its parameters bound to params,
+// or to any type, and returns the type of the body.
+func (c *checker) lambda(expr ExprNode, params ...*Type) *Type {
+	last := len(expr.Args) - 1
+	scope := make(Schema, len(c.scope)+last)
+	for name, t := range c.scope {
+		scope[name] = t
+	}
+	for i, param := range expr.Args[:last] {
+		scope[param.Name] = TypeAny
+		if i < len(params) {
+			scope[param.Name] = params[i]
+		}
+	}
+	if last > len(params) && len(params) > 0 {
+		c.report(expr, DiagnosticArgumentCount, "lambda has %d parameters, expected at most %d", last, len(params))
+	}
+	outer := c.scope
+	c.scope = scope
+	defer func() { c.scope = outer }()
+	return c.check(expr.Args[last])
+}
+
+// arity reports a count of arguments out of min to max.
+func (c *checker) arity(expr ExprNode, min, max int) bool {
+	count := len(expr.Args)
+	switch {
+	case count >= min && count <= max:
+		return true
+	case min == max:
+		c.report(expr, DiagnosticArgumentCount, "wrong number of arguments: %d, expected: %d", count, min)
+	default:
+		c.report(expr, DiagnosticArgumentCount, "wrong number of arguments: %d, expected: %d to %d", count, min, max)
+	}
+	return false
+}
+
+// arg checks argument idx, expecting one of the kinds.
+func (c *checker) arg(expr ExprNode, idx int, kind Kind) *Type {
+	arg := expr.Args[idx]
+	if arg.OperatorType == OperatorTypeLambda {
+		c.report(arg, DiagnosticTypeMismatch, "%s is a lambda, expected %s", formatArgName(expr, idx), kind)
+		c.lambda(arg)
+		return TypeAny
+	}
+	t := c.check(arg)
+	c.expect(expr, idx, t, kind)
+	return t
+}
+
+func (c *checker) expect(expr ExprNode, idx int, t *Type, kind Kind) {
+	if t.Kind&kind == 0 {
+		c.report(expr.Args[idx], DiagnosticTypeMismatch, "%s is %s, expected %s", formatArgName(expr, idx), t, kind)
+	}
+}
+
+// lambdaArg checks argument idx, which must be a lambda taking params.
+func (c *checker) lambdaArg(expr ExprNode, idx int, params ...*Type) *Type {
+	arg := expr.Args[idx]
+	if arg.OperatorType != OperatorTypeLambda {
+		c.check(arg)
+		c.report(arg, DiagnosticTypeMismatch, "%s is not a lambda", formatArgName(expr, idx))
+		return TypeAny
+	}
+	return c.lambda(arg, params...)
+}
+
+// checkRule checks the arguments of an operator and returns its type.
+type checkRule func(c *checker, expr ExprNode) *Type
+
+// signature checks arguments of the given kinds, the ones after min being
+// optional.
+func signature(result *Type, min int, params ...Kind) checkRule {
+	return func(c *checker, expr ExprNode) *Type {
+		c.arity(expr, min, len(params))
+		for i := range expr.Args {
+			kind := KindAny
+			if i < len(params) {
+				kind = params[i]
+			}
+			c.arg(expr, i, kind)
+		}
+		return result
+	}
+}
+
+func fixed(result *Type, params ...Kind) checkRule {
+	return signature(result, len(params), params...)
+}
+
+// variadic checks any number of arguments of a kind after the fixed ones.
+func variadic(result *Type, rest Kind, params ...Kind) checkRule {
+	return func(c *checker, expr ExprNode) *Type {
+		if len(expr.Args) < len(params) {
+			c.report(expr, DiagnosticArgumentCount, "wrong number of arguments: %d, expected at least: %d", len(expr.Args), len(params))
+		}
+		for i := range expr.Args {
+			kind := rest
+			if i < len(params) {
+				kind = params[i]
+			}
+			c.arg(expr, i, kind)
+		}
+		return result
+	}
+}
+
+// kindDate is what DateArg accepts: dates, date strings and timestamps.
+const kindDate = KindDate | KindString | KindNumber
+
+var checkRules map[string]checkRule
+
+func init() {
+	number, boolean, str := KindNumber, KindBool, KindString
+	checkRules = map[string]checkRule{
+		"==": fixed(TypeBool, KindAny, KindAny),
+		"!=": fixed(TypeBool, KindAny, KindAny),
+		"<":  fixed(TypeBool, number, number),
+		"<=": fixed(TypeBool, number, number),
+		">":  fixed(TypeBool, number, number),
+		">=": fixed(TypeBool, number, number),
+		"&&": fixed(TypeBool, boolean, boolean),
+		"||": fixed(TypeBool, boolean, boolean),
+		"!":  fixed(TypeBool, boolean),
+		"-":  signature(TypeNumber, 1, number, number),
+
+		"?:":    checkTernary,
+		"??":    checkCoalesce,
+		"array": checkArray,
+		"in":    fixed(TypeBool, KindAny, KindList),
+		"[]":    checkIndexer,
+		".":     checkMember,
+		"?.":    checkMember,
+
+		"round":  signature(TypeNumber, 1, number, number),
+		"string": fixed(TypeString, KindAny),
+
+		"now":          fixed(TypeString),
+		"now_date":     fixed(TypeString),
+		"current_date": fixed(TypeString),
+		"now_time":     fixed(TypeString),
+		"now_datetime": fixed(TypeString),
+
+		"upper":       fixed(TypeString, str),
+		"lower":       fixed(TypeString, str),
+		"trim":        signature(TypeString, 1, str, str),
+		"substr":      signature(TypeString, 2, str, number, number),
+		"replace":     fixed(TypeString, str, str, str),
+		"split":       fixed(ListOf(TypeString), str, str),
+		"join":        fixed(TypeString, KindList, str),
+		"contains":    checkContains,
+		"regex_match": fixed(TypeBool, str, str),
+		"len":         fixed(TypeNumber, KindNull|KindString|KindList|KindObject),
+		"format":      variadic(TypeString, KindAny, str),
+
+		"parse_date":  checkParseDate,
+		"date_add":    fixed(TypeDate, kindDate, str),
+		"date_sub":    fixed(TypeDate, kindDate, str),
+		"date_diff":   fixed(TypeNumber, kindDate, kindDate),
+		"date_format": signature(TypeString, 1, kindDate, str),
+		"weekday":     fixed(TypeString, kindDate),
+
+		"map":    checkMap,
+		"filter": checkPredicate(nil),
+		"any":    checkPredicate(TypeBool),
+		"all":    checkPredicate(TypeBool),
+		"reduce": checkReduce,
+		"sum":    checkNumbers,
+		"avg":    checkNumbers,
+		"sort":   checkSort,
+		"unique": checkUnique,
+	}
+	for _, name := range []string{"+", "*", "/", "%", "**", "&", "|", "^", "<<", ">>", "min", "max"} {
+		checkRules[name] = fixed(TypeNumber, number, number)
+	}
+	for _, name := range []string{"~", "floor", "ceil", "sqrt", "sin", "cos", "tan", "tanh", "abs", "log", "log2", "log10"} {
+		checkRules[name] = fixed(TypeNumber, number)
+	}
+}
+
+func checkTernary(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 3, 3) {
+		return TypeAny
+	}
+	c.arg(expr, 0, KindBool)
+	return union(c.check(expr.Args[1]), c.check(expr.Args[2]))
+}
+
+func checkCoalesce(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 2, 2) {
+		return TypeAny
+	}
+	left, right := c.check(expr.Args[0]), c.check(expr.Args[1])
+	if left.Kind&KindNull == 0 || left.Kind == KindAny {
+		return union(left, right)
+	}
+	if left.Kind == KindNull {
+		return right
+	}
+	nonNull := *left
+	nonNull.Kind &^= KindNull
+	return union(&nonNull, right)
+}
+
+func checkArray(c *checker, expr ExprNode) *Type {
+	var items *Type
+	for i := range expr.Args {
+		t := c.arg(expr, i, KindAny)
+		if items == nil {
+			items = t
+		} else {
+			items = union(items, t)
+		}
+	}
+	return ListOf(items)
+}
+
+func checkIndexer(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 2, 2) {
+		return TypeAny
+	}
+	receiver := c.arg(expr, 0, KindList|KindObject|KindNull)
+	if receiver.Kind == KindList {
+		c.arg(expr, 1, KindNumber)
+		return itemType(receiver)
+	}
+	index := c.arg(expr, 1, KindNumber|KindString)
+	if receiver.Kind == KindObject && index.Kind == KindString {
+		if key, ok := expr.Args[1].Value.(string); ok && expr.Args[1].Type == NodeTypeLiteral {
+			return c.field(expr, receiver, key)
+		}
+	}
+	return TypeAny
+}
+
+func checkMember(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 2, 2) {
+		return TypeAny
+	}
+	receiver := c.check(expr.Args[0])
+	key, _ := expr.Args[1].Value.(string)
+	optional := expr.Name == "?." || optionalChain(expr.Args[0])
+	if receiver.Kind == KindNull && optional {
+		return TypeNull
+	}
+	kind := KindObject
+	if optional {
+		kind |= KindNull
+	}
+	c.expect(expr, 0, receiver, kind)
+	if receiver.Kind&^KindNull != KindObject {
+		return TypeAny
+	}
+	t := c.field(expr, receiver, key)
+	if optional && t.Kind != KindAny {
+		t = union(t, TypeNull)
+	}
+	return t
+}
+
+// field returns the type of a field of an object, reporting fields the
+// object does not declare.
+func (c *checker) field(expr ExprNode, object *Type, key string) *Type {
+	if object.Fields == nil {
+		return TypeAny
+	}
+	t, ok := object.Fields[key]
+	if !ok {
+		if expr.Name != "?." {
+			c.report(expr.Args[1], DiagnosticUndefinedField, "field undefined: %s", key)
+		}
+		return TypeAny
+	}
+	if t == nil {
+		return TypeAny
+	}
+	return t
+}
+
+func itemType(list *Type) *Type {
+	if list.Kind == KindList && list.Items != nil {
+		return list.Items
+	}
+	return TypeAny
+}
+
+func checkContains(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 2, 2) {
+		return TypeBool
+	}
+	if c.arg(expr, 0, KindString|KindList).Kind == KindString {
+		c.arg(expr, 1, KindString)
+	} else {
+		c.arg(expr, 1, KindAny)
+	}
+	return TypeBool
+}
+
+func checkParseDate(c *checker, expr ExprNode) *Type {
+	if len(expr.Args) == 2 {
+		return fixed(TypeDate, KindString, KindString)(c, expr)
+	}
+	return signature(TypeDate, 1, kindDate, KindString)(c, expr)
+}
+
+// collection checks the list of a collection function taking a lambda,
+// optional if min is 1, and returns the list and the lambda body type.
+func (c *checker) collection(expr ExprNode, min int) (list, body *Type, ok bool) {
+	if !c.arity(expr, min, 2) {
+		return TypeAny, nil, false
+	}
+	list = c.arg(expr, 0, KindList)
+	if len(expr.Args) == 1 {
+		return list, nil, true
+	}
+	return list, c.lambdaArg(expr, 1, itemType(list), TypeNumber), true
+}
+
+func checkMap(c *checker, expr ExprNode) *Type {
+	_, body, ok := c.collection(expr, 2)
+	if !ok {
+		return TypeList
+	}
+	return ListOf(body)
+}
+
+func checkPredicate(result *Type) checkRule {
+	return func(c *checker, expr ExprNode) *Type {
+		list, body, ok := c.collection(expr, 2)
+		if ok && body.Kind&KindBool == 0 {
+			c.report(expr.Args[1], DiagnosticTypeMismatch, "%s returns %s, expected bool", formatArgName(expr, 1), body)
+		}
+		if result == nil {
+			return list
+		}
+		return result
+	}
+}
+
+func checkReduce(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 2, 3) {
+		return TypeAny
+	}
+	list := c.arg(expr, 0, KindList)
+	acc := itemType(list)
+	if len(expr.Args) == 3 {
+		acc = c.arg(expr, 2, KindAny)
+	}
+	return c.lambdaArg(expr, 1, acc, itemType(list), TypeNumber)
+}
+
+func checkNumbers(c *checker, expr ExprNode) *Type {
+	list, body, ok := c.collection(expr, 1)
+	if !ok {
+		return TypeNumber
+	}
+	item := body
+	if item == nil {
+		item = itemType(list)
+	}
+	if item.Kind&KindNumber == 0 {
+		c.report(expr.Args[len(expr.Args)-1], DiagnosticTypeMismatch, "%s has %s items, expected number", formatArgName(expr, len(expr.Args)-1), item)
+	}
+	return TypeNumber
+}
+
+func checkSort(c *checker, expr ExprNode) *Type {
+	list, body, ok := c.collection(expr, 1)
+	if !ok {
+		return list
+	}
+	key := body
+	if key == nil {
+		key = itemType(list)
+	}
+	if key.Kind&(KindNumber|KindString|KindDate) == 0 {
+		c.report(expr.Args[len(expr.Args)-1], DiagnosticTypeMismatch, "%s has %s items, which can not be sorted", formatArgName(expr, len(expr.Args)-1), key)
+	}
+	return list
+}
+
+func checkUnique(c *checker, expr ExprNode) *Type {
+	if !c.arity(expr, 1, 1) {
+		return TypeAny
+	}
+	return c.arg(expr, 0, KindList)
+}
@@ -0,0 +1,106 @@
+package evaluate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/pkg/jsonschema"
+)
+
+func TestCheck(t *testing.T) {
+	schema := Schema{
+		"amount": TypeNumber,
+		"name":   TypeString,
+		"active": TypeBool,
+		"due":    TypeDate,
+		"tags":   ListOf(TypeString),
+		"order": ObjectOf(map[string]*Type{
+			"total":    TypeNumber,
+			"discount": {Kind: KindNumber | KindNull},
+			"items":    ListOf(ObjectOf(map[string]*Type{"sku": TypeString, "qty": TypeNumber})),
+		}),
+		"meta": TypeObject,
+	}
+	for expr, want := range map[string]string{
+		`amount * 2 > 10 && active`:                       "bool",
+		`upper(name)`:                                     "string",
+		`order.discount ?? 0`:                             "number",
+		`order?.discount`:                                 "null|number",
+		`order.items[0].sku`:                              "string",
+		`map(order.items, x => x.qty * 2)`:                "list<number>",
+		`filter(order.items, x => x.qty > 1)`:             "list<{qty: number, sku: string}>",
+		`reduce(order.items, (acc, x) => acc + x.qty, 0)`: "number",
+		`date_add(due, "1d")`:                             "date",
+		`active ? "yes" : "no"`:                           "string",
+		`meta.anything`:                                   "any",
+		`contains(tags, name)`:                            "bool",
+	} {
+		typ, diagnostics := Check(MustParse(expr), schema)
+		if len(diagnostics) > 0 {
+			t.Errorf("%s: unexpected diagnostics %v", expr, diagnostics)
+			continue
+		}
+		if typ.String() != want {
+			t.Errorf("%s: got %s, want %s", expr, typ, want)
+		}
+	}
+
+	for expr, want := range map[string][]Diagnostic{
+		`amount + name`: {
+			{Code: DiagnosticTypeMismatch, Message: "rhs of + is string, expected number", SourcePos: 9, SourceLen: 4},
+		},
+		`total > 1`: {
+			{Code: DiagnosticUndefinedVariable, Message: "variable undefined: total", SourcePos: 0, SourceLen: 5},
+		},
+		`order.totl`: {
+			{Code: DiagnosticUndefinedField, Message: "field undefined: totl", SourcePos: 6, SourceLen: 4},
+		},
+		`upper(name, 1)`: {
+			{Code: DiagnosticArgumentCount, Message: "wrong number of arguments: 2, expected: 1", SourcePos: 0, SourceLen: 14},
+		},
+		`frobnicate(amount)`: {
+			{Code: DiagnosticUndefinedOperator, Message: "operator undefined: frobnicate", SourcePos: 0, SourceLen: 18},
+		},
+		`filter(tags, x => len(x))`: {
+			{Code: DiagnosticTypeMismatch, Message: "argument #2 of filter returns number, expected bool", SourcePos: 13, SourceLen: 11},
+		},
+		`name.first`: {
+			{Code: DiagnosticTypeMismatch, Message: "member receiver is string, expected object", SourcePos: 0, SourceLen: 4},
+		},
+	} {
+		_, diagnostics := Check(MustParse(expr), schema)
+		if !reflect.DeepEqual(diagnostics, want) {
+			t.Errorf("%s: got %v, want %v", expr, diagnostics, want)
+		}
+	}
+}
+
+func TestSchemaFromJSONSchema(t *testing.T) {
+	var s jsonschema.Schema
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"total": {"type": "number"},
+			"note": {"type": ["string", "null"]},
+			"created": {"type": "string", "format": "date-time"},
+			"lines": {"type": "array", "items": {"type": "object", "properties": {"qty": {"type": "integer"}}}}
+		}
+	}`), &s); err != nil {
+		t.Fatal(err)
+	}
+	schema := SchemaFromJSONSchema(&s)
+	for name, want := range map[string]string{
+		"total":   "number",
+		"note":    "null|string",
+		"created": "date",
+		"lines":   "list<{qty: number}>",
+	} {
+		if got := schema[name].String(); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+	if _, diagnostics := Check(MustParse(`sum(lines, l => l.qty) > total`), schema); len(diagnostics) > 0 {
+		t.Errorf("unexpected diagnostics %v", diagnostics)
+	}
+}
@@ -0,0 +1,461 @@
+package evaluate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Compile parses an expression and compiles it to a Program. Literal
+// subexpressions are folded and BuiltinOptimizers are applied where the
+// rewrite cannot change the result of Eval, so the Program behaves exactly
+// like ExprNode.Eval with NewEvalParams.
+func Compile(input string) (*Program, error) {
+	expr, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return CompileNode(expr)
+}
+
+// MustCompile returns a Program or panics if the expression cannot be compiled.
+func MustCompile(input string) *Program {
+	program, err := Compile(input)
+	if err != nil {
+		panic(fmt.Errorf("MustCompile error: %v", err))
+	}
+	return program
+}
+
+// CompileNode compiles an already parsed expression. Builtin operators are
+// bound when compiling, overriding them with AddCustomOperator afterwards does
+// not affect existing programs; custom operators are looked up at run time.
+func CompileNode(expr ExprNode) (*Program, error) {
+	c := &compiler{
+		operators: defaultOperators.builtin,
+		slots:     map[string]int{},
+	}
+	folded := c.fold(expr)
+	if err := c.compile(folded, ""); err != nil {
+		return nil, err
+	}
+	return &Program{
+		code:   c.code,
+		consts: c.consts,
+		nodes:  c.nodes,
+		vars:   c.vars,
+		expr:   expr,
+	}, nil
+}
+
+type opcode uint8
+
+const (
+	opConst opcode = iota
+	opVar
+	opNorm
+	opCheckNumber
+	opCheckInteger
+	opCheckBool
+	opCheckSlice
+	opNumeric
+	opInteger
+	opUnary
+	opEq
+	opNeq
+	opNot
+	opNeg
+	opInverse
+	opJump
+	opJumpIfFalse
+	opJumpIfTrue
+	opJumpIfFalsePop
+	opJumpIfNotNil
+	opArray
+	opIn
+	opString
+	opCall
+)
+
+type instruction struct {
+	op   opcode
+	arg  int
+	node int
+}
+
+// nodeInfo keeps the source node of an instruction and the argument names
+// Eval prepends to errors raised below it.
+type nodeInfo struct {
+	expr   ExprNode
+	prefix string
+}
+
+type nativeKind int
+
+const (
+	nativeNone nativeKind = iota
+	nativeNumeric
+	nativeInteger
+	nativeUnary
+	nativeMinus
+	nativeEq
+	nativeNeq
+	nativeAnd
+	nativeOr
+	nativeNot
+	nativeInverse
+	nativeTernary
+	nativeCoalesce
+	nativeArray
+	nativeIn
+	nativeString
+)
+
+type native struct {
+	kind nativeKind
+	// fn indexes numericOps, integerOps or unaryOps
+	fn int
+}
+
+var numericOps = []func(a, b float64) interface{}{
+	func(a, b float64) interface{} { return a + b },
+	func(a, b float64) interface{} { return a - b },
+	func(a, b float64) interface{} { return a * b },
+	func(a, b float64) interface{} { return a / b },
+	func(a, b float64) interface{} { return math.Pow(a, b) },
+	func(a, b float64) interface{} { return a < b },
+	func(a, b float64) interface{} { return a <= b },
+	func(a, b float64) interface{} { return a > b },
+	func(a, b float64) interface{} { return a >= b },
+	func(a, b float64) interface{} { return math.Min(a, b) },
+	func(a, b float64) interface{} { return math.Max(a, b) },
+}
+
+var integerOps = []func(a, b int) interface{}{
+	func(a, b int) interface{} { return float64(a % b) },
+	func(a, b int) interface{} { return float64(a & b) },
+	func(a, b int) interface{} { return float64(a | b) },
+	func(a, b int) interface{} { return float64(a ^ b) },
+	func(a, b int) interface{} { return float64(a << uint(b)) },
+	func(a, b int) interface{} { return float64(a >> uint(b)) },
+}
+
+var unaryOps = []func(float64) float64{
+	math.Floor, math.Ceil, math.Round, math.Sqrt, math.Sin, math.Cos, math.Tan,
+	math.Tanh, math.Abs, math.Log, math.Log2, math.Log10,
+}
+
+// natives maps the builtin operator implementations to their bytecode.
+var natives = map[uintptr]native{}
+
+func init() {
+	register := func(op Operator, n native) {
+		natives[reflect.ValueOf(op).Pointer()] = n
+	}
+	for i, op := range []Operator{builtinSum, builtinSub, builtinMul, builtinDiv, builtinPow,
+		builtinLt, builtinLte, builtinGt, builtinGte, builtinMin, builtinMax} {
+		register(op, native{kind: nativeNumeric, fn: i})
+	}
+	for i, op := range []Operator{builtinMod, builtinBitwiseAnd, builtinBitwiseOr, builtinBitwiseXor,
+		builtinBitwiseLShift, builtinBitwiseRShift} {
+		register(op, native{kind: nativeInteger, fn: i})
+	}
+	for i, op := range []Operator{builtinFloor, builtinCeil, builtinRound, builtinSqrt, builtinSin,
+		builtinCos, builtinTan, builtinTanh, builtinAbs, builtinLog, builtinLog2, builtinLog10} {
+		register(op, native{kind: nativeUnary, fn: i})
+	}
+	register(builtinMinus, native{kind: nativeMinus})
+	register(builtinEq, native{kind: nativeEq})
+	register(builtinNeq, native{kind: nativeNeq})
+	register(builtinLogicalAnd, native{kind: nativeAnd})
+	register(builtinLogicalOr, native{kind: nativeOr})
+	register(builtinLogicalNot, native{kind: nativeNot})
+	register(builtinBitwiseInverse, native{kind: nativeInverse})
+	register(builtinTernaryIf, native{kind: nativeTernary})
+	register(builtinCoalesce, native{kind: nativeCoalesce})
+	register(builtinArray, native{kind: nativeArray})
+	register(builtinContains, native{kind: nativeIn})
+	register(builtinString, native{kind: nativeString})
+}
+
+func nativeOf(op Operator) native {
+	if op == nil {
+		return native{}
+	}
+	return natives[reflect.ValueOf(op).Pointer()]
+}
+
+// arity is the argument count the native implementation accepts; other
+// counts are left to the operator so it reports them.
+func (n native) arity(args int) bool {
+	switch n.kind {
+	case nativeNumeric, nativeInteger, nativeEq, nativeNeq, nativeAnd, nativeOr, nativeCoalesce, nativeIn:
+		return args == 2
+	case nativeUnary, nativeNot, nativeInverse, nativeString:
+		return args == 1
+	case nativeMinus:
+		return args == 1 || args == 2
+	case nativeTernary:
+		return args == 3
+	case nativeArray:
+		return true
+	}
+	return false
+}
+
+type compiler struct {
+	code      []instruction
+	consts    []interface{}
+	nodes     []nodeInfo
+	vars      []string
+	slots     map[string]int
+	operators map[string]Operator
+}
+
+func (c *compiler) emit(op opcode, arg, node int) int {
+	c.code = append(c.code, instruction{op: op, arg: arg, node: node})
+	return len(c.code) - 1
+}
+
+func (c *compiler) node(expr ExprNode, prefix string) int {
+	c.nodes = append(c.nodes, nodeInfo{expr: expr, prefix: prefix})
+	return len(c.nodes) - 1
+}
+
+func (c *compiler) patch(at int) {
+	c.code[at].arg = len(c.code)
+}
+
+// fold replaces literal subexpressions of pure builtin operators by their
+// value and applies the BuiltinOptimizers rewrites that keep Eval semantics.
+func (c *compiler) fold(expr ExprNode) ExprNode {
+	if expr.Type != NodeTypeOperator {
+		return expr
+	}
+	args := make([]ExprNode, len(expr.Args))
+	allLiteral := true
+	for i, arg := range expr.Args {
+		args[i] = c.fold(arg)
+		if args[i].Type != NodeTypeLiteral {
+			allLiteral = false
+		}
+	}
+	expr.Args = args
+	n := nativeOf(c.operators[expr.Name])
+	if n.kind == nativeNone || !n.arity(len(args)) {
+		return expr
+	}
+	if allLiteral {
+		if value, ok := c.evalLiteral(expr); ok {
+			return NewExprNodeLiteral(value, expr.SourcePos, expr.SourceLen)
+		}
+		return expr
+	}
+	if optimizer, ok := builtinOptimizers[expr.Name]; ok && exactRewrite(n.kind, expr) {
+		return optimizer(expr)
+	}
+	return expr
+}
+
+// evalLiteral evaluates an operator over literals. Values that Eval would
+// allocate anew on every call, and operations that fail, are left to run
+// time.
+func (c *compiler) evalLiteral(expr ExprNode) (value interface{}, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	value, err := expr.Eval(EvalParams{Operators: c.operators})
+	if err != nil {
+		return nil, false
+	}
+	switch value.(type) {
+	case nil, bool, float64, string:
+		return value, true
+	}
+	return nil, false
+}
+
+var builtinOptimizers = BuiltinOptimizers()
+
+// exactRewrite reports whether the optimizer for expr only drops operands
+// Eval would not evaluate and yields a literal, which Eval would return as is.
+func exactRewrite(kind nativeKind, expr ExprNode) bool {
+	switch kind {
+	case nativeAnd:
+		return expr.Args[0].IsLiteral(false)
+	case nativeOr:
+		return expr.Args[0].IsLiteral(true)
+	case nativeTernary:
+		return (expr.Args[0].IsLiteral(true) && plainLiteral(expr.Args[1])) ||
+			(expr.Args[0].IsLiteral(false) && plainLiteral(expr.Args[2]))
+	}
+	return false
+}
+
+// plainLiteral reports whether EvalContext.Arg returns the literal unchanged.
+func plainLiteral(expr ExprNode) bool {
+	if expr.Type != NodeTypeLiteral {
+		return false
+	}
+	switch expr.Value.(type) {
+	case nil, bool, float64, string:
+		return true
+	}
+	return false
+}
+
+func (c *compiler) compile(expr ExprNode, prefix string) error {
+	switch expr.Type {
+	case NodeTypeLiteral:
+		c.consts = append(c.consts, expr.Value)
+		c.emit(opConst, len(c.consts)-1, -1)
+		return nil
+	case NodeTypeVariable:
+		slot, ok := c.slots[expr.Name]
+		if !ok {
+			slot = len(c.vars)
+			c.slots[expr.Name] = slot
+			c.vars = append(c.vars, expr.Name)
+		}
+		c.emit(opVar, slot, c.node(expr, prefix))
+		return nil
+	case NodeTypeOperator:
+		return c.compileOperator(expr, prefix)
+	}
+	return fmt.Errorf("bad expr type: %v", expr)
+}
+
+func (c *compiler) compileOperator(expr ExprNode, prefix string) error {
+	node := c.node(expr, prefix)
+	n := nativeOf(c.operators[expr.Name])
+	if n.kind == nativeNone || !n.arity(len(expr.Args)) {
+		c.emit(opCall, 0, node)
+		return nil
+	}
+	arg := func(idx int, check opcode) error {
+		if err := c.compile(expr.Args[idx], prefix+formatArgName(expr, idx)+" / "); err != nil {
+			return err
+		}
+		c.emit(check, idx, node)
+		return nil
+	}
+	switch n.kind {
+	case nativeNumeric:
+		if err := arg(0, opCheckNumber); err != nil {
+			return err
+		}
+		if err := arg(1, opCheckNumber); err != nil {
+			return err
+		}
+		c.emit(opNumeric, n.fn, node)
+	case nativeInteger:
+		if err := arg(0, opCheckInteger); err != nil {
+			return err
+		}
+		if err := arg(1, opCheckInteger); err != nil {
+			return err
+		}
+		c.emit(opInteger, n.fn, node)
+	case nativeUnary:
+		if err := arg(0, opCheckNumber); err != nil {
+			return err
+		}
+		c.emit(opUnary, n.fn, node)
+	case nativeMinus:
+		if len(expr.Args) == 2 {
+			if err := arg(0, opCheckNumber); err != nil {
+				return err
+			}
+			if err := arg(1, opCheckNumber); err != nil {
+				return err
+			}
+			c.emit(opNumeric, 1, node)
+			return nil
+		}
+		if err := arg(0, opCheckNumber); err != nil {
+			return err
+		}
+		c.emit(opNeg, 0, node)
+	case nativeEq, nativeNeq:
+		if err := arg(0, opNorm); err != nil {
+			return err
+		}
+		if err := arg(1, opNorm); err != nil {
+			return err
+		}
+		if n.kind == nativeEq {
+			c.emit(opEq, 0, node)
+		} else {
+			c.emit(opNeq, 0, node)
+		}
+	case nativeAnd, nativeOr:
+		if err := arg(0, opCheckBool); err != nil {
+			return err
+		}
+		jump := opJumpIfFalse
+		if n.kind == nativeOr {
+			jump = opJumpIfTrue
+		}
+		at := c.emit(jump, 0, node)
+		if err := arg(1, opCheckBool); err != nil {
+			return err
+		}
+		c.patch(at)
+	case nativeNot:
+		if err := arg(0, opCheckBool); err != nil {
+			return err
+		}
+		c.emit(opNot, 0, node)
+	case nativeInverse:
+		if err := arg(0, opCheckInteger); err != nil {
+			return err
+		}
+		c.emit(opInverse, 0, node)
+	case nativeTernary:
+		if err := arg(0, opCheckBool); err != nil {
+			return err
+		}
+		elseAt := c.emit(opJumpIfFalsePop, 0, node)
+		if err := arg(1, opNorm); err != nil {
+			return err
+		}
+		endAt := c.emit(opJump, 0, node)
+		c.patch(elseAt)
+		if err := arg(2, opNorm); err != nil {
+			return err
+		}
+		c.patch(endAt)
+	case nativeCoalesce:
+		if err := arg(0, opNorm); err != nil {
+			return err
+		}
+		at := c.emit(opJumpIfNotNil, 0, node)
+		if err := arg(1, opNorm); err != nil {
+			return err
+		}
+		c.patch(at)
+	case nativeArray:
+		for i := range expr.Args {
+			if err := arg(i, opNorm); err != nil {
+				return err
+			}
+		}
+		c.emit(opArray, len(expr.Args), node)
+	case nativeIn:
+		if err := arg(0, opNorm); err != nil {
+			return err
+		}
+		if err := arg(1, opCheckSlice); err != nil {
+			return err
+		}
+		c.emit(opIn, 0, node)
+	case nativeString:
+		if err := arg(0, opNorm); err != nil {
+			return err
+		}
+		c.emit(opString, 0, node)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package evaluate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/pkg/decimal"
+)
+
+// corpus covers every builtin operator, short-circuiting, literal folding
+// and the error paths of Eval.
+var corpus = []string{
+	`1`, `"str"`, `true`, `nil`, `x`, `missing`,
+	`1 + 2 * 3`, `(1 + 2) * 3`, `x + y`, `x - y`, `x * y`, `x / y`, `x / 0`, `x ** 2`, `-x`, `- -x`,
+	`x % y`, `x % 0`, `x % n`, `1 % 0`, `x & 3`, `x | 3`, `x ^ 3`, `x << 2`, `x >> 1`, `x << -1`, `~x`, `f % 2`, `s % 2`,
+	`x < y`, `x <= y`, `x > y`, `x >= y`, `x == y`, `x != y`, `s == "abc"`, `n == nil`, `x == 3`,
+	`b && x > 1`, `b || missing`, `!b && missing`, `b && missing`, `!b || missing`, `x && b`, `b && x`,
+	`false && missing`, `true || missing`, `true && b`, `x && false`, `missing && false`,
+	`!b`, `!x`, `b ? x : y`, `!b ? x : y`, `x ? 1 : 2`, `true ? 1 : missing`, `false ? missing : x`, `true ? x : missing`,
+	`n ?? x`, `x ?? missing`, `n ?? missing`, `n ?? n`, `missing ?? 1`,
+	`[1, x, s]`, `[]`, `list[0]`, `list[1 + 1]`, `list[x]`, `list[-1]`, `list[f]`, `x[0]`, `[1, 2, 3][x - 1]`,
+	`x in [1, 2, 3]`, `s in list`, `x in list`, `x in s`, `"a" in ["a"]`,
+	`floor(f)`, `ceil(f)`, `round(f)`, `sqrt(x)`, `sin(x)`, `cos(x)`, `tan(x)`, `tanh(x)`, `abs(-f)`,
+	`log(x)`, `log2(x)`, `log10(x)`, `min(x, y)`, `max(x, y)`, `min(x)`, `max(x, y, 1)`, `floor(s)`,
+	`string(x)`, `string(list)`, `string(f) + 1`, `string()`,
+	`0 + s`, `s * 0`, `s * 1`, `s / 1`, `0 - s`, `s - 0`,
+	`1 + (2 * (s + 3))`, `x + (y ? 1 : 2)`, `[x, s + 1]`, `-(s)`, `(b ? list : x)[0]`,
+	`expr + 1`, `self + 1`, `undefined_fn(1)`, `double(x) + 1`, `double(s)`,
+	`int8 + int16 + int32 + int64 + uint + uint8 + uint16 + uint32 + uint64 + f32`, `int8`, `[int8][0]`,
+	`i + 1`, `i`, `i == 5`, `i ?? 1`, `b ? i : 0`,
+	`dec * 2`, `dec == f`, `dec in [2.5]`, `dec != 1`, `dec`, `floor(dec)`,
+}
+
+func corpusVariables() []map[string]interface{} {
+	base := map[string]interface{}{
+		"x": 3.0, "y": 4.0, "f": 2.5, "s": "abc", "b": true, "n": nil,
+		"list": []interface{}{1.0, "abc", 3.0},
+		"i":    5,
+		"int8": int8(1), "int16": int16(2), "int32": int32(3), "int64": int64(4),
+		"uint": uint(5), "uint8": uint8(6), "uint16": uint16(7), "uint32": uint32(8), "uint64": uint64(9),
+		"f32":  float32(0.5),
+		"dec":  decimal.RequireFromString("2.50"),
+		"expr": MustParse("x * y"),
+		"self": MustParse("self + 1"),
+	}
+	flipped := map[string]interface{}{}
+	for k, v := range base {
+		flipped[k] = v
+	}
+	flipped["b"] = false
+	flipped["x"] = 2
+	flipped["y"] = "4"
+	flipped["n"] = 0.0
+	return []map[string]interface{}{base, flipped, {}}
+}
+
+func init() {
+	AddCustomOperator("double", func(ctx EvalContext) (interface{}, error) {
+		if err := ctx.CheckArgCount(1); err != nil {
+			return nil, err
+		}
+		v, err := ctx.NumericArg(0)
+		return v * 2, err
+	})
+}
+
+func evalSafely(fn func() (interface{}, error)) (val interface{}, err error, panicked interface{}) {
+	defer func() {
+		panicked = recover()
+	}()
+	val, err = fn()
+	return
+}
+
+func TestProgramMatchesEval(t *testing.T) {
+	for _, input := range corpus {
+		expr := MustParse(input)
+		program, err := CompileNode(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		for i, vars := range corpusVariables() {
+			want, wantErr, wantPanic := evalSafely(func() (interface{}, error) {
+				return expr.Eval(NewEvalParams(vars))
+			})
+			got, gotErr, gotPanic := evalSafely(func() (interface{}, error) {
+				return program.Run(vars)
+			})
+			if fmt.Sprint(gotPanic) != fmt.Sprint(wantPanic) {
+				t.Errorf("%s with variables #%d: panic %v, want %v", input, i, gotPanic, wantPanic)
+				continue
+			}
+			if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("%s with variables #%d: error %v, want %v", input, i, gotErr, wantErr)
+				continue
+			}
+			if wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("%s with variables #%d: got %#v, want %#v", input, i, got, want)
+			}
+		}
+	}
+}
+
+func TestProgramRunValues(t *testing.T) {
+	program := MustCompile("a * 2 + b")
+	if vars := program.Vars(); !reflect.DeepEqual(vars, []string{"a", "b"}) {
+		t.Fatalf("unexpected slots %v", vars)
+	}
+	got, err := program.RunValues(2, 1.5)
+	if err != nil || got != 5.5 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if _, err := program.RunValues(1); err == nil {
+		t.Fatal("expected an error for a missing value")
+	}
+}
+
+func TestCompileFolds(t *testing.T) {
+	program := MustCompile("x > 2 * 3 + 1 && (false && y)")
+	for _, in := range program.code {
+		if in.op == opVar && program.vars[in.arg] == "y" {
+			t.Fatal("false && y was not folded")
+		}
+	}
+	var numeric int
+	for _, in := range program.code {
+		if in.op == opNumeric {
+			numeric++
+		}
+	}
+	if numeric != 1 {
+		t.Fatalf("2 * 3 + 1 was not folded: %d numeric instructions", numeric)
+	}
+}
+
+func TestCompileModuloByZero(t *testing.T) {
+	for _, input := range []string{`x % 0`, `x % z`, `1 % 0`} {
+		program, err := Compile(input)
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		_, err, panicked := evalSafely(func() (interface{}, error) {
+			return program.Run(map[string]interface{}{"x": 7, "z": 0})
+		})
+		if panicked != nil || err == nil || !strings.Contains(err.Error(), "division by zero") {
+			t.Errorf("%s: expected a division by zero error, got %v, panic %v", input, err, panicked)
+		}
+	}
+}
+
+var benchmarkExpr = strings.Repeat("(price * qty + tax) * 2 > limit && ", 4) + "active"
+
+func benchmarkVars() map[string]interface{} {
+	return map[string]interface{}{"price": 12.5, "qty": 4, "tax": 1.2, "limit": 10.0, "active": true}
+}
+
+func BenchmarkEval(b *testing.B) {
+	expr := MustParse(benchmarkExpr)
+	params := NewEvalParams(benchmarkVars())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = expr.Eval(params)
+	}
+}
+
+func BenchmarkProgramRun(b *testing.B) {
+	program := MustCompile(benchmarkExpr)
+	vars := benchmarkVars()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = program.Run(vars)
+	}
+}
@@ -0,0 +1,263 @@
+package evaluate
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/oarkflow/pkg/decimal"
+)
+
+// RoundingMode selects how decimal results are rounded by round() and by
+// divisions that do not terminate.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero, like math.Round.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the even neighbour (banker's rounding).
+	RoundHalfEven
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundCeiling rounds towards positive infinity.
+	RoundCeiling
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor
+)
+
+// DecimalMode makes Eval represent numbers as decimal.Decimal instead of
+// float64. Numeric literals, numeric variables and operator results are
+// converted, and arithmetic, comparisons, round, floor, ceil, abs, min and max
+// are computed exactly. Other math functions fall back to float64.
+type DecimalMode struct {
+	Rounding RoundingMode
+	// DivisionPrecision is the number of decimal places kept by divisions
+	// that do not terminate; decimal.DivisionPrecision is used if zero.
+	DivisionPrecision int32
+}
+
+// NewDecimalEvalParams returns params evaluating numbers as decimals.
+func NewDecimalEvalParams(variables map[string]interface{}, mode DecimalMode) EvalParams {
+	params := NewEvalParams(variables)
+	params.Decimal = &mode
+	return params
+}
+
+// number converts numeric values to decimal.Decimal in decimal mode.
+func (params EvalParams) number(val interface{}) interface{} {
+	if params.Decimal == nil {
+		return val
+	}
+	if d, ok := toDecimal(val); ok {
+		return d
+	}
+	return val
+}
+
+func toDecimal(val interface{}) (decimal.Decimal, bool) {
+	switch v := val.(type) {
+	case decimal.Decimal:
+		return v, true
+	case *decimal.Decimal:
+		if v != nil {
+			return *v, true
+		}
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return decimal.NewFromFloat(v), true
+		}
+	case float32:
+		if !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0) {
+			return decimal.NewFromFloat32(v), true
+		}
+	case int:
+		return decimal.NewFromInt(int64(v)), true
+	case int8:
+		return decimal.NewFromInt(int64(v)), true
+	case int16:
+		return decimal.NewFromInt(int64(v)), true
+	case int32:
+		return decimal.NewFromInt(int64(v)), true
+	case int64:
+		return decimal.NewFromInt(v), true
+	case uint:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(uint64(v)), 0), true
+	case uint8:
+		return decimal.NewFromInt(int64(v)), true
+	case uint16:
+		return decimal.NewFromInt(int64(v)), true
+	case uint32:
+		return decimal.NewFromInt(int64(v)), true
+	case uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(v), 0), true
+	}
+	return decimal.Decimal{}, false
+}
+
+// valuesEqual compares like ==, but decimals are equal to numbers of the
+// same value.
+func valuesEqual(a, b interface{}) bool {
+	_, aDec := a.(decimal.Decimal)
+	_, bDec := b.(decimal.Decimal)
+	if aDec || bDec {
+		da, ok1 := toDecimal(a)
+		db, ok2 := toDecimal(b)
+		return ok1 && ok2 && da.Equal(db)
+	}
+	return a == b
+}
+
+func (m *DecimalMode) precision() int32 {
+	if m.DivisionPrecision > 0 {
+		return m.DivisionPrecision
+	}
+	return int32(decimal.DivisionPrecision)
+}
+
+func (m *DecimalMode) round(d decimal.Decimal, places int32) decimal.Decimal {
+	switch m.Rounding {
+	case RoundHalfEven:
+		return d.RoundBank(places)
+	case RoundDown:
+		return d.RoundDown(places)
+	case RoundUp:
+		return d.RoundUp(places)
+	case RoundCeiling:
+		return d.RoundCeil(places)
+	case RoundFloor:
+		return d.RoundFloor(places)
+	}
+	return d.Round(places)
+}
+
+// div divides a by a non zero b, rounding the quotient to the division
+// precision with the rounding mode.
+func (m *DecimalMode) div(a, b decimal.Decimal) decimal.Decimal {
+	precision := m.precision()
+	if m.Rounding == RoundHalfUp {
+		return a.DivRound(b, precision)
+	}
+	// q is truncated towards zero, the exact quotient lies between q and
+	// q + ulp in the direction of its sign
+	q, r := a.QuoRem(b, precision)
+	if r.IsZero() {
+		return q
+	}
+	negative := a.Sign()*b.Sign() < 0
+	ulp := decimal.New(1, -precision)
+	if negative {
+		ulp = ulp.Neg()
+	}
+	away := false
+	switch m.Rounding {
+	case RoundUp:
+		away = true
+	case RoundCeiling:
+		away = !negative
+	case RoundFloor:
+		away = negative
+	case RoundHalfEven:
+		// compare the remainder with half of the divisor's ulp
+		c := r.Abs().Shift(precision).Mul(decimal.NewFromInt(2)).Cmp(b.Abs())
+		away = c > 0 || (c == 0 && q.Shift(precision).BigInt().Bit(0) != 0)
+	}
+	if away {
+		return q.Add(ulp)
+	}
+	return q
+}
+
+func (ctx EvalContext) DecimalArg(idx int) (decimal.Decimal, error) {
+	val, err := ctx.Arg(idx)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if d, ok := toDecimal(val); ok {
+		return d, nil
+	}
+	return decimal.Decimal{}, formatArgError(ctx.expr, idx, "is not numeric: %v", val)
+}
+
+func binaryDecimalArgs(ctx EvalContext) (decimal.Decimal, decimal.Decimal, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, err
+	}
+	left, err := ctx.DecimalArg(0)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, err
+	}
+	right, err := ctx.DecimalArg(1)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, err
+	}
+	return left, right, nil
+}
+
+// decimalBinary evaluates a binary operator on decimals.
+func decimalBinary(ctx EvalContext, fn func(a, b decimal.Decimal) (interface{}, error)) (interface{}, error) {
+	a, b, err := binaryDecimalArgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fn(a, b)
+}
+
+func decimalDiv(ctx EvalContext) (interface{}, error) {
+	return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+		if b.IsZero() {
+			return nil, ctx.FormatError("division by zero")
+		}
+		return ctx.params.Decimal.div(a, b), nil
+	})
+}
+
+func decimalPow(ctx EvalContext) (interface{}, error) {
+	return decimalBinary(ctx, func(a, b decimal.Decimal) (interface{}, error) {
+		if !b.IsInteger() {
+			return ctx.params.number(math.Pow(a.InexactFloat64(), b.InexactFloat64())), nil
+		}
+		// the coefficient of the power grows by the bits of a for each unit
+		// of b; charge it before computing a power that may not fit
+		if bitLen := a.Coefficient().BitLen(); bitLen > 1 {
+			size := math.Min(b.Abs().InexactFloat64()*float64(bitLen)/8, math.MaxInt64)
+			if err := ctx.Charge(0, int64(size)); err != nil {
+				return nil, err
+			}
+		}
+		if b.IsNegative() {
+			if a.IsZero() {
+				return nil, ctx.FormatError("division by zero")
+			}
+			return ctx.params.Decimal.div(decimal.NewFromInt(1), a.Pow(b.Neg())), nil
+		}
+		return a.Pow(b), nil
+	})
+}
+
+// decimalRound rounds to the optional number of places given as second
+// argument.
+func decimalRound(ctx EvalContext, round func(d decimal.Decimal, places int32) decimal.Decimal) (interface{}, error) {
+	if ctx.ArgCount() != 1 {
+		if err := ctx.CheckArgCount(2); err != nil {
+			return nil, err
+		}
+	}
+	d, err := ctx.DecimalArg(0)
+	if err != nil {
+		return nil, err
+	}
+	var places int
+	if ctx.ArgCount() == 2 {
+		if places, err = ctx.IntegerArg(1); err != nil {
+			return nil, err
+		}
+	}
+	// each place adds a digit to the coefficient
+	if extra := places + int(d.Exponent()); extra > 0 {
+		if err := ctx.Charge(0, int64(extra)/2); err != nil {
+			return nil, err
+		}
+	}
+	return round(d, int32(places)), nil
+}
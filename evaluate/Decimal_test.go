@@ -0,0 +1,85 @@
+package evaluate
+
+import (
+	"testing"
+
+	"github.com/oarkflow/pkg/decimal"
+)
+
+func TestDecimalMode(t *testing.T) {
+	vars := map[string]interface{}{
+		"qty":   3,
+		"price": decimal.RequireFromString("19999999.99"),
+		"rate":  0.13,
+	}
+	for _, tt := range []struct {
+		expr     string
+		rounding RoundingMode
+		want     string
+	}{
+		{expr: `0.1 + 0.2`, want: "0.3"},
+		{expr: `qty * price`, want: "59999999.97"},
+		{expr: `round(qty * price * rate, 2)`, want: "7800000"},
+		{expr: `qty * price * rate`, want: "7799999.9961"},
+		{expr: `round(2.345, 2)`, want: "2.35"},
+		{expr: `round(2.345, 2)`, rounding: RoundHalfEven, want: "2.34"},
+		{expr: `round(-2.345, 2)`, rounding: RoundDown, want: "-2.34"},
+		{expr: `round(-2.341, 2)`, rounding: RoundUp, want: "-2.35"},
+		{expr: `round(2.341, 2)`, rounding: RoundCeiling, want: "2.35"},
+		{expr: `round(-2.341, 2)`, rounding: RoundFloor, want: "-2.35"},
+		{expr: `round(2.5)`, want: "3"},
+		{expr: `floor(-2.5)`, want: "-3"},
+		{expr: `ceil(2.01)`, want: "3"},
+		{expr: `floor(2.567, 1)`, want: "2.5"},
+		{expr: `abs(-1.5)`, want: "1.5"},
+		{expr: `min(qty, 2.5)`, want: "2.5"},
+		{expr: `max(qty, 2.5)`, want: "3"},
+		{expr: `-price`, want: "-19999999.99"},
+		{expr: `1 / 3`, want: "0.3333333333333333"},
+		{expr: `2 / 3`, rounding: RoundDown, want: "0.6666666666666666"},
+		{expr: `2 / 3`, rounding: RoundHalfEven, want: "0.6666666666666667"},
+		{expr: `-2 / 3`, rounding: RoundCeiling, want: "-0.6666666666666666"},
+		{expr: `-2 / 3`, rounding: RoundFloor, want: "-0.6666666666666667"},
+		{expr: `2 ** 10`, want: "1024"},
+		{expr: `2 ** -2`, want: "0.25"},
+	} {
+		params := NewDecimalEvalParams(vars, DecimalMode{Rounding: tt.rounding})
+		got, err := MustParse(tt.expr).Eval(params)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		d, ok := got.(decimal.Decimal)
+		if !ok {
+			t.Errorf("%s: got %T, want decimal.Decimal", tt.expr, got)
+			continue
+		}
+		if d.String() != tt.want {
+			t.Errorf("%s with rounding %d: got %s, want %s", tt.expr, tt.rounding, d, tt.want)
+		}
+	}
+}
+
+func TestDecimalModeComparisons(t *testing.T) {
+	params := NewDecimalEvalParams(map[string]interface{}{"total": 0.3, "n": 2}, DecimalMode{})
+	for expr, want := range map[string]bool{
+		`0.1 + 0.2 == 0.3`:       true,
+		`0.1 + 0.2 == total`:     true,
+		`0.1 + 0.2 != total`:     false,
+		`total < 0.30000000001`:  true,
+		`total >= 0.3`:           true,
+		`n in [1, 2]`:            true,
+		`n == 2 && total > 0.29`: true,
+	} {
+		got, err := MustParse(expr).Eval(params)
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", expr, got, err, want)
+		}
+	}
+	if _, err := MustParse(`1 / (total - 0.3)`).Eval(params); err == nil {
+		t.Error("expected division by zero error")
+	}
+	if _, err := MustParse(`total + "a"`).Eval(params); err == nil {
+		t.Error("expected non numeric error")
+	}
+}
@@ -1,6 +1,7 @@
 package evaluate
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -8,12 +9,31 @@ import (
 type EvalParams struct {
 	Variables map[string]interface{}
 	Operators map[string]Operator
+	// Decimal, when set, evaluates numbers as decimal.Decimal.
+	Decimal *DecimalMode
+
+	// Context, MaxSteps and MaxAlloc limit the evaluation of untrusted
+	// expressions. It is aborted with a *LimitError when the context is done,
+	// after MaxSteps nodes and operator loop iterations, or once operators
+	// allocated about MaxAlloc bytes. Zero values do not limit.
+	Context  context.Context
+	MaxSteps int64
+	MaxAlloc int64
+
+	budget *budget
 }
 
 func (expr ExprNode) Eval(params EvalParams) (interface{}, error) {
+	if params.budget == nil {
+		if params.limited() {
+			return expr.evalLimited(params)
+		}
+	} else if err := params.budget.charge(1, 0); err != nil {
+		return nil, err
+	}
 	switch expr.Type {
 	case NodeTypeLiteral:
-		return expr.Value, nil
+		return params.number(expr.Value), nil
 	case NodeTypeVariable:
 		/*
 			var value any
@@ -40,13 +60,11 @@ func (expr ExprNode) Eval(params EvalParams) (interface{}, error) {
 		// Check if var is a node that can be Eval'd
 		node, nodeType := value.(ExprNode)
 		if !nodeType {
-			return value, nil
+			return params.number(value), nil
 		}
 
-		for _, v := range node.Vars() {
-			if v == expr.Name {
-				return nil, fmt.Errorf("variable can not refer to itself: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen)
-			}
+		if refersTo(node.Vars(), expr.Name) {
+			return nil, fmt.Errorf("variable can not refer to itself: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen)
 		}
 		return node.Eval(params)
 	case NodeTypeOperator:
@@ -54,7 +72,13 @@ func (expr ExprNode) Eval(params EvalParams) (interface{}, error) {
 		if !ok {
 			return nil, fmt.Errorf("operator undefined: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen)
 		}
-		return operator(EvalContext{params: params, expr: expr})
+		value, err := operator(EvalContext{params: params, expr: expr})
+		if params.budget != nil && err == nil && !passThrough[expr.Name] {
+			if err := params.budget.charge(0, sizeOf(value)); err != nil {
+				return nil, err
+			}
+		}
+		return params.number(value), err
 	}
 	return nil, fmt.Errorf("bad expr type: %v", expr)
 }
@@ -1,6 +1,10 @@
 package evaluate
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/oarkflow/pkg/decimal"
+)
 
 type Operator func(ctx EvalContext) (interface{}, error)
 
@@ -28,7 +32,7 @@ func (ctx EvalContext) Arg(idx int) (interface{}, error) {
 
 	val, err := args[idx].Eval(ctx.params)
 	if err != nil {
-		return val, fmt.Errorf("%s / %s", formatArgName(ctx.expr, idx), err.Error())
+		return val, fmt.Errorf("%s / %w", formatArgName(ctx.expr, idx), err)
 	}
 
 	switch v := val.(type) {
@@ -76,8 +80,11 @@ func (ctx EvalContext) NumericArg(idx int) (float64, error) {
 		return 0.0, err
 	}
 
-	if numVal, ok := val.(float64); ok {
+	switch numVal := val.(type) {
+	case float64:
 		return numVal, nil
+	case decimal.Decimal:
+		return numVal.InexactFloat64(), nil
 	}
 
 	return 0.0, formatArgError(ctx.expr, idx, "is not numeric: %v", val)
@@ -153,6 +160,12 @@ func formatArgName(expr ExprNode, idx int) string {
 		} else if idx == 1 {
 			return "index"
 		}
+	case OperatorTypeMember:
+		if idx == 0 {
+			return "member receiver"
+		}
+	case OperatorTypeLambda:
+		return "lambda body"
 	}
 	return fmt.Sprintf("argument #%d of %s", idx+1, expr.Name)
 }
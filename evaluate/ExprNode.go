@@ -43,6 +43,12 @@ const (
 	OperatorTypeTernary
 	OperatorTypeArray
 	OperatorTypeIndexer
+	// OperatorTypeMember is field access, x.name; the field name is a
+	// string literal in Args[1].
+	OperatorTypeMember
+	// OperatorTypeLambda is a function literal, x => body or (a, b) => body.
+	// Args holds the parameters as variables followed by the body.
+	OperatorTypeLambda
 )
 
 // NewExprNodeLiteral constructs a literal node.
@@ -118,6 +124,26 @@ func collectVars(expr ExprNode, output map[string]int) {
 	case NodeTypeVariable:
 		output[expr.Name]++
 	case NodeTypeOperator:
+		if expr.OperatorType == OperatorTypeLambda {
+			// parameters are bound by the lambda, not read from variables
+			body := map[string]int{}
+			collectVars(expr.Args[len(expr.Args)-1], body)
+		body:
+			for name, count := range body {
+				for _, param := range expr.Args[:len(expr.Args)-1] {
+					if refersTo([]string{name}, param.Name) {
+						continue body
+					}
+				}
+				output[name] += count
+			}
+			return
+		}
+		// member chains report the full path, e.g. order.customer.city
+		if path, ok := memberPath(expr); ok {
+			output[path]++
+			return
+		}
 		for _, arg := range expr.Args {
 			collectVars(arg, output)
 		}
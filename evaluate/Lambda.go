@@ -0,0 +1,63 @@
+package evaluate
+
+import "fmt"
+
+// Lambda is a function literal passed as an operator argument, e.g. the
+// x => x.price * x.qty in map(items, x => x.price * x.qty).
+type Lambda struct {
+	ctx    EvalContext
+	idx    int
+	params []string
+	body   ExprNode
+	scope  EvalParams
+}
+
+// LambdaArg returns the lambda passed as argument idx. The lambda sees the
+// variables of the expression, with its parameters shadowing them.
+func (ctx EvalContext) LambdaArg(idx int) (*Lambda, error) {
+	args := ctx.expr.Args
+	if idx >= len(args) {
+		return nil, ctx.FormatError("requested argument #%d, but argument count is %d", idx+1, len(args))
+	}
+	arg := args[idx]
+	if arg.OperatorType != OperatorTypeLambda || !arg.IsOperator("=>") {
+		return nil, formatArgError(ctx.expr, idx, "is not a lambda")
+	}
+	last := len(arg.Args) - 1
+	params := make([]string, last)
+	for i, param := range arg.Args[:last] {
+		params[i] = param.Name
+	}
+	variables := make(map[string]interface{}, len(ctx.params.Variables)+len(params))
+	for name, value := range ctx.params.Variables {
+		variables[name] = value
+	}
+	scope := ctx.params
+	scope.Variables = variables
+	return &Lambda{ctx: ctx, idx: idx, params: params, body: arg.Args[last], scope: scope}, nil
+}
+
+// Arity returns the number of parameters.
+func (l *Lambda) Arity() int {
+	return len(l.params)
+}
+
+// Call evaluates the body with the parameters bound to args. Extra args
+// are ignored, so callers may pass optional values like the item index.
+func (l *Lambda) Call(args ...interface{}) (interface{}, error) {
+	if len(l.params) > len(args) {
+		return nil, formatArgError(l.ctx.expr, l.idx, "has %d parameters, expected at most %d", len(l.params), len(args))
+	}
+	for i, param := range l.params {
+		l.scope.Variables[param] = args[i]
+	}
+	val, err := l.body.Eval(l.scope)
+	if err != nil {
+		return val, fmt.Errorf("%s / %w", formatArgName(l.ctx.expr, l.idx), err)
+	}
+	return normalize(val), nil
+}
+
+func builtinLambda(ctx EvalContext) (interface{}, error) {
+	return nil, ctx.FormatError("lambda can only be passed to a function")
+}
@@ -0,0 +1,147 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"time"
+
+	"github.com/oarkflow/pkg/decimal"
+)
+
+// Limit names the limit a LimitError hit.
+type Limit string
+
+const (
+	LimitContext Limit = "context"
+	LimitSteps   Limit = "steps"
+	LimitAlloc   Limit = "alloc"
+)
+
+// LimitError aborts an evaluation that ran past a limit of its EvalParams:
+// the context was done, or it took more than MaxSteps steps, or allocated
+// more than MaxAlloc bytes. Errors of operators wrap it, so check for it
+// with errors.As.
+type LimitError struct {
+	Limit Limit
+	// Max is the exceeded budget, for LimitSteps and LimitAlloc.
+	Max int64
+	// Err is the context error, for LimitContext.
+	Err error
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitSteps:
+		return fmt.Sprintf("evaluation limit exceeded: more than %d steps", e.Max)
+	case LimitAlloc:
+		return fmt.Sprintf("evaluation limit exceeded: more than %d bytes allocated", e.Max)
+	}
+	return fmt.Sprintf("evaluation aborted: %v", e.Err)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// budget counts the steps and allocations of one evaluation. Once a limit
+// is hit it stays exceeded, so an operator ignoring the error of an argument
+// fails on the next one it evaluates.
+type budget struct {
+	ctx      context.Context
+	steps    int64
+	maxSteps int64
+	alloc    int64
+	maxAlloc int64
+	err      *LimitError
+}
+
+// contextCheckInterval is the number of steps between checks of the context.
+const contextCheckInterval = 64
+
+func (params EvalParams) limited() bool {
+	return params.Context != nil || params.MaxSteps > 0 || params.MaxAlloc > 0
+}
+
+// evalLimited evaluates expr under a new budget. The limit error is returned
+// even if an operator swallowed it.
+func (expr ExprNode) evalLimited(params EvalParams) (interface{}, error) {
+	params.budget = &budget{ctx: params.Context, maxSteps: params.MaxSteps, maxAlloc: params.MaxAlloc}
+	if err := params.budget.charge(0, 0); err != nil {
+		return nil, err
+	}
+	val, err := expr.Eval(params)
+	if limitErr := params.budget.err; limitErr != nil && !errors.As(err, new(*LimitError)) {
+		return nil, limitErr
+	}
+	return val, err
+}
+
+func (b *budget) charge(steps, bytes int64) error {
+	if b.err != nil {
+		return b.err
+	}
+	before := b.steps
+	b.steps += steps
+	b.alloc += bytes
+	switch {
+	case b.maxSteps > 0 && b.steps > b.maxSteps:
+		b.err = &LimitError{Limit: LimitSteps, Max: b.maxSteps}
+	case b.maxAlloc > 0 && b.alloc > b.maxAlloc:
+		b.err = &LimitError{Limit: LimitAlloc, Max: b.maxAlloc}
+	case b.ctx != nil && (steps == 0 || before/contextCheckInterval != b.steps/contextCheckInterval):
+		if err := b.ctx.Err(); err != nil {
+			b.err = &LimitError{Limit: LimitContext, Err: err}
+		}
+	}
+	if b.err != nil {
+		return b.err
+	}
+	return nil
+}
+
+// Charge counts work done by an operator against the limits of the
+// evaluation: steps for loops over items and bytes for values about to be
+// allocated. Operators doing work that does not go through Arg should
+// charge it, and stop on the returned error.
+func (ctx EvalContext) Charge(steps, bytes int64) error {
+	if ctx.params.budget == nil {
+		return nil
+	}
+	return ctx.params.budget.charge(steps, bytes)
+}
+
+// Context returns the context of the evaluation.
+func (ctx EvalContext) Context() context.Context {
+	if ctx.params.Context == nil {
+		return context.Background()
+	}
+	return ctx.params.Context
+}
+
+// passThrough lists the operators returning one of their arguments or a
+// part of it, which allocate nothing.
+var passThrough = map[string]bool{".": true, "?.": true, "[]": true, "?:": true, "??": true}
+
+// sizeOf estimates the bytes allocated for a result, not counting the
+// items of arrays and objects, which were counted when they were created.
+func sizeOf(val interface{}) int64 {
+	switch v := val.(type) {
+	case string:
+		return int64(len(v))
+	case []interface{}:
+		return 16 * int64(len(v))
+	case map[string]interface{}:
+		return 48 * int64(len(v))
+	case decimal.Decimal:
+		return 16 + decimalBytes(v)
+	case time.Time:
+		return 24
+	}
+	return 16
+}
+
+func decimalBytes(d decimal.Decimal) int64 {
+	return int64(len(d.Coefficient().Bits())) * bits.UintSize / 8
+}
@@ -0,0 +1,75 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEvalLimits(t *testing.T) {
+	items := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	vars := map[string]interface{}{"items": items, "s": "abcdefghij"}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, test := range []struct {
+		expr   string
+		params EvalParams
+		limit  Limit
+	}{
+		{`sum(map(items, x => x * 2))`, EvalParams{MaxSteps: 500}, LimitSteps},
+		{`reduce(items, (acc, x) => acc + x, 0)`, EvalParams{MaxSteps: 1000}, LimitSteps},
+		{`unique(items)`, EvalParams{MaxSteps: 100}, LimitSteps},
+		{`map(items, x => [x, x, x, x])`, EvalParams{MaxAlloc: 10000}, LimitAlloc},
+		{`replace(s, "", join(items, ""))`, EvalParams{MaxAlloc: 10000}, LimitAlloc},
+		{`format("%0999999999d", 1)`, EvalParams{MaxAlloc: 1 << 20}, LimitAlloc},
+		{`1 + 1`, EvalParams{Context: canceled}, LimitContext},
+		{`swallow(sum(items))`, EvalParams{MaxSteps: 10}, LimitSteps},
+	} {
+		params := NewEvalParams(vars)
+		params.Operators = map[string]Operator{"swallow": func(ctx EvalContext) (interface{}, error) {
+			_, _ = ctx.Arg(0)
+			return "ok", nil
+		}}
+		for name, op := range defaultOperators.builtin {
+			params.Operators[name] = op
+		}
+		params.Context, params.MaxSteps, params.MaxAlloc = test.params.Context, test.params.MaxSteps, test.params.MaxAlloc
+		_, err := MustParse(test.expr).Eval(params)
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+			t.Errorf("%s: expected a %s limit error, got %v", test.expr, test.limit, err)
+		}
+	}
+	if _, err := MustParse(`1 + 1`).Eval(EvalParams{Context: canceled, Operators: defaultOperators.builtin}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context error to be wrapped, got %v", err)
+	}
+
+	decimal := NewDecimalEvalParams(nil, DecimalMode{})
+	decimal.MaxAlloc = 1 << 20
+	for _, expr := range []string{`3 ** 100000000`, `round(1.5, 100000000)`} {
+		var limitErr *LimitError
+		if _, err := MustParse(expr).Eval(decimal); !errors.As(err, &limitErr) {
+			t.Errorf("%s: expected a limit error, got %v", expr, err)
+		}
+	}
+
+	for _, params := range []EvalParams{NewEvalParams(map[string]interface{}{"x": 7, "y": 0}), NewDecimalEvalParams(map[string]interface{}{"x": 7, "y": 0}, DecimalMode{})} {
+		params.MaxSteps = 100
+		_, err, panicked := evalSafely(func() (interface{}, error) {
+			return MustParse(`x % y`).Eval(params)
+		})
+		if panicked != nil || err == nil {
+			t.Errorf("x %% y: expected a division by zero error, got %v, panic %v", err, panicked)
+		}
+	}
+
+	params := NewEvalParams(vars)
+	params.MaxSteps, params.MaxAlloc = 10000, 1<<20
+	if got, err := MustParse(`sum(map(items, x => x * 2))`).Eval(params); err != nil || got != 999000.0 {
+		t.Errorf("evaluation within limits failed: %v, %v", got, err)
+	}
+}
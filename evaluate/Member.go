@@ -0,0 +1,116 @@
+package evaluate
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/oarkflow/pkg/dipper"
+)
+
+// builtinMember reads a field of an object, like order.customer. With ?. a
+// nil receiver or a missing field yields nil instead of an error, and so do
+// the members read after it: order?.customer.city is nil without an order.
+func builtinMember(ctx EvalContext) (interface{}, error) {
+	if err := ctx.CheckArgCount(2); err != nil {
+		return nil, err
+	}
+	receiver, err := ctx.Arg(0)
+	if err != nil {
+		return nil, err
+	}
+	key, _ := ctx.expr.Args[1].Value.(string)
+	return member(ctx, receiver, key)
+}
+
+// member reads key from a map, or from a struct field matched by name or
+// json tag. Anything but maps and structs are resolved with dipper.
+func member(ctx EvalContext, receiver interface{}, key string) (interface{}, error) {
+	optional := ctx.expr.Name == "?."
+	if isNil(receiver) && (optional || optionalChain(ctx.expr.Args[0])) {
+		return nil, nil
+	}
+	if object, ok := receiver.(map[string]interface{}); ok {
+		if value, ok := object[key]; ok {
+			return value, nil
+		}
+		if optional {
+			return nil, nil
+		}
+		return nil, ctx.FormatError("field undefined: %s", key)
+	}
+	if kind := reflect.Indirect(reflect.ValueOf(receiver)).Kind(); kind != reflect.Map && kind != reflect.Struct {
+		return nil, formatArgError(ctx.expr, 0, "is not an object: %v", receiver)
+	}
+	value := dipper.Get(receiver, key)
+	switch err := dipper.Error(value); {
+	case err == nil:
+		return value, nil
+	case err == dipper.ErrNotFound && optional:
+		return nil, nil
+	case err == dipper.ErrNotFound:
+		return nil, ctx.FormatError("field undefined: %s", key)
+	default:
+		return nil, ctx.FormatError("field %s: %v", key, err)
+	}
+}
+
+// optionalChain reports whether a member or index chain contains a ?.
+func optionalChain(expr ExprNode) bool {
+	for expr.Type == NodeTypeOperator && (expr.OperatorType == OperatorTypeMember || expr.OperatorType == OperatorTypeIndexer) {
+		if expr.Name == "?." {
+			return true
+		}
+		expr = expr.Args[0]
+	}
+	return false
+}
+
+func isNil(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// memberPath returns the dotted path of a member or index chain rooted at a
+// variable, e.g. items.0.sku for items[0].sku. Chains with computed indexes
+// have no path.
+func memberPath(expr ExprNode) (string, bool) {
+	switch {
+	case expr.Type == NodeTypeVariable:
+		return expr.Name, true
+	case expr.Type != NodeTypeOperator || len(expr.Args) != 2:
+		return "", false
+	case expr.OperatorType != OperatorTypeMember && expr.OperatorType != OperatorTypeIndexer:
+		return "", false
+	}
+	path, ok := memberPath(expr.Args[0])
+	if !ok || expr.Args[1].Type != NodeTypeLiteral {
+		return "", false
+	}
+	switch key := expr.Args[1].Value.(type) {
+	case string:
+		return path + "." + key, true
+	case float64:
+		if key >= 0 && key == float64(int(key)) {
+			return path + "." + strconv.Itoa(int(key)), true
+		}
+	}
+	return "", false
+}
+
+// refersTo reports whether vars read the variable name, or a path below it.
+func refersTo(vars []string, name string) bool {
+	for _, v := range vars {
+		if v == name || strings.HasPrefix(v, name+".") {
+			return true
+		}
+	}
+	return false
+}
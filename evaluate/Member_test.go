@@ -0,0 +1,110 @@
+package evaluate
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testCustomer struct {
+	Name    string
+	Address *testAddress `json:"address"`
+}
+
+type testItem struct {
+	SKU string `json:"sku"`
+	Qty int
+}
+
+func TestMemberAccess(t *testing.T) {
+	vars := map[string]interface{}{
+		"order": map[string]interface{}{
+			"customer": map[string]interface{}{
+				"address": map[string]interface{}{"city": "Kathmandu"},
+			},
+			"items": []interface{}{
+				map[string]interface{}{"sku": "A", "qty": 2},
+			},
+			"discount": nil,
+		},
+		"buyer":  testCustomer{Name: "Ada", Address: &testAddress{City: "London"}},
+		"guest":  &testCustomer{Name: "Bob"},
+		"items":  []testItem{{SKU: "X", Qty: 3}, {SKU: "Y", Qty: 5}},
+		"prices": map[string]float64{"X": 1.5},
+		"none":   nil,
+	}
+	for expr, want := range map[string]interface{}{
+		`order.customer.address.city`:                 "Kathmandu",
+		`order.items[0].sku`:                          "A",
+		`order.items[0].qty * 2`:                      4.0,
+		`order["customer"].address["city"]`:           "Kathmandu",
+		`order?.discount ?? 0`:                        0.0,
+		`order?.coupon ?? "none"`:                     "none",
+		`none?.customer.address.city ?? "-"`:          "-",
+		`order.customer?.phone`:                       nil,
+		`buyer.Name`:                                  "Ada",
+		`buyer.address.city`:                          "London",
+		`guest?.address?.city ?? "unknown"`:           "unknown",
+		`items[1].sku`:                                "Y",
+		`items[0].Qty + items[1].Qty`:                 8.0,
+		`prices.X`:                                    1.5,
+		`sum(map(order.items, x => x.qty))`:           2.0,
+		`order.customer.address.city != ""`:           true,
+		`len(order.items) > 0 && buyer.Name == "Ada"`: true,
+	} {
+		got, err := MustParse(expr).Eval(NewEvalParams(vars))
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", expr, got, want)
+		}
+		program, err := Compile(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if got, err := program.Run(vars); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: program got %#v, %v", expr, got, err)
+		}
+	}
+	for _, expr := range []string{
+		`order.coupon`,
+		`none.customer`,
+		`order.customer.address.city.name`,
+		`buyer.secret`,
+		`guest.address.city`,
+		`items[2]`,
+		`items["sku"]`,
+	} {
+		if _, err := MustParse(expr).Eval(NewEvalParams(vars)); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestMemberVars(t *testing.T) {
+	expr := MustParse(`order.customer.address.city == city && items[0].sku != items[i].sku && order?.discount ?? 0`)
+	vars := expr.Vars()
+	sort.Strings(vars)
+	want := []string{"city", "i", "items", "items.0.sku", "order.customer.address.city", "order.discount"}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("got %v, want %v", vars, want)
+	}
+	printed, err := expr.Print(PrintConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printed != `order.customer.address.city == city && items[0].sku != items[i].sku && order?.discount ?? 0` {
+		t.Errorf("unexpected print %s", printed)
+	}
+	self := map[string]interface{}{"order": MustParse(`order.total * 2`)}
+	if _, err := MustParse(`order`).Eval(NewEvalParams(self)); err == nil {
+		t.Error("expected an error for a variable referring to itself")
+	}
+}
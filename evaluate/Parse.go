@@ -10,13 +10,14 @@ import (
 // ternary = indexer, "?", expr, ":", expr ;
 // binary  = indexer, operator, expr
 //         | indexer, ident, expr ;
-// indexer = value, { "[", expr, "]" } ;
+// indexer = value, { "[", expr, "]" | ( "." | "?." ), ident } ;
 // value   = literal | call | boolean | ident | "(", expr, ")" | array | prefix ;
 // call    = ident, "(", args, ")" ;
 // array   = "[", args, "]" ;
 // args    = [ expr, { ",", expr }, [ "," ] ] ;
 // prefix  = operator, expr ;
 // boolean = "true" | "false" ;
+// lambda  = ident, "=>", expr | "(", ident, { ",", ident }, ")", "=>", expr ;
 
 var replacer = strings.NewReplacer("{", "", "}", "")
 
@@ -71,6 +72,9 @@ func parseExprInner(s *TokenStream, lhs ExprNode, minPrecedence int) (ExprNode,
 		if operator == "?" {
 			return parseTernaryIf(s, lhs)
 		}
+		if operator == "=>" {
+			return parseLambda(s, lhs)
+		}
 		rhs, err := parseIndexer(s)
 		if err != nil {
 			return ExprNode{}, err
@@ -96,8 +100,17 @@ func parseIndexer(s *TokenStream) (ExprNode, error) {
 		return ExprNode{}, err
 	}
 	res := value
-	for s.Peek().Is(TokenKindBracket, '[') {
-		s.Next()
+	for s.Peek().Is(TokenKindBracket, '[') || s.Peek().Is(TokenKindOperator, ".") || s.Peek().Is(TokenKindOperator, "?.") {
+		if token := s.Next(); token.Kind == TokenKindOperator {
+			field := s.Next()
+			if field.Kind != TokenKindIdentifier {
+				return ExprNode{}, unexpectedToken(field, "field name")
+			}
+			key := NewExprNodeLiteral(field.Value, field.SourcePos, field.SourceLen)
+			pos, len := value.SourcePos, field.SourcePos+field.SourceLen-value.SourcePos
+			res = NewExprNodeOperator(token.Value.(string), []ExprNode{res, key}, pos, len, OperatorTypeMember)
+			continue
+		}
 		index, err := parseExpr(s, 0)
 		if err != nil {
 			return ExprNode{}, err
@@ -236,6 +249,30 @@ func parseTernaryIf(s *TokenStream, condition ExprNode) (ExprNode, error) {
 	return NewExprNodeOperator("?:", args, pos, len, OperatorTypeTernary), nil
 }
 
+func parseLambda(s *TokenStream, params ExprNode) (ExprNode, error) {
+	var args []ExprNode
+	var collect func(ExprNode) bool
+	collect = func(node ExprNode) bool {
+		switch {
+		case node.Type == NodeTypeVariable:
+			args = append(args, node)
+			return true
+		case node.IsOperator(",") && len(node.Args) == 2:
+			return collect(node.Args[0]) && collect(node.Args[1])
+		}
+		return false
+	}
+	if !collect(params) {
+		return ExprNode{}, fmt.Errorf("lambda parameters must be identifiers, pos: %d", params.SourcePos)
+	}
+	body, err := parseExpr(s, defaultPrecedence("=>", 2))
+	if err != nil {
+		return ExprNode{}, err
+	}
+	pos, len := params.SourcePos, body.SourcePos+body.SourceLen-params.SourcePos
+	return NewExprNodeOperator("=>", append(args, body), pos, len, OperatorTypeLambda), nil
+}
+
 func peekOperator(s *TokenStream) (string, int, bool) {
 	if token := s.Peek(); token.Kind == TokenKindOperator || token.Kind == TokenKindIdentifier {
 		name := token.Value.(string)
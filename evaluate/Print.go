@@ -170,6 +170,48 @@ func operator(name string, args []ExprNode, output *ExprNodePrinter, config *Pri
 		return fn(args, output)
 	}
 
+	// member access: x.name, x?.name, x[index]
+	if (mappedName == "." || mappedName == "?." || mappedName == "[]") && arity == 2 {
+		if config.precedenceForNode(args[0]) < config.precedence(name, arity) {
+			output.AppendString("(")
+			output.AppendNode(args[0])
+			output.AppendString(")")
+		} else {
+			output.AppendNode(args[0])
+		}
+		if mappedName == "[]" {
+			output.AppendString("[")
+			output.AppendNode(args[1])
+			output.AppendString("]")
+			return nil
+		}
+		output.AppendString(mappedName)
+		if key, ok := args[1].Value.(string); ok {
+			output.AppendString(key)
+		}
+		return nil
+	}
+
+	// lambda: x => body, (a, b) => body
+	if mappedName == "=>" && arity >= 2 {
+		params := args[:arity-1]
+		if len(params) > 1 {
+			output.AppendString("(")
+		}
+		for idx, param := range params {
+			if idx > 0 {
+				output.AppendString(", ")
+			}
+			output.AppendNode(param)
+		}
+		if len(params) > 1 {
+			output.AppendString(")")
+		}
+		output.AppendString(" => ")
+		output.AppendNode(args[arity-1])
+		return nil
+	}
+
 	// binary operator: x + y
 	infix := config.isInfix(name, arity)
 	if infix {
@@ -312,7 +354,7 @@ func defaultPrecedence(operator string, arity int) int {
 	switch operator {
 	case ",":
 		return 0
-	case "?:", "?", ":":
+	case "?:", "?", ":", "=>":
 		return 1
 	case "??":
 		return 2
@@ -330,6 +372,8 @@ func defaultPrecedence(operator string, arity int) int {
 		return 9
 	case "**":
 		return 11
+	case ".", "?.", "[]":
+		return 12
 	}
 	return 6
 }
@@ -0,0 +1,253 @@
+package evaluate
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/oarkflow/pkg/decimal"
+)
+
+// Program is a compiled expression. It evaluates like ExprNode.Eval with
+// NewEvalParams, including its errors, but runs on a stack of values and
+// reads each variable once from a slot instead of walking the tree.
+// A Program is safe for concurrent use.
+type Program struct {
+	code   []instruction
+	consts []interface{}
+	nodes  []nodeInfo
+	vars   []string
+	expr   ExprNode
+	stacks sync.Pool
+}
+
+// Expr returns the expression the program was compiled from.
+func (p *Program) Expr() ExprNode {
+	return p.expr
+}
+
+// Vars returns the variables the program reads, in slot order.
+func (p *Program) Vars() []string {
+	return p.vars
+}
+
+// undefined marks a slot whose variable is missing; reading it is an error.
+type undefined struct{}
+
+// Run evaluates the program against variables.
+func (p *Program) Run(variables map[string]interface{}) (interface{}, error) {
+	slots := make([]interface{}, len(p.vars))
+	for i, name := range p.vars {
+		value, ok := variables[name]
+		if !ok {
+			value = undefined{}
+		}
+		slots[i] = value
+	}
+	return p.run(slots, variables)
+}
+
+// RunValues evaluates the program with the values of Vars, in order, and
+// does not look variables up at all.
+func (p *Program) RunValues(values ...interface{}) (interface{}, error) {
+	if len(values) != len(p.vars) {
+		return nil, fmt.Errorf("wrong number of values: %d, expected: %d", len(values), len(p.vars))
+	}
+	return p.run(values, nil)
+}
+
+func (p *Program) run(slots []interface{}, variables map[string]interface{}) (result interface{}, err error) {
+	stack, _ := p.stacks.Get().(*[]interface{})
+	if stack == nil {
+		stack = new([]interface{})
+	}
+	s := (*stack)[:0]
+	defer func() {
+		for i := range s {
+			s[i] = nil
+		}
+		*stack = s[:0]
+		p.stacks.Put(stack)
+	}()
+
+	params := func() EvalParams {
+		if variables == nil {
+			variables = make(map[string]interface{}, len(p.vars))
+			for i, name := range p.vars {
+				if _, ok := slots[i].(undefined); !ok {
+					variables[name] = slots[i]
+				}
+			}
+		}
+		return NewEvalParams(variables)
+	}
+	fail := func(in instruction, err error) (interface{}, error) {
+		return nil, fmt.Errorf("%s%s", p.nodes[in.node].prefix, err.Error())
+	}
+
+	for pc := 0; pc < len(p.code); pc++ {
+		in := p.code[pc]
+		switch in.op {
+		case opConst:
+			s = append(s, p.consts[in.arg])
+		case opVar:
+			expr := p.nodes[in.node].expr
+			value := slots[in.arg]
+			switch v := value.(type) {
+			case undefined:
+				return fail(in, fmt.Errorf("variable undefined: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen))
+			case ExprNode:
+				// variables holding expressions are evaluated like Eval does
+				value, err = expr.Eval(params())
+				if err != nil {
+					return fail(in, err)
+				}
+			default:
+				value = v
+			}
+			s = append(s, value)
+		case opNorm:
+			s[len(s)-1] = normalize(s[len(s)-1])
+		case opCheckNumber, opCheckInteger:
+			value := normalize(s[len(s)-1])
+			if d, ok := value.(decimal.Decimal); ok {
+				value = d.InexactFloat64()
+			}
+			s[len(s)-1] = value
+			num, ok := value.(float64)
+			if !ok {
+				return fail(in, formatArgError(p.nodes[in.node].expr, in.arg, "is not numeric: %v", value))
+			}
+			if in.op == opCheckInteger && float64(int(num)) != num {
+				return fail(in, formatArgError(p.nodes[in.node].expr, in.arg, "is not integer: %v", num))
+			}
+		case opCheckBool:
+			value := normalize(s[len(s)-1])
+			s[len(s)-1] = value
+			if _, ok := value.(bool); !ok {
+				return fail(in, formatArgError(p.nodes[in.node].expr, in.arg, "is not boolean: %v", value))
+			}
+		case opCheckSlice:
+			value := normalize(s[len(s)-1])
+			s[len(s)-1] = value
+			if _, ok := value.([]interface{}); !ok {
+				return fail(in, formatArgError(p.nodes[in.node].expr, in.arg, "is not array: %v", value))
+			}
+		case opNumeric:
+			a, b := s[len(s)-2].(float64), s[len(s)-1].(float64)
+			s = s[:len(s)-1]
+			s[len(s)-1] = numericOps[in.arg](a, b)
+		case opInteger:
+			a, b := int(s[len(s)-2].(float64)), int(s[len(s)-1].(float64))
+			if (in.arg == 4 || in.arg == 5) && b < 0 {
+				return fail(in, EvalContext{expr: p.nodes[in.node].expr}.FormatError("shift count is negative: %d", b))
+			}
+			if in.arg == 0 && b == 0 {
+				return fail(in, EvalContext{expr: p.nodes[in.node].expr}.FormatError("division by zero"))
+			}
+			s = s[:len(s)-1]
+			s[len(s)-1] = integerOps[in.arg](a, b)
+		case opUnary:
+			s[len(s)-1] = unaryOps[in.arg](s[len(s)-1].(float64))
+		case opNeg:
+			s[len(s)-1] = -s[len(s)-1].(float64)
+		case opNot:
+			s[len(s)-1] = !s[len(s)-1].(bool)
+		case opInverse:
+			s[len(s)-1] = float64(^int(s[len(s)-1].(float64)))
+		case opEq:
+			a, b := s[len(s)-2], s[len(s)-1]
+			s = s[:len(s)-1]
+			s[len(s)-1] = valuesEqual(a, b)
+		case opNeq:
+			a, b := s[len(s)-2], s[len(s)-1]
+			s = s[:len(s)-1]
+			s[len(s)-1] = !valuesEqual(a, b)
+		case opJump:
+			pc = in.arg - 1
+		case opJumpIfFalse:
+			if !s[len(s)-1].(bool) {
+				pc = in.arg - 1
+			} else {
+				s = s[:len(s)-1]
+			}
+		case opJumpIfTrue:
+			if s[len(s)-1].(bool) {
+				pc = in.arg - 1
+			} else {
+				s = s[:len(s)-1]
+			}
+		case opJumpIfFalsePop:
+			cond := s[len(s)-1].(bool)
+			s = s[:len(s)-1]
+			if !cond {
+				pc = in.arg - 1
+			}
+		case opJumpIfNotNil:
+			if s[len(s)-1] != nil {
+				pc = in.arg - 1
+			} else {
+				s = s[:len(s)-1]
+			}
+		case opArray:
+			items := make([]interface{}, in.arg)
+			copy(items, s[len(s)-in.arg:])
+			s = append(s[:len(s)-in.arg], items)
+		case opIn:
+			item, slice := s[len(s)-2], s[len(s)-1].([]interface{})
+			s = s[:len(s)-1]
+			found := false
+			for _, v := range slice {
+				if valuesEqual(item, v) {
+					found = true
+					break
+				}
+			}
+			s[len(s)-1] = found
+		case opString:
+			s[len(s)-1] = fmt.Sprintf("%v", s[len(s)-1])
+		case opCall:
+			expr := p.nodes[in.node].expr
+			ctx := params()
+			operator, ok := ctx.Operators[expr.Name]
+			if !ok {
+				return fail(in, fmt.Errorf("operator undefined: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen))
+			}
+			value, err := operator(EvalContext{params: ctx, expr: expr})
+			if err != nil {
+				return fail(in, err)
+			}
+			s = append(s, value)
+		}
+	}
+	return s[0], nil
+}
+
+// normalize converts numbers like EvalContext.Arg does.
+func normalize(val interface{}) interface{} {
+	switch v := val.(type) {
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
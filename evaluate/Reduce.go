@@ -22,10 +22,8 @@ func (expr ExprNode) Reduce(params EvalParams, optimizers map[string]Optimizer)
 			return NewExprNodeLiteral(value, expr.SourcePos, expr.SourceLen), nil
 		}
 
-		for _, v := range node.Vars() {
-			if v == expr.Name {
-				return ExprNode{}, fmt.Errorf("variable can not refer to itself: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen)
-			}
+		if refersTo(node.Vars(), expr.Name) {
+			return ExprNode{}, fmt.Errorf("variable can not refer to itself: %v [pos=%d; len=%d]", expr.Name, expr.SourcePos, expr.SourceLen)
 		}
 		node.SourcePos = expr.SourcePos
 		node.SourceLen = expr.SourceLen
@@ -37,6 +35,9 @@ func (expr ExprNode) Reduce(params EvalParams, optimizers map[string]Optimizer)
 		}
 		return reduced, nil
 	case NodeTypeOperator:
+		if expr.OperatorType == OperatorTypeLambda {
+			return expr.reduceLambda(params, optimizers)
+		}
 		// reduce arguments
 		reducedArgs := make([]ExprNode, len(expr.Args))
 		allArgsKnown := true
@@ -71,3 +72,26 @@ func (expr ExprNode) Reduce(params EvalParams, optimizers map[string]Optimizer)
 	}
 	return expr, fmt.Errorf("bad node type: %v", expr)
 }
+
+// reduceLambda reduces the body of a lambda; its parameters shadow variables.
+func (expr ExprNode) reduceLambda(params EvalParams, optimizers map[string]Optimizer) (ExprNode, error) {
+	last := len(expr.Args) - 1
+	variables := make(map[string]interface{}, len(params.Variables))
+	for name, value := range params.Variables {
+		variables[name] = value
+	}
+	for _, param := range expr.Args[:last] {
+		delete(variables, param.Name)
+	}
+	inner := params
+	inner.Variables = variables
+	body, err := expr.Args[last].Reduce(inner, optimizers)
+	if err != nil {
+		return expr, err
+	}
+	args := make([]ExprNode, len(expr.Args))
+	copy(args, expr.Args)
+	args[last] = body
+	expr.Args = args
+	return expr, nil
+}
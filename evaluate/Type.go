@@ -0,0 +1,170 @@
+package evaluate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/oarkflow/pkg/jsonschema"
+)
+
+// Kind is the type of a value as seen by Check. Kinds are bit flags, so a
+// Kind may be a union like KindString | KindNull.
+type Kind uint
+
+const (
+	KindNull Kind = 1 << iota
+	KindNumber
+	KindString
+	KindBool
+	KindDate
+	KindList
+	KindObject
+
+	// KindAny is a value of unknown type; it is accepted everywhere.
+	KindAny = KindNull | KindNumber | KindString | KindBool | KindDate | KindList | KindObject
+)
+
+var kindNames = []string{"null", "number", "string", "bool", "date", "list", "object"}
+
+func (k Kind) String() string {
+	if k == KindAny {
+		return "any"
+	}
+	var names []string
+	for i, name := range kindNames {
+		if k&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "never"
+	}
+	return strings.Join(names, "|")
+}
+
+// Type describes a value. Items is the type of the items of a list and
+// Fields the types of the fields of an object; objects without Fields may
+// have any field.
+type Type struct {
+	Kind   Kind
+	Items  *Type
+	Fields map[string]*Type
+}
+
+var (
+	TypeAny    = &Type{Kind: KindAny}
+	TypeNull   = &Type{Kind: KindNull}
+	TypeNumber = &Type{Kind: KindNumber}
+	TypeString = &Type{Kind: KindString}
+	TypeBool   = &Type{Kind: KindBool}
+	TypeDate   = &Type{Kind: KindDate}
+	TypeList   = &Type{Kind: KindList}
+	TypeObject = &Type{Kind: KindObject}
+)
+
+// ListOf returns the type of a list of items.
+func ListOf(items *Type) *Type {
+	return &Type{Kind: KindList, Items: items}
+}
+
+// ObjectOf returns the type of an object with the given fields.
+func ObjectOf(fields map[string]*Type) *Type {
+	return &Type{Kind: KindObject, Fields: fields}
+}
+
+func (t *Type) String() string {
+	switch {
+	case t.Kind == KindList && t.Items != nil:
+		return "list<" + t.Items.String() + ">"
+	case t.Kind == KindObject && t.Fields != nil:
+		names := make([]string, 0, len(t.Fields))
+		for name := range t.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name + ": " + t.Fields[name].String()
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return t.Kind.String()
+}
+
+// union returns a type holding values of a or b.
+func union(a, b *Type) *Type {
+	if a.Kind == KindAny || b.Kind == KindAny {
+		return TypeAny
+	}
+	res := &Type{Kind: a.Kind | b.Kind, Items: a.Items, Fields: a.Fields}
+	if res.Items == nil {
+		res.Items = b.Items
+	}
+	if res.Fields == nil {
+		res.Fields = b.Fields
+	}
+	return res
+}
+
+// Schema declares the types of the variables of an expression.
+type Schema map[string]*Type
+
+// SchemaFromJSONSchema declares a variable for each property of an object
+// schema.
+func SchemaFromJSONSchema(s *jsonschema.Schema) Schema {
+	schema := Schema{}
+	if t := TypeFromJSONSchema(s); t.Fields != nil {
+		for name, field := range t.Fields {
+			schema[name] = field
+		}
+	}
+	return schema
+}
+
+// TypeFromJSONSchema derives a type from the type, format, properties and
+// items keywords of a schema. Strings with a date or date-time format are
+// dates.
+func TypeFromJSONSchema(s *jsonschema.Schema) *Type {
+	if s == nil {
+		return TypeAny
+	}
+	res := &Type{}
+	if t, ok := s.JSONProp("type").(*jsonschema.Type); ok {
+		for _, name := range strings.Split(t.String(), ",") {
+			switch name {
+			case "null":
+				res.Kind |= KindNull
+			case "number", "integer":
+				res.Kind |= KindNumber
+			case "string":
+				res.Kind |= KindString
+			case "boolean":
+				res.Kind |= KindBool
+			case "array":
+				res.Kind |= KindList
+			case "object":
+				res.Kind |= KindObject
+			}
+		}
+	}
+	if format, ok := s.JSONProp("format").(*jsonschema.Format); ok && res.Kind&KindString != 0 {
+		if *format == "date" || *format == "date-time" {
+			res.Kind = res.Kind&^KindString | KindDate
+		}
+	}
+	if properties, ok := s.JSONProp("properties").(*jsonschema.Properties); ok {
+		res.Kind |= KindObject
+		res.Fields = make(map[string]*Type, len(*properties))
+		for name, property := range *properties {
+			res.Fields[name] = TypeFromJSONSchema(property)
+		}
+	}
+	if items, ok := s.JSONProp("items").(*jsonschema.Items); ok && len(items.Schemas) == 1 {
+		res.Kind |= KindList
+		res.Items = TypeFromJSONSchema(items.Schemas[0])
+	}
+	if res.Kind == 0 {
+		return TypeAny
+	}
+	return res
+}
@@ -131,6 +131,11 @@ func init() {
 				)
 			}
 
+			if a.runtime.limits != nil {
+				a.runtime.limits.enter()
+				defer a.runtime.limits.leave()
+			}
+
 			a.runtime.newScope()
 			defer a.runtime.releaseScope()
 
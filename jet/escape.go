@@ -0,0 +1,649 @@
+package jet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/oarkflow/pkg/fastprinter"
+)
+
+// WithContextualEscaping returns an option function that makes templates escape every printed value
+// for the place it lands in, in the spirit of html/template, instead of always using the Set's SafeWriter:
+//
+//   - HTML text and attribute values are HTML escaped; unquoted attribute values also escape spaces
+//   - values in <script> elements and on* attributes are JavaScript literals (JSON) or escaped
+//     string contents, when inside a string, and escaped to match literally in regular expressions
+//   - values in <style> elements and style attributes are filtered CSS values or escaped string contents
+//   - URLs in href, src and other URL attributes are normalized, and rejected as "#ZjetZ" when they start
+//     with a scheme other than http, https or mailto; query parts are percent encoded
+//
+// The parse state is tracked through everything the template writes, so blocks, includes and the output
+// of raw or safeHtml (which keep bypassing the escaper) move it along.
+func WithContextualEscaping() Option {
+	return func(s *Set) {
+		s.contextual = true
+	}
+}
+
+type escapeState uint8
+
+const (
+	stateText        escapeState = iota // HTML text
+	stateTagOpen                        // after '<', reading a tag name
+	stateTag                            // inside a tag, between attributes
+	stateAttrName                       // reading an attribute name
+	stateAfterName                      // after an attribute name, before '='
+	stateBeforeValue                    // after '=', before the attribute value
+	stateAttrValue                      // inside an attribute value
+	stateComment                        // inside <!-- -->
+	stateRawText                        // content of script, style, textarea and title elements
+)
+
+type attrKind uint8
+
+const (
+	attrPlain attrKind = iota
+	attrURL
+	attrJS
+	attrCSS
+)
+
+type subState uint8
+
+const (
+	subCode         subState = iota // JavaScript or CSS code
+	subDoubleQuote                  // "string"
+	subSingleQuote                  // 'string'
+	subBackQuote                    // `template literal`
+	subLineComment                  // // comment
+	subBlockComment                 // /* comment */
+	subRegexp                       // /regular expression/
+	subRegexpClass                  // [character class] of a regular expression
+	subURLStart                     // start of a URL, where the scheme is
+	subURLPath                      // rest of a URL before the query
+	subURLQuery                     // query or fragment of a URL
+)
+
+var urlAttributes = map[string]bool{
+	"action": true, "archive": true, "background": true, "cite": true, "classid": true, "codebase": true,
+	"data": true, "formaction": true, "href": true, "icon": true, "longdesc": true, "manifest": true,
+	"poster": true, "profile": true, "src": true, "usemap": true, "xmlns": true,
+}
+
+// escapeContext is the parse state of the output written so far.
+type escapeContext struct {
+	state   escapeState
+	name    []byte // tag or attribute name being read
+	rawTag  string // element whose raw text is being written
+	endTag  bool
+	attr    attrKind
+	delim   byte // quote around the attribute value, or ' ' when unquoted
+	sub     subState
+	prev    byte
+	escaped bool // previous byte was a backslash in a string
+	// regexp is set when a '/' in JavaScript code starts a regular expression rather than a division,
+	// regexpStart right after that '/', where a '/' or '*' starts a comment instead
+	regexp      bool
+	regexpStart bool
+	word        []byte // identifier before the current byte in JavaScript code
+	dashes      int    // consecutive dashes in a comment
+	match       int    // bytes of the raw text end tag matched
+}
+
+// contextWriter tracks the parse state of everything written to w.
+type contextWriter struct {
+	w io.Writer
+	*escapeContext
+}
+
+func newContextWriter(w io.Writer) *contextWriter {
+	return &contextWriter{w: w, escapeContext: &escapeContext{}}
+}
+
+func (w *contextWriter) Write(b []byte) (int, error) {
+	for _, c := range b {
+		w.next(c)
+	}
+	return w.w.Write(b)
+}
+
+// printValue writes v escaped for the current context.
+func (w *contextWriter) printValue(v reflect.Value) error {
+	var buf bytes.Buffer
+	if _, err := fastprinter.PrintValue(&buf, v); err != nil {
+		return err
+	}
+	return w.writeEscaped(buf.String(), func() []byte {
+		if v.CanInterface() {
+			if b, err := json.Marshal(v.Interface()); err == nil {
+				return b
+			}
+		}
+		return []byte("null")
+	})
+}
+
+// writeEscaped writes text escaped for the current context; literal returns the JavaScript literal to
+// write instead in JavaScript code.
+func (w *contextWriter) writeEscaped(text string, literal func() []byte) error {
+	var out string
+	switch w.state {
+	case stateText, stateComment:
+		out = template.HTMLEscapeString(text)
+	case stateRawText:
+		switch w.rawTag {
+		case "script":
+			out = w.escapeJS(text, literal)
+		case "style":
+			out = w.escapeCSS(text)
+		default:
+			out = template.HTMLEscapeString(text)
+		}
+	case stateBeforeValue, stateAttrValue:
+		if w.state == stateBeforeValue {
+			w.state, w.delim = stateAttrValue, ' '
+			w.beginValue()
+		}
+		switch w.attr {
+		case attrJS:
+			out = w.escapeJS(text, literal)
+		case attrCSS:
+			out = w.escapeCSS(text)
+		case attrURL:
+			out = w.escapeURL(text)
+		default:
+			out = text
+		}
+		if w.delim == ' ' {
+			out = htmlNospaceEscape(out)
+		} else {
+			out = template.HTMLEscapeString(out)
+		}
+	default:
+		// values can't form tags or attribute names, but must not break out of them either
+		out = htmlNospaceEscape(text)
+	}
+	_, err := io.WriteString(w.w, out)
+	return err
+}
+
+func (c *escapeContext) escapeJS(text string, literal func() []byte) string {
+	switch c.sub {
+	case subCode:
+		// '/' only appears in the strings of a JSON literal, where "\/" means the same
+		c.regexp = false
+		return " " + strings.ReplaceAll(string(literal()), "/", `\/`) + " "
+	case subRegexp, subRegexpClass:
+		start := c.regexpStart
+		c.regexpStart = false
+		if start && text == "" {
+			// "//" would start a comment
+			return "(?:)"
+		}
+		return jsRegexpEscape(text)
+	}
+	return jsStringEscape(text)
+}
+
+func (c *escapeContext) escapeCSS(text string) string {
+	if c.sub == subCode {
+		return cssValueFilter(text)
+	}
+	return cssEscape(text)
+}
+
+func (c *escapeContext) escapeURL(text string) string {
+	var out string
+	switch c.sub {
+	case subURLStart:
+		out = urlNormalize(urlFilter(text))
+	case subURLPath:
+		out = urlNormalize(text)
+	default:
+		out = urlQueryEscape(text)
+	}
+	for i := 0; i < len(out); i++ {
+		c.url(out[i])
+	}
+	return out
+}
+
+func (c *escapeContext) save() escapeContext {
+	saved := *c
+	saved.name = append([]byte(nil), c.name...)
+	saved.word = append([]byte(nil), c.word...)
+	return saved
+}
+
+// next moves the parse state past b.
+func (c *escapeContext) next(b byte) {
+	switch c.state {
+	case stateText:
+		if b == '<' {
+			c.state, c.name = stateTagOpen, c.name[:0]
+		}
+	case stateTagOpen:
+		c.tagName(b)
+	case stateComment:
+		if b == '>' && c.dashes >= 2 {
+			c.state = stateText
+		}
+		if b == '-' {
+			c.dashes++
+		} else {
+			c.dashes = 0
+		}
+	case stateTag:
+		switch {
+		case b == '>':
+			c.finishTag()
+		case !isHTMLSpace(b) && b != '/':
+			c.state, c.name = stateAttrName, append(c.name[:0], lower(b))
+		}
+	case stateAttrName, stateAfterName:
+		switch {
+		case b == '=':
+			c.state, c.attr = stateBeforeValue, attributeKind(string(c.name))
+		case b == '>':
+			c.finishTag()
+		case b == '/':
+			c.state = stateTag
+		case isHTMLSpace(b):
+			c.state = stateAfterName
+		case c.state == stateAfterName:
+			c.state, c.name = stateAttrName, append(c.name[:0], lower(b))
+		default:
+			c.name = append(c.name, lower(b))
+		}
+	case stateBeforeValue:
+		switch {
+		case b == '"' || b == '\'':
+			c.state, c.delim = stateAttrValue, b
+			c.beginValue()
+		case b == '>':
+			c.finishTag()
+		case !isHTMLSpace(b):
+			c.state, c.delim = stateAttrValue, ' '
+			c.beginValue()
+			c.value(b)
+		}
+	case stateAttrValue:
+		switch {
+		case b == c.delim || c.delim == ' ' && isHTMLSpace(b):
+			c.state = stateTag
+		case c.delim == ' ' && b == '>':
+			c.finishTag()
+		default:
+			c.value(b)
+		}
+	case stateRawText:
+		switch c.rawTag {
+		case "script":
+			c.js(b)
+		case "style":
+			c.css(b)
+		}
+		end := "</" + c.rawTag
+		switch {
+		case lower(b) == end[c.match]:
+			c.match++
+			if c.match == len(end) {
+				c.state, c.endTag, c.rawTag, c.match = stateTag, true, "", 0
+			}
+		case b == '<':
+			c.match = 1
+		default:
+			c.match = 0
+		}
+	}
+}
+
+func (c *escapeContext) tagName(b byte) {
+	if len(c.name) == 0 {
+		if isASCIILetter(b) || b == '/' || b == '!' {
+			c.name = append(c.name, lower(b))
+		} else {
+			c.state = stateText
+			c.next(b)
+		}
+		return
+	}
+	if c.name[0] == '!' {
+		// comments and declarations like <!DOCTYPE html>
+		c.name = append(c.name, b)
+		switch {
+		case string(c.name) == "!--":
+			c.state, c.dashes = stateComment, 0
+		case b == '>':
+			c.endTag = true
+			c.finishTag()
+		case !strings.HasPrefix("!--", string(c.name)):
+			c.state, c.endTag = stateTag, true
+		}
+		return
+	}
+	if !isHTMLSpace(b) && b != '>' && (b != '/' || len(c.name) == 1 && c.name[0] == '/') {
+		c.name = append(c.name, lower(b))
+		return
+	}
+	name := string(c.name)
+	c.endTag = strings.HasPrefix(name, "/")
+	c.rawTag = ""
+	switch name {
+	case "script", "style", "textarea", "title":
+		c.rawTag = name
+	}
+	if b == '>' {
+		c.finishTag()
+	} else {
+		c.state = stateTag
+	}
+}
+
+func (c *escapeContext) finishTag() {
+	c.state, c.attr = stateText, attrPlain
+	if !c.endTag && c.rawTag != "" {
+		c.state, c.sub, c.prev, c.match = stateRawText, subCode, 0, 0
+		c.regexp, c.regexpStart = true, false
+	}
+	c.endTag = false
+}
+
+func (c *escapeContext) beginValue() {
+	c.sub, c.prev, c.escaped = subCode, 0, false
+	c.regexp, c.regexpStart = true, false
+	if c.attr == attrURL {
+		c.sub = subURLStart
+	}
+}
+
+// value moves the state of the attribute value past b.
+func (c *escapeContext) value(b byte) {
+	switch c.attr {
+	case attrJS:
+		c.js(b)
+	case attrCSS:
+		c.css(b)
+	case attrURL:
+		c.url(b)
+	}
+}
+
+func (c *escapeContext) js(b byte) {
+	prev := c.prev
+	c.prev = b
+	switch c.sub {
+	case subCode:
+		switch {
+		case b == '"':
+			c.sub = subDoubleQuote
+		case b == '\'':
+			c.sub = subSingleQuote
+		case b == '`':
+			c.sub = subBackQuote
+		case b == '/' && prev == '/':
+			c.sub = subLineComment
+		case b == '*' && prev == '/':
+			c.sub, c.prev = subBlockComment, 0
+		case b == '/' && c.regexp:
+			c.sub, c.regexpStart = subRegexp, true
+		default:
+			c.jsToken(b, prev)
+		}
+	case subDoubleQuote, subSingleQuote, subBackQuote:
+		c.quoted(b)
+		c.regexp = false
+	case subRegexp, subRegexpClass:
+		start := c.regexpStart
+		c.regexpStart = false
+		switch {
+		case start && b == '/':
+			c.sub = subLineComment
+		case start && b == '*':
+			c.sub, c.prev = subBlockComment, 0
+		case c.escaped:
+			c.escaped = false
+		case b == '\\':
+			c.escaped = true
+		case b == '[':
+			c.sub = subRegexpClass
+		case b == ']' && c.sub == subRegexpClass:
+			c.sub = subRegexp
+		case b == '/' && c.sub == subRegexp, b == '\n':
+			c.sub, c.prev, c.regexp = subCode, 0, false
+		}
+	case subLineComment:
+		if b == '\n' {
+			c.sub = subCode
+		}
+	case subBlockComment:
+		if b == '/' && prev == '*' {
+			c.sub, c.prev = subCode, 0
+		}
+	}
+}
+
+// regexpPrecederKeywords are the keywords after which a '/' starts a regular expression.
+var regexpPrecederKeywords = map[string]bool{
+	"break": true, "case": true, "continue": true, "delete": true, "do": true, "else": true, "finally": true,
+	"in": true, "instanceof": true, "return": true, "throw": true, "try": true, "typeof": true, "void": true,
+}
+
+// jsToken updates whether a '/' after b starts a regular expression, the way html/template does:
+// it does after punctuation and keywords, but not after identifiers, literals, ')' and ']'.
+func (c *escapeContext) jsToken(b, prev byte) {
+	switch {
+	case isHTMLSpace(b):
+	case isJSIdentPart(b):
+		if !isJSIdentPart(prev) {
+			c.word = c.word[:0]
+		}
+		c.word = append(c.word, b)
+		c.regexp = regexpPrecederKeywords[string(c.word)]
+	case b == '+' || b == '-':
+		// ++ and -- end an expression, + and - don't
+		c.regexp = prev != b || !c.regexp
+	case b == '.':
+		c.regexp = prev < '0' || prev > '9'
+	case b == ')' || b == ']':
+		c.regexp = false
+	default:
+		c.regexp = true
+	}
+}
+
+func (c *escapeContext) css(b byte) {
+	prev := c.prev
+	c.prev = b
+	switch c.sub {
+	case subCode:
+		switch {
+		case b == '"':
+			c.sub = subDoubleQuote
+		case b == '\'':
+			c.sub = subSingleQuote
+		case b == '*' && prev == '/':
+			c.sub, c.prev = subBlockComment, 0
+		}
+	case subDoubleQuote, subSingleQuote:
+		c.quoted(b)
+	case subBlockComment:
+		if b == '/' && prev == '*' {
+			c.sub, c.prev = subCode, 0
+		}
+	}
+}
+
+func (c *escapeContext) quoted(b byte) {
+	switch {
+	case c.escaped:
+		c.escaped = false
+	case b == '\\':
+		c.escaped = true
+	case b == '"' && c.sub == subDoubleQuote, b == '\'' && c.sub == subSingleQuote, b == '`' && c.sub == subBackQuote:
+		c.sub = subCode
+	}
+}
+
+func (c *escapeContext) url(b byte) {
+	switch {
+	case b == '?' || b == '#':
+		c.sub = subURLQuery
+	case c.sub == subURLStart && !isHTMLSpace(b):
+		c.sub = subURLPath
+	}
+}
+
+func attributeKind(name string) attrKind {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(name, "on"):
+		return attrJS
+	case name == "style":
+		return attrCSS
+	case urlAttributes[name]:
+		return attrURL
+	}
+	return attrPlain
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func isASCIILetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func isASCIIAlphaNumeric(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
+
+func isJSIdentPart(b byte) bool {
+	return isASCIIAlphaNumeric(rune(b)) || b == '_' || b == '$' || b >= utf8.RuneSelf
+}
+
+func lower(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// htmlNospaceEscape escapes s for an unquoted attribute value.
+func htmlNospaceEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case 0, ' ', '\t', '\n', '\r', '\f', '"', '\'', '&', '<', '>', '=', '`':
+			fmt.Fprintf(&sb, "&#%d;", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// jsStringEscape escapes s for the inside of a JavaScript string, template literal or comment.
+func jsStringEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r < ' ', r == '\u2028', r == '\u2029', strings.ContainsRune("\\'\"`$<>&=/*+", r):
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		case r == utf8.RuneError:
+			sb.WriteString(`\uFFFD`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// jsRegexpEscape escapes s for the inside of a JavaScript regular expression literal, so it matches s literally.
+func jsRegexpEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.+*?()[]{}|^$/-`, r) {
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(jsStringEscape(string(r)))
+		}
+	}
+	return sb.String()
+}
+
+// cssEscape escapes s for the inside of a CSS string or comment.
+func cssEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if isASCIIAlphaNumeric(r) || r >= utf8.RuneSelf && r != '\u2028' && r != '\u2029' {
+			sb.WriteRune(r)
+		} else {
+			fmt.Fprintf(&sb, `\%x `, r)
+		}
+	}
+	return sb.String()
+}
+
+// cssValueFilter returns s if it is a plain CSS value like a color, length or keyword, and ZjetZ otherwise.
+func cssValueFilter(s string) string {
+	for _, r := range s {
+		if !isASCIIAlphaNumeric(r) && !strings.ContainsRune(" #%.,-_+!", r) {
+			return "ZjetZ"
+		}
+	}
+	if lowered := strings.ToLower(s); strings.Contains(lowered, "expression") || strings.Contains(lowered, "mozbinding") {
+		return "ZjetZ"
+	}
+	return s
+}
+
+// urlFilter returns s unless it starts with a scheme other than http, https and mailto.
+func urlFilter(s string) string {
+	if i := strings.IndexAny(s, ":/?#"); i >= 0 && s[i] == ':' {
+		switch strings.ToLower(s[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return "#ZjetZ"
+		}
+	}
+	return s
+}
+
+// urlNormalize percent encodes the bytes of s that may not appear in a URL.
+func urlNormalize(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if isASCIIAlphaNumeric(rune(b)) || strings.IndexByte("-._~!#$&'()*+,/:;=?@[]%", b) >= 0 {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// urlQueryEscape percent encodes all the bytes of s but the unreserved ones.
+func urlQueryEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if isASCIIAlphaNumeric(rune(b)) || strings.IndexByte("-._~", b) >= 0 {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package jet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestContextualEscaping(t *testing.T) {
+	set := NewSet(NewInMemLoader(), WithDelims("{{", "}}"), WithContextualEscaping())
+	vars := VarMap{}
+	vars.Set("s", `a"b'<c>&d`).
+		Set("n", 42).
+		Set("js", "javascript:alert(1)").
+		Set("q", "a b&c=d").
+		Set("color", "red").
+		Set("bad", "red;background:url(x)").
+		Set("tag", "<b>bold</b>").
+		Set("re", "/;alert(1);//").
+		Set("empty", "").
+		SetFunc("fail", func(a Arguments) reflect.Value { panic("fail") })
+
+	for _, test := range []struct{ template, want string }{
+		{`<p>{{ s }}</p>`, `<p>a&#34;b&#39;&lt;c&gt;&amp;d</p>`},
+		{`<p title="{{ s }}">`, `<p title="a&#34;b&#39;&lt;c&gt;&amp;d">`},
+		{`<p title={{ q }}>`, `<p title=a&#32;b&#38;c&#61;d>`},
+		{`<a href="{{ js }}">`, `<a href="#ZjetZ">`},
+		{`<a href="/search?q={{ q }}&amp;n={{ n }}">`, `<a href="/search?q=a%20b%26c%3Dd&amp;n=42">`},
+		{`<a href="{{ q }}">`, `<a href="a%20b&amp;c=d">`},
+		{`<script>var n = {{ n }}, s = {{ s }};</script>`, `<script>var n =  42 , s =  "a\"b'\u003cc\u003e\u0026d" ;</script>`},
+		{`<script>var s = "{{ s }}";</script>`, `<script>var s = "a\u0022b\u0027\u003Cc\u003E\u0026d";</script>`},
+		{`<script>// "{{ n }}` + "\n" + `var s = '{{ q }}';</script><p>{{ s }}</p>`, `<script>// "42` + "\n" + `var s = 'a b\u0026c\u003Dd';</script><p>a&#34;b&#39;&lt;c&gt;&amp;d</p>`},
+		{`<script>var re = /{{ re }}/;</script>`, `<script>var re = /\/;alert\(1\);\/\//;</script>`},
+		{`<script>var a = 1, b = a / {{ re }};</script>`, `<script>var a = 1, b = a /  "\/;alert(1);\/\/" ;</script>`},
+		{`<script>f(/[{{ re }}]/g, n / 2); if (x) return /{{ empty }}/.test({{ re }})</script>`, `<script>f(/[\/;alert\(1\);\/\/]/g, n / 2); if (x) return /(?:)/.test( "\/;alert(1);\/\/" )</script>`},
+		{`<script>var s = '/'; i++ / {{ n }}; x = "a" /* {{ re }} */</script>`, `<script>var s = '/'; i++ /  42 ; x = "a" /* \u002F;alert(1);\u002F\u002F */</script>`},
+		{`<button onclick="f({{ s }})">`, `<button onclick="f( &#34;a\&#34;b&#39;\u003cc\u003e\u0026d&#34; )">`},
+		{`<style>p { color: {{ color }}; background: {{ bad }} }</style>`, `<style>p { color: red; background: ZjetZ }</style>`},
+		{`<p style="font-family: '{{ q }}'">`, `<p style="font-family: 'a\20 b\26 c\3d d'">`},
+		{`<textarea>{{ tag }}</textarea>`, `<textarea>&lt;b&gt;bold&lt;/b&gt;</textarea>`},
+		{`<!-- <script> -->{{ s }}`, `<!-- <script> -->a&#34;b&#39;&lt;c&gt;&amp;d`},
+		{`{{ raw("<script>") }}{{ n }}{{ raw("</script>") }}{{ s }}`, `<script> 42 </script>a&#34;b&#39;&lt;c&gt;&amp;d`},
+		{`<p>{{ safeHtml(tag) }}{{ raw(tag) }}</p>`, `<p>&lt;b&gt;bold&lt;/b&gt;<b>bold</b></p>`},
+		{`<script>{{ try }}var a = "{{ fail() }}{{ end }}var b = {{ n }}</script>`, `<script>var b =  42 </script>`},
+	} {
+		tmpl, err := set.Parse("/test.jet", test.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s:\n got %s\nwant %s", test.template, got, test.want)
+		}
+	}
+}
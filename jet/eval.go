@@ -16,6 +16,7 @@ package jet
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
@@ -63,7 +64,12 @@ type escapeeWriter struct {
 }
 
 func (w *escapeeWriter) Write(b []byte) (int, error) {
-	if w.set == nil || w.set.escapee == nil {
+	if cw, ok := w.Writer.(*contextWriter); ok {
+		cw.writeEscaped(string(b), func() []byte {
+			literal, _ := json.Marshal(string(b))
+			return literal
+		})
+	} else if w.set == nil || w.set.escapee == nil {
 		w.Writer.Write(b)
 	} else {
 		w.set.escapee(w.Writer, b)
@@ -76,6 +82,7 @@ type Runtime struct {
 	*escapeeWriter
 	*scope
 	content func(*Runtime, Expression) e.Error
+	limits  *renderLimits // budget of the render, or nil when unlimited
 
 	context reflect.Value
 }
@@ -204,6 +211,12 @@ func (rt *Runtime) resolve(name string) (reflect.Value, e.Error) {
 		sc = sc.parent
 	}
 
+	if sb := rt.set.sandbox; sb != nil && !sb.allowsGlobal(name) {
+		return reflect.Value{}, e.New().
+			WithReason("not_allowed.identifier").
+			WithMessage(fmt.Sprintf("identifier %q is not available in current or parent scope, and not allowed in the sandbox", name))
+	}
+
 	// try globals
 	rt.set.gmx.RLock()
 	v, ok := rt.set.globals[name]
@@ -276,7 +289,7 @@ func (rt *Runtime) executeSet(left Expression, right reflect.Value) e.Error {
 	}
 	lef := len(fields) - 1
 	for i := 0; i < lef; i++ {
-		value, err = resolveIndex(value, reflect.Value{}, fields[i].name, fields[i].lax)
+		value, err = rt.resolveIndex(value, reflect.Value{}, fields[i].name, fields[i].lax)
 		if err != nil {
 			return left.error(err.Reason(), err.Message())
 		}
@@ -376,6 +389,10 @@ func (rt *Runtime) executeLetList(set *SetNode) e.Error {
 }
 
 func (rt *Runtime) executeYieldBlock(block *BlockNode, blockParam, yieldParam *BlockParameterList, expression Expression, content *ListNode) e.Error {
+	if rt.limits != nil {
+		rt.limits.enter()
+		defer rt.limits.leave()
+	}
 	needNewScope := len(blockParam.List) > 0 || len(yieldParam.List) > 0
 	if needNewScope {
 		rt.newScope()
@@ -473,6 +490,9 @@ func (rt *Runtime) executeYieldBlock(block *BlockNode, blockParam, yieldParam *B
 
 func (rt *Runtime) executeList(list *ListNode) (returnValue reflect.Value, err e.Error) {
 	inNewScope := false // to use just one scope for multiple actions with variable declarations
+	if rt.limits != nil {
+		rt.limits.check()
+	}
 
 	for i := 0; i < len(list.Nodes); i++ {
 		node := list.Nodes[i]
@@ -506,7 +526,7 @@ func (rt *Runtime) executeList(list *ListNode) (returnValue reflect.Value, err e
 					if v.Type().Implements(rendererType) {
 						v.Interface().(Renderer).Render(rt)
 					} else {
-						if _, err := fastprinter.PrintValue(rt.escapeeWriter, v); err != nil {
+						if err := rt.printValue(v); err != nil {
 							return reflect.Value{}, node.error("", err.Error())
 						}
 					}
@@ -582,6 +602,9 @@ func (rt *Runtime) executeList(list *ListNode) (returnValue reflect.Value, err e
 			indexValue, rangeValue, end := ranger.Range()
 			if !end {
 				for !end && !returnValue.IsValid() {
+					if rt.limits != nil {
+						rt.limits.iterate()
+					}
 					if isSet {
 						if isLet {
 							if keyVarSlot >= 0 {
@@ -648,18 +671,31 @@ func (rt *Runtime) executeList(list *ListNode) (returnValue reflect.Value, err e
 	return returnValue, err
 }
 
+// printValue prints v to the render output, escaped for the context it lands in when the Set escapes
+// contextually, or else with the Set's SafeWriter.
+func (rt *Runtime) printValue(v reflect.Value) error {
+	if cw, ok := rt.Writer.(*contextWriter); ok {
+		return cw.printValue(v)
+	}
+	_, err := fastprinter.PrintValue(rt.escapeeWriter, v)
+	return err
+}
+
 func (rt *Runtime) executeTry(try *TryNode) (returnValue reflect.Value, err e.Error) {
-	writer := rt.Writer
 	buf := new(bytes.Buffer)
+	flush, discard := rt.bufferOutput(buf)
 
 	defer func() {
 		r := recover()
 
 		// copy buffered render output to writer only if no panic occured
 		if r == nil {
-			io.Copy(writer, buf)
+			flush()
 		} else {
-			// rt.Writer is already set to its original value since the later defer ran first
+			discard()
+			if _, ok := r.(*limitError); ok {
+				panic(r)
+			}
 			if try.Catch != nil {
 				if try.Catch.Err != nil {
 					rt.newScope()
@@ -675,13 +711,14 @@ func (rt *Runtime) executeTry(try *TryNode) (returnValue reflect.Value, err e.Er
 		}
 	}()
 
-	rt.Writer = buf
-	defer func() { rt.Writer = writer }()
-
 	return rt.executeList(try.List)
 }
 
 func (rt *Runtime) executeInclude(node *IncludeNode) (returnValue reflect.Value, err e.Error) {
+	if rt.limits != nil {
+		rt.limits.enter()
+		defer rt.limits.leave()
+	}
 	var templatePath string
 	name, err := rt.evalPrimaryExpressionGroup(node.Name)
 	if err != nil {
@@ -699,7 +736,7 @@ func (rt *Runtime) executeInclude(node *IncludeNode) (returnValue reflect.Value,
 	}
 
 	t, getTemplateErr := rt.set.getSiblingTemplate(templatePath, node.TemplatePath, true)
-	if err != nil {
+	if getTemplateErr != nil {
 		return reflect.Value{}, node.error("", getTemplateErr.Error())
 	}
 
@@ -786,7 +823,7 @@ func (rt *Runtime) evalPrimaryExpressionGroup(node Expression) (reflect.Value, e
 			return reflect.Value{}, err
 		}
 
-		resolved, err := resolveIndex(base, index, "", node.Nullable)
+		resolved, err := rt.resolveIndex(base, index, "", node.Nullable)
 		if err != nil {
 			return reflect.Value{}, node.error(err.Reason(), err.Message())
 		}
@@ -847,6 +884,9 @@ func notNil(v reflect.Value) bool {
 func (rt *Runtime) isSet(node Node) (ok bool, err e.Error) {
 	defer func() {
 		if r := recover(); r != nil {
+			if _, exceeded := r.(*limitError); exceeded {
+				panic(r)
+			}
 			// something panicked while evaluating node
 			ok = false
 		}
@@ -878,7 +918,7 @@ func (rt *Runtime) isSet(node Node) (ok bool, err e.Error) {
 			return false, err
 		}
 
-		resolved, err := resolveIndex(base, index, "", node.Nullable)
+		resolved, err := rt.resolveIndex(base, index, "", node.Nullable)
 		return err == nil && notNil(resolved), nil
 	case NodeIdentifier:
 		value, err := rt.resolve(node.String())
@@ -888,7 +928,7 @@ func (rt *Runtime) isSet(node Node) (ok bool, err e.Error) {
 		resolved := rt.context
 		for i := 0; i < len(node.Idents); i++ {
 			var err error
-			resolved, err = resolveIndex(resolved, reflect.Value{}, node.Idents[i].name, node.Idents[i].lax)
+			resolved, err = rt.resolveIndex(resolved, reflect.Value{}, node.Idents[i].name, node.Idents[i].lax)
 			if err != nil || !notNil(resolved) {
 				return false, nil
 			}
@@ -1315,7 +1355,7 @@ func (rt *Runtime) evalBaseExpressionGroup(node Node) (reflect.Value, e.Error) {
 		node := node.(*FieldNode)
 		resolved := rt.context
 		for i := 0; i < len(node.Idents); i++ {
-			field, err := resolveIndex(resolved, reflect.Value{}, node.Idents[i].name, node.Idents[i].lax)
+			field, err := rt.resolveIndex(resolved, reflect.Value{}, node.Idents[i].name, node.Idents[i].lax)
 			if err != nil {
 				return reflect.Value{}, node.error(err.Reason(), err.Message())
 			}
@@ -1406,7 +1446,7 @@ func (rt *Runtime) evalChainNodeExpression(node *ChainNode) (reflect.Value, e.Er
 
 	for i := 0; i < len(node.Field); i++ {
 		lax := node.Field[i].lax
-		field, err := resolveIndex(resolved, reflect.ValueOf(node.Field[i].name), node.Field[i].name, lax)
+		field, err := rt.resolveIndex(resolved, reflect.ValueOf(node.Field[i].name), node.Field[i].name, lax)
 		if err != nil {
 			return reflect.Value{}, node.error(err.Reason(), err.Message())
 		}
@@ -1763,6 +1803,22 @@ func indirectEface(v reflect.Value) reflect.Value {
 	return v
 }
 
+// resolveIndex calls resolveIndex(), refusing to call methods of types the Set's sandbox doesn't allow.
+func (rt *Runtime) resolveIndex(v, index reflect.Value, indexAsStr string, lax bool) (reflect.Value, e.Error) {
+	if sb := rt.set.sandbox; sb != nil {
+		name := indexAsStr
+		if name == "" && index.Kind() == reflect.String {
+			name = index.String()
+		}
+		if name != "" && !sb.allowsMethod(v, name) {
+			return reflect.Value{}, e.New().
+				WithReason("not_allowed.method").
+				WithMessage(fmt.Sprintf("method %s of %s is not allowed in the sandbox", name, getTypeString(v)))
+		}
+	}
+	return resolveIndex(v, index, indexAsStr, lax)
+}
+
 // mostly copied from text/template's evalField() (exec.go):
 //
 // The index to use to access v can be specified in either index or indexAsStr.
@@ -19,6 +19,7 @@
 package jet
 
 import (
+	"context"
 	"io"
 	"reflect"
 	"sort"
@@ -53,13 +54,27 @@ func (scope VarMap) SetWriter(name string, v SafeWriter) VarMap {
 
 // Execute executes the template into w.
 func (t *Template) Execute(w io.Writer, variables VarMap, data interface{}) (err error) {
+	if t.set != nil && t.set.sandbox != nil {
+		return t.ExecuteContext(context.Background(), w, variables, data)
+	}
+	return t.execute(w, variables, data, nil)
+}
+
+func (t *Template) execute(w io.Writer, variables VarMap, data interface{}, limits *renderLimits) (err error) {
 	st := pool_State.Get().(*Runtime)
 	defer st.recover(&err)
 
 	st.blocks = t.processedBlocks
 	st.variables = variables
 	st.set = t.set
+	st.limits = limits
 	st.Writer = w
+	if limits != nil {
+		st.Writer = &limitWriter{w: w, renderLimits: limits}
+	}
+	if t.set != nil && t.set.contextual {
+		st.Writer = newContextWriter(st.Writer)
+	}
 
 	// resolve extended template
 	for t.extends != nil {
@@ -0,0 +1,872 @@
+package jet
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/pkg/dateparse"
+	"github.com/oarkflow/pkg/decimal"
+	"github.com/oarkflow/pkg/jet/utils/e"
+	"github.com/oarkflow/pkg/money"
+	"github.com/oarkflow/pkg/timeutil"
+)
+
+// LocaleVariable is the variable the translation and formatting functions added by WithTranslator read the
+// locale of a render from, like "en" or "ne-NP". Without it they use the fallback locale of the Catalog.
+const LocaleVariable = "locale"
+
+// Calendar is a calendar dates are formatted in.
+type Calendar string
+
+const (
+	CalendarGregorian    Calendar = "gregorian"
+	CalendarBikramSambat Calendar = "bikram-sambat"
+)
+
+// message holds the text of a message by CLDR plural category; messages without plural forms only have
+// the "other" form.
+type message map[string]string
+
+// Catalog holds the translated messages of an application by locale. It is safe for concurrent use.
+type Catalog struct {
+	mx        sync.RWMutex
+	fallback  string
+	messages  map[string]map[string]message
+	calendars map[string]Calendar
+}
+
+// NewCatalog returns an empty Catalog falling back to the messages of the fallback locale for keys
+// missing from the locale of a render.
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback:  normalizeLocale(fallback),
+		messages:  map[string]map[string]message{},
+		calendars: map[string]Calendar{},
+	}
+}
+
+// Add adds the message key of locale.
+func (c *Catalog) Add(locale, key, text string) *Catalog {
+	return c.AddPlural(locale, key, map[string]string{"other": text})
+}
+
+// AddPlural adds the message key of locale with its text by CLDR plural category: zero, one, two, few,
+// many and other.
+func (c *Catalog) AddPlural(locale, key string, forms map[string]string) *Catalog {
+	msg := message{}
+	for category, text := range forms {
+		msg[category] = text
+	}
+	locale = normalizeLocale(locale)
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.messages[locale] == nil {
+		c.messages[locale] = map[string]message{}
+	}
+	c.messages[locale][key] = msg
+	return c
+}
+
+// SetCalendar sets the calendar formatDate uses for locale. Nepali locales default to the Bikram Sambat
+// calendar, all others to the Gregorian one.
+func (c *Catalog) SetCalendar(locale string, calendar Calendar) *Catalog {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.calendars[normalizeLocale(locale)] = calendar
+	return c
+}
+
+// LoadJSON adds the messages of locale read from r, a JSON object of messages by key. A plural message
+// is an object of texts by plural category, any other object nests keys, joined by dots:
+//
+//	{"invoice": {"title": "Invoice", "items": {"one": "{0} item", "other": "{0} items"}}}
+func (c *Catalog) LoadJSON(locale string, r io.Reader) error {
+	var doc map[string]any
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("jet: reading %s catalog: %w", locale, err)
+	}
+	return c.loadJSON(locale, "", doc)
+}
+
+func (c *Catalog) loadJSON(locale, prefix string, doc map[string]any) error {
+	for key, value := range doc {
+		key = prefix + key
+		switch value := value.(type) {
+		case string:
+			c.Add(locale, key, value)
+		case map[string]any:
+			forms, plural := map[string]string{}, len(value) > 0
+			for category, text := range value {
+				text, isString := text.(string)
+				if !isString || !isPluralCategory(category) {
+					plural = false
+					break
+				}
+				forms[category] = text
+			}
+			if plural {
+				c.AddPlural(locale, key, forms)
+			} else if err := c.loadJSON(locale, key+".", value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("jet: reading %s catalog: message %q is neither a string nor an object", locale, key)
+		}
+	}
+	return nil
+}
+
+// LoadPO adds the messages of locale read from r, a gettext PO file. The msgid of an entry is its key,
+// prefixed by its msgctxt and "\x04" like gettext does. The msgstr[n] forms of plural entries map to the
+// plural categories of the locale in CLDR order, so "one" and "other" for English and Nepali. Fuzzy and
+// untranslated entries are skipped.
+func (c *Catalog) LoadPO(locale string, r io.Reader) error {
+	var (
+		entry   poEntry
+		target  *string
+		lineNum int
+	)
+	flush := func() {
+		entry.add(c, locale)
+		entry, target = poEntry{}, nil
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, "#"):
+			if entry.started() {
+				flush()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				entry.fuzzy = true
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if target == nil {
+				return fmt.Errorf("jet: reading %s catalog: line %d: string outside of an entry", locale, lineNum)
+			}
+		default:
+			keyword, rest, _ := strings.Cut(line, " ")
+			line = strings.TrimSpace(rest)
+			if (keyword == "msgctxt" || keyword == "msgid") && entry.translated() {
+				flush()
+			}
+			switch {
+			case keyword == "msgctxt":
+				target = &entry.context
+				entry.hasContext = true
+			case keyword == "msgid":
+				target = &entry.id
+				entry.hasID = true
+			case keyword == "msgid_plural":
+				target = &entry.plural
+			case keyword == "msgstr":
+				entry.forms = append(entry.forms[:0], "")
+				target = &entry.forms[0]
+			case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+				n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+				if err != nil || n != len(entry.forms) {
+					return fmt.Errorf("jet: reading %s catalog: line %d: unexpected %s", locale, lineNum, keyword)
+				}
+				entry.forms = append(entry.forms, "")
+				target = &entry.forms[n]
+			default:
+				return fmt.Errorf("jet: reading %s catalog: line %d: unknown keyword %q", locale, lineNum, keyword)
+			}
+		}
+		s, err := strconv.Unquote(line)
+		if err != nil {
+			return fmt.Errorf("jet: reading %s catalog: line %d: invalid string %s", locale, lineNum, line)
+		}
+		*target += s
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("jet: reading %s catalog: %w", locale, err)
+	}
+	flush()
+	return nil
+}
+
+// poEntry is an entry of a PO file being read.
+type poEntry struct {
+	context, id, plural string
+	hasContext, hasID   bool
+	forms               []string
+	fuzzy               bool
+}
+
+func (entry *poEntry) started() bool {
+	return entry.hasContext || entry.hasID
+}
+
+func (entry *poEntry) translated() bool {
+	return len(entry.forms) > 0
+}
+
+// add adds the entry to c, unless it is the header, fuzzy or untranslated.
+func (entry *poEntry) add(c *Catalog, locale string) {
+	if !entry.hasID || entry.id == "" || entry.fuzzy || len(entry.forms) == 0 {
+		return
+	}
+	key := entry.id
+	if entry.hasContext {
+		key = entry.context + "\x04" + key
+	}
+	if entry.plural == "" {
+		if entry.forms[0] != "" {
+			c.Add(locale, key, entry.forms[0])
+		}
+		return
+	}
+	categories := pluralRuleOf(locale).categories
+	forms := map[string]string{}
+	for i, text := range entry.forms {
+		if i < len(categories) && text != "" {
+			forms[categories[i]] = text
+		}
+	}
+	if len(forms) > 0 {
+		c.AddPlural(locale, key, forms)
+	}
+}
+
+// Translate returns the message key of locale, falling back to the language of locale and then to the
+// fallback locale, with its placeholders replaced by args; it returns key if no locale has the message.
+// {0}, {1} and so on are replaced by the arguments at that position, {name} by the entry name of a map
+// argument. The first argument, or the "count" entry of a map first argument, selects the plural form.
+func (c *Catalog) Translate(locale, key string, args ...any) string {
+	locale = c.locale(locale)
+	msg, found := c.lookup(locale, key)
+	if !found {
+		return key
+	}
+	text, found := msg["other"]
+	if len(msg) > 1 || !found {
+		var count any
+		if len(args) > 0 {
+			count = args[0]
+			if named, ok := args[0].(map[string]any); ok {
+				count = named["count"]
+			}
+		}
+		if operands, ok := newPluralOperands(count); ok {
+			if form, ok := msg[pluralRuleOf(locale).category(operands)]; ok {
+				text = form
+			}
+		}
+	}
+	return formatMessage(text, locale, args)
+}
+
+// locale returns the normalized locale, or the fallback locale if locale is empty.
+func (c *Catalog) locale(locale string) string {
+	if locale = normalizeLocale(locale); locale == "" {
+		return c.fallback
+	}
+	return locale
+}
+
+func (c *Catalog) lookup(locale, key string) (message, bool) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	for _, candidate := range []string{locale, languageOf(locale), c.fallback, languageOf(c.fallback)} {
+		if msg, found := c.messages[candidate][key]; found {
+			return msg, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Catalog) calendar(locale string) Calendar {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	if calendar, found := c.calendars[locale]; found {
+		return calendar
+	}
+	if calendar, found := c.calendars[languageOf(locale)]; found {
+		return calendar
+	}
+	return formatOf(locale).calendar
+}
+
+// normalizeLocale turns locales like "ne_NP" into their BCP 47 form, "ne-NP".
+func normalizeLocale(locale string) string {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(part)
+		} else if len(part) == 2 {
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func languageOf(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return language
+}
+
+// formatMessage replaces the placeholders of text by args, numbers formatted for locale.
+func formatMessage(text, locale string, args []any) string {
+	if len(args) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+	var named map[string]any
+	if len(args) == 1 {
+		named, _ = args[0].(map[string]any)
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		name := text[start+1 : end]
+		value, found := named[name]
+		if named == nil {
+			if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(args) {
+				value, found = args[i], true
+			}
+		}
+		sb.WriteString(text[:start])
+		if found {
+			sb.WriteString(formatArgument(value, locale))
+		} else {
+			sb.WriteString(text[start : end+1])
+		}
+		text = text[end+1:]
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
+
+func formatArgument(value any, locale string) string {
+	if number, ok := numberString(reflect.ValueOf(value), -1); ok {
+		return formatOf(locale).number(number)
+	}
+	return fmt.Sprint(value)
+}
+
+// pluralOperands are the operands of the CLDR plural rules: the absolute value n, its integer digits i,
+// the number of its visible fraction digits v and the fraction digits f.
+type pluralOperands struct {
+	n       float64
+	i, v, f int64
+}
+
+// newPluralOperands returns the operands of a number, or of a string holding one. Formatted numbers keep
+// their fraction digits, so "1.0" is plural in English while 1 is not.
+func newPluralOperands(count any) (pluralOperands, bool) {
+	number, ok := numberString(reflect.ValueOf(count), -1)
+	if !ok {
+		return pluralOperands{}, false
+	}
+	number = strings.TrimPrefix(number, "-")
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return pluralOperands{}, false
+	}
+	integer, fraction, _ := strings.Cut(number, ".")
+	op := pluralOperands{n: n, v: int64(len(fraction))}
+	op.i, _ = strconv.ParseInt(integer, 10, 64)
+	if fraction != "" {
+		op.f, _ = strconv.ParseInt(fraction, 10, 64)
+	}
+	return op, true
+}
+
+// in reports whether the integer x is within [from, to].
+func in(x, from, to int64) bool {
+	return x >= from && x <= to
+}
+
+// pluralRule is the CLDR cardinal plural rule of a language.
+type pluralRule struct {
+	categories []string
+	category   func(op pluralOperands) string
+}
+
+var (
+	pluralOneOther = pluralRule{categories: []string{"one", "other"}}
+	pluralOther    = pluralRule{categories: []string{"other"}, category: func(pluralOperands) string { return "other" }}
+
+	// pluralOneIntegerOne selects one for 1 written without fraction digits, as English does.
+	pluralOneIntegerOne = pluralRule{categories: pluralOneOther.categories, category: func(op pluralOperands) string {
+		if op.i == 1 && op.v == 0 {
+			return "one"
+		}
+		return "other"
+	}}
+	// pluralOneN selects one for the value 1, as Nepali does.
+	pluralOneN = pluralRule{categories: pluralOneOther.categories, category: func(op pluralOperands) string {
+		if op.n == 1 {
+			return "one"
+		}
+		return "other"
+	}}
+	// pluralOneZeroOrN selects one for 0 to 1, as Hindi does.
+	pluralOneZeroOrN = pluralRule{categories: pluralOneOther.categories, category: func(op pluralOperands) string {
+		if op.i == 0 || op.n == 1 {
+			return "one"
+		}
+		return "other"
+	}}
+	// pluralOneZeroOrOne selects one for 0 to 1.x, as French does.
+	pluralOneZeroOrOne = pluralRule{categories: pluralOneOther.categories, category: func(op pluralOperands) string {
+		if op.i == 0 || op.i == 1 {
+			return "one"
+		}
+		return "other"
+	}}
+	pluralEastSlavic = pluralRule{categories: []string{"one", "few", "many", "other"}, category: func(op pluralOperands) string {
+		switch {
+		case op.v != 0:
+			return "other"
+		case op.i%10 == 1 && op.i%100 != 11:
+			return "one"
+		case in(op.i%10, 2, 4) && !in(op.i%100, 12, 14):
+			return "few"
+		}
+		return "many"
+	}}
+	pluralPolish = pluralRule{categories: []string{"one", "few", "many", "other"}, category: func(op pluralOperands) string {
+		switch {
+		case op.v != 0:
+			return "other"
+		case op.i == 1:
+			return "one"
+		case in(op.i%10, 2, 4) && !in(op.i%100, 12, 14):
+			return "few"
+		}
+		return "many"
+	}}
+	pluralCzech = pluralRule{categories: []string{"one", "few", "many", "other"}, category: func(op pluralOperands) string {
+		switch {
+		case op.v != 0:
+			return "many"
+		case op.i == 1:
+			return "one"
+		case in(op.i, 2, 4):
+			return "few"
+		}
+		return "other"
+	}}
+	pluralArabic = pluralRule{categories: []string{"zero", "one", "two", "few", "many", "other"}, category: func(op pluralOperands) string {
+		if op.v != 0 && op.f != 0 {
+			return "other"
+		}
+		switch n := op.i; {
+		case n == 0:
+			return "zero"
+		case n == 1:
+			return "one"
+		case n == 2:
+			return "two"
+		case in(n%100, 3, 10):
+			return "few"
+		case in(n%100, 11, 99):
+			return "many"
+		}
+		return "other"
+	}}
+)
+
+// pluralRules are the plural rules by language. Languages missing from it use the English rule.
+var pluralRules = map[string]pluralRule{
+	"en": pluralOneIntegerOne, "de": pluralOneIntegerOne, "nl": pluralOneIntegerOne, "sv": pluralOneIntegerOne,
+	"it": pluralOneIntegerOne, "fi": pluralOneIntegerOne, "et": pluralOneIntegerOne,
+	"ne": pluralOneN, "es": pluralOneN, "el": pluralOneN, "tr": pluralOneN, "hu": pluralOneN, "ur": pluralOneIntegerOne,
+	"hi": pluralOneZeroOrN, "bn": pluralOneZeroOrN, "gu": pluralOneZeroOrN, "mr": pluralOneN, "fa": pluralOneZeroOrN,
+	"fr": pluralOneZeroOrOne, "pt": pluralOneZeroOrOne,
+	"ru": pluralEastSlavic, "uk": pluralEastSlavic, "be": pluralEastSlavic,
+	"pl": pluralPolish,
+	"cs": pluralCzech, "sk": pluralCzech,
+	"ar": pluralArabic,
+	"ja": pluralOther, "zh": pluralOther, "ko": pluralOther, "th": pluralOther, "vi": pluralOther, "id": pluralOther,
+	"ms": pluralOther,
+}
+
+func pluralRuleOf(locale string) pluralRule {
+	if rule, found := pluralRules[languageOf(normalizeLocale(locale))]; found {
+		return rule
+	}
+	return pluralOneIntegerOne
+}
+
+func isPluralCategory(category string) bool {
+	switch category {
+	case "zero", "one", "two", "few", "many", "other":
+		return true
+	}
+	return false
+}
+
+// localeFormat holds how a locale writes numbers, amounts of money and dates.
+type localeFormat struct {
+	decimal, group string
+	indianGrouping bool   // group by two digits after the first three, as in 12,34,567
+	zero           rune   // the zero of the native digits of the locale, 0 if they are ASCII
+	money          string // the amount as #, the currency symbol as ¤
+	date           string // the default formatDate format
+	calendar       Calendar
+	names          *dateNames
+}
+
+// dateNames are the names of months and weekdays in a language.
+type dateNames struct {
+	months, bikramSambatMonths [12]string
+	weekdays                   [7]string
+}
+
+var englishDateNames = &dateNames{
+	months: [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September",
+		"October", "November", "December"},
+	bikramSambatMonths: [12]string{"Baishakh", "Jestha", "Asar", "Shrawan", "Bhadra", "Asoj", "Kartik", "Mangsir",
+		"Poush", "Magh", "Falgun", "Chaitra"},
+	weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+}
+
+var nepaliDateNames = &dateNames{
+	months: [12]string{"जनवरी", "फेब्रुअरी", "मार्च", "अप्रिल", "मे", "जुन", "जुलाई", "अगस्ट", "सेप्टेम्बर", "अक्टोबर",
+		"नोभेम्बर", "डिसेम्बर"},
+	bikramSambatMonths: [12]string{"बैशाख", "जेठ", "असार", "साउन", "भदौ", "असोज", "कात्तिक", "मंसिर", "पुस", "माघ",
+		"फागुन", "चैत"},
+	weekdays: [7]string{"आइतबार", "सोमबार", "मंगलबार", "बुधबार", "बिहिबार", "शुक्रबार", "शनिबार"},
+}
+
+// localeFormats are the formats by language. Languages missing from it use the English format; month
+// and weekday names are only available in English and Nepali.
+var localeFormats = map[string]*localeFormat{
+	"en": {decimal: ".", group: ",", money: "¤#", date: "%B %-d, %Y", calendar: CalendarGregorian,
+		names: englishDateNames},
+	"ne": {decimal: ".", group: ",", indianGrouping: true, zero: '०', money: "¤ #", date: "%Y %B %-d",
+		calendar: CalendarBikramSambat, names: nepaliDateNames},
+	"hi": {decimal: ".", group: ",", indianGrouping: true, money: "¤#", date: "%d/%m/%Y"},
+	"de": {decimal: ",", group: ".", money: "# ¤", date: "%d.%m.%Y"},
+	"fr": {decimal: ",", group: " ", money: "# ¤", date: "%d/%m/%Y"},
+	"es": {decimal: ",", group: ".", money: "# ¤", date: "%d/%m/%Y"},
+}
+
+func formatOf(locale string) *localeFormat {
+	format, found := localeFormats[languageOf(normalizeLocale(locale))]
+	if !found {
+		format = localeFormats["en"]
+	}
+	if format.names == nil {
+		clone := *format
+		clone.names, clone.calendar = englishDateNames, CalendarGregorian
+		return &clone
+	}
+	return format
+}
+
+// number formats number, a plain decimal number like "-1234.5", with the separators and digits of the
+// locale.
+func (f *localeFormat) number(number string) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+		if strings.Trim(number, "0.") == "" {
+			sign = ""
+		}
+	}
+	integer, fraction, hasFraction := strings.Cut(number, ".")
+	var groups []string
+	for size := 3; len(integer) > size; {
+		groups = append([]string{integer[len(integer)-size:]}, groups...)
+		integer = integer[:len(integer)-size]
+		if f.indianGrouping {
+			size = 2
+		}
+	}
+	number = sign + strings.Join(append([]string{integer}, groups...), f.group)
+	if hasFraction {
+		number += f.decimal + fraction
+	}
+	return f.digits(number)
+}
+
+// digits replaces the ASCII digits of s by the native digits of the locale.
+func (f *localeFormat) digits(s string) string {
+	if f.zero == 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return f.zero + r - '0'
+		}
+		return r
+	}, s)
+}
+
+// numberString returns v, a number, a decimal.Decimal or a string holding a number, as a plain decimal
+// number with decimals fraction digits, or as many as needed if decimals is negative.
+func numberString(v reflect.Value, decimals int) (string, bool) {
+	v, isNil := indirect(v)
+	if isNil || !v.IsValid() {
+		return "", false
+	}
+	if d, ok := v.Interface().(decimal.Decimal); ok {
+		if decimals < 0 {
+			return d.String(), true
+		}
+		return d.StringFixed(int32(decimals)), true
+	}
+	kind := v.Kind()
+	switch {
+	case isInt(kind), isUint(kind):
+		s := fmt.Sprint(v.Interface())
+		if decimals > 0 {
+			s += "." + strings.Repeat("0", decimals)
+		}
+		return s, true
+	case isFloat(kind):
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'f', decimals, 64), true
+	case kind == reflect.String:
+		d, err := decimal.NewFromString(strings.TrimSpace(v.String()))
+		if err != nil {
+			return "", false
+		}
+		if decimals < 0 {
+			if _, fraction, found := strings.Cut(v.String(), "."); found {
+				return d.StringFixed(int32(len(strings.TrimSpace(fraction)))), true
+			}
+			return d.String(), true
+		}
+		return d.StringFixed(int32(decimals)), true
+	}
+	return "", false
+}
+
+// formatDate formats t with the strftime-like format in the calendar of the locale. It supports the
+// directives of timeutil.NepaliFormatter, %d %-d %m %-m %y %Y %H %-H %I %-I %p %M %-M %S %-S %f %-f
+// and %%, plus %B for the name of the month and %A for the name of the weekday.
+func (f *localeFormat) formatDate(t time.Time, format string, calendar Calendar) (string, error) {
+	if calendar == CalendarBikramSambat {
+		nt, err := timeutil.FromEnglishTime(t)
+		if err != nil {
+			return "", err
+		}
+		weekday := nt.GetEnglishTime().Weekday()
+		formatter := timeutil.NewFormatter(nt)
+		// The directives are formatted one by one, as NepaliFormatter doesn't keep non-ASCII text intact.
+		s := expandDirectives(format, func(directive string) (string, bool) {
+			switch directive {
+			case "B":
+				return f.names.bikramSambatMonths[nt.Month()-1], true
+			case "A":
+				return f.names.weekdays[weekday], true
+			}
+			s, formatErr := formatter.Format("%" + directive)
+			if formatErr != nil {
+				err = fmt.Errorf("unknown directive %%%s", directive)
+				return "", false
+			}
+			return s, true
+		})
+		if err != nil {
+			return "", err
+		}
+		return f.digits(s), nil
+	}
+
+	var unknown string
+	s := expandDirectives(format, func(directive string) (string, bool) {
+		pad := !strings.HasPrefix(directive, "-")
+		number := func(n, width int) string {
+			if pad {
+				return fmt.Sprintf("%0*d", width, n)
+			}
+			return strconv.Itoa(n)
+		}
+		hour12 := t.Hour() % 12
+		if hour12 == 0 {
+			hour12 = 12
+		}
+		switch strings.TrimPrefix(directive, "-") {
+		case "d":
+			return number(t.Day(), 2), true
+		case "m":
+			return number(int(t.Month()), 2), true
+		case "y":
+			return number(t.Year()%100, 2), true
+		case "Y":
+			return strconv.Itoa(t.Year()), true
+		case "H":
+			return number(t.Hour(), 2), true
+		case "I":
+			return number(hour12, 2), true
+		case "p":
+			if t.Hour() < 12 {
+				return "AM", true
+			}
+			return "PM", true
+		case "M":
+			return number(t.Minute(), 2), true
+		case "S":
+			return number(t.Second(), 2), true
+		case "f":
+			return number(t.Nanosecond()/1000, 6), true
+		case "B":
+			return f.names.months[t.Month()-1], true
+		case "A":
+			return f.names.weekdays[t.Weekday()], true
+		case "%":
+			return "%", true
+		}
+		unknown = directive
+		return "", false
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown directive %%%s", unknown)
+	}
+	return f.digits(s), nil
+}
+
+// expandDirectives replaces the % directives of format expand handles and keeps the others.
+func expandDirectives(format string, expand func(directive string) (string, bool)) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+		directive := format[i+1 : i+2]
+		if directive == "-" && i+2 < len(format) {
+			directive = format[i+1 : i+3]
+		}
+		if s, ok := expand(directive); ok {
+			sb.WriteString(s)
+		} else {
+			sb.WriteString("%" + directive)
+		}
+		i += len(directive)
+	}
+	return sb.String()
+}
+
+// WithTranslator returns an option function that adds the translation and formatting functions of
+// catalog to the Set, all using the locale in the LocaleVariable of the render:
+//
+//   - t(key, args...) translates the message key, see Catalog.Translate.
+//   - formatNumber(number, decimals?) writes number with the separators and digits of the locale.
+//   - formatMoney(amount, currency) writes amount with two decimals and the symbol of the currency,
+//     like $1,234.50 in English or ₨ १,२३४.५० in Nepali.
+//   - formatDate(date, format?) writes a time.Time, or a string holding a date, in the calendar of the
+//     locale, by default in its long form. See localeFormat.formatDate for the directives of format.
+func WithTranslator(catalog *Catalog) Option {
+	if catalog == nil {
+		panic("jet: WithTranslator() must not be called with a nil catalog")
+	}
+	localeOf := func(a Arguments) string {
+		var locale string
+		if v := indirectEface(a.runtime.Resolve(LocaleVariable)); v.IsValid() && v.Kind() == reflect.String {
+			locale = v.String()
+		}
+		return catalog.locale(locale)
+	}
+	number := func(a Arguments, funcname string, i, decimals int) string {
+		number, ok := numberString(a.Get(i), decimals)
+		if !ok {
+			a.Panicf(e.InvalidValueErr.WithMessage(fmt.Sprintf("%s(): argument %d is not a number", funcname, i)).Error())
+		}
+		return number
+	}
+	funcs := map[string]Func{
+		"t": func(a Arguments) reflect.Value {
+			a.RequireNumOfArguments("t", 1, -1)
+			key, ok := indirectEface(a.Get(0)).Interface().(string)
+			if !ok {
+				a.Panicf(e.InvalidValueErr.WithMessage("t(): the key is not a string").Error())
+			}
+			args := make([]any, a.NumOfArguments()-1)
+			for i := range args {
+				if arg := indirectEface(a.Get(i + 1)); arg.IsValid() && arg.CanInterface() {
+					args[i] = arg.Interface()
+				}
+			}
+			return reflect.ValueOf(catalog.Translate(localeOf(a), key, args...))
+		},
+		"formatNumber": func(a Arguments) reflect.Value {
+			a.RequireNumOfArguments("formatNumber", 1, 2)
+			decimals := -1
+			if a.NumOfArguments() == 2 {
+				decimals = int(toInt(a.Get(1)))
+			}
+			return reflect.ValueOf(formatOf(localeOf(a)).number(number(a, "formatNumber", 0, decimals)))
+		},
+		"formatMoney": func(a Arguments) reflect.Value {
+			a.RequireNumOfArguments("formatMoney", 2, 2)
+			currency := strings.ToUpper(fmt.Sprint(indirectEface(a.Get(1))))
+			symbol := money.GetCurrencySymbol(currency)
+			if symbol == "" {
+				symbol = currency
+			}
+			format := formatOf(localeOf(a))
+			amount := format.number(number(a, "formatMoney", 0, 2))
+			sign := ""
+			if strings.HasPrefix(amount, "-") {
+				sign, amount = "-", amount[1:]
+			}
+			return reflect.ValueOf(sign + strings.NewReplacer("#", amount, "¤", symbol).Replace(format.money))
+		},
+		"formatDate": func(a Arguments) reflect.Value {
+			a.RequireNumOfArguments("formatDate", 1, 2)
+			var date time.Time
+			switch value := indirectEface(a.Get(0)).Interface().(type) {
+			case time.Time:
+				date = value
+			case *time.Time:
+				date = *value
+			case string:
+				var err error
+				if date, err = dateparse.ParseAny(value); err != nil {
+					a.Panicf(e.InvalidValueErr.WithMessage(fmt.Sprintf("formatDate(): %s", err)).Error())
+				}
+			default:
+				a.Panicf(e.InvalidValueErr.WithMessage(fmt.Sprintf("formatDate(): %T is not a date", value)).Error())
+			}
+			locale := localeOf(a)
+			format := formatOf(locale)
+			layout := format.date
+			if a.NumOfArguments() == 2 {
+				layout = fmt.Sprint(indirectEface(a.Get(1)))
+			}
+			s, err := format.formatDate(date, layout, catalog.calendar(locale))
+			if err != nil {
+				a.Panicf(e.InvalidValueErr.WithMessage(fmt.Sprintf("formatDate(): %s", err)).Error())
+			}
+			return reflect.ValueOf(s)
+		},
+	}
+	arities := map[string][2]int{"t": {1, -1}, "formatNumber": {1, 2}, "formatMoney": {2, 2}, "formatDate": {1, 2}}
+	return func(s *Set) {
+		for name, fn := range funcs {
+			s.AddGlobalFunc(name, fn)
+			s.DeclareFuncArity(name, arities[name][0], arities[name][1])
+		}
+	}
+}
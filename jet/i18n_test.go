@@ -0,0 +1,122 @@
+package jet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranslator(t *testing.T) {
+	catalog := NewCatalog("en")
+	if err := catalog.LoadJSON("en", strings.NewReader(`{
+		"invoice": {
+			"title": "Invoice for {name}",
+			"items": {"one": "{0} item", "other": "{0} items"}
+		},
+		"thanks": "Thank you!"
+	}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.LoadPO("ne", strings.NewReader(`# Nepali
+msgid ""
+msgstr ""
+"Language: ne\n"
+
+msgid "invoice.title"
+msgstr "{name} को "
+"बिल"
+
+msgid "invoice.items"
+msgid_plural "invoice.items"
+msgstr[0] "{0} वस्तु"
+msgstr[1] "{0} वस्तुहरू"
+
+#, fuzzy
+msgid "thanks"
+msgstr "धन्यवाद"
+
+msgctxt "email"
+msgid "subject"
+msgstr "तपाईंको बिल"
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewInMemLoader()
+	set := NewSet(loader, WithTranslator(catalog))
+	date := time.Date(2024, time.April, 14, 9, 5, 0, 0, time.UTC)
+	for _, test := range []struct {
+		locale, template, want string
+	}{
+		{"", `{ t("invoice.title", map("name", "Asha")) }`, "Invoice for Asha"},
+		{"en", `{ t("invoice.items", 1) } / { t("invoice.items", 1200) }`, "1 item / 1,200 items"},
+		{"en-US", `{ t("invoice.items", "1.0") }`, "1.0 items"},
+		{"ne", `{ t("invoice.title", map("name", "आशा")) }`, "आशा को बिल"},
+		{"ne-NP", `{ t("invoice.items", 1) }, { t("invoice.items", 3) }`, "१ वस्तु, ३ वस्तुहरू"},
+		{"ne", `{ t("thanks") } { t("missing") } { t("email\x04subject") }`, "Thank you! missing तपाईंको बिल"},
+		{"en", `{ formatNumber(1234567.891, 2) } { formatNumber(-1000) } { formatNumber("0.50") }`, "1,234,567.89 -1,000 0.50"},
+		{"ne", `{ formatNumber(1234567.5) }`, "१२,३४,५६७.५"},
+		{"en", `{ formatMoney(1234.5, "usd") } { formatMoney(-3, "NPR") }`, "$1,234.50 -₨3.00"},
+		{"ne", `{ formatMoney("123456.789", "NPR") }`, "₨ १,२३,४५६.७९"},
+		{"de", `{ formatMoney(1234.5, "EUR") }`, "1.234,50 €"},
+		{"en", `{ formatDate(date) } { formatDate(date, "%A %d/%m/%y %I:%M %p %%") }`, "April 14, 2024 Sunday 14/04/24 09:05 AM %"},
+		{"en", `{ formatDate("2024-04-14") }`, "April 14, 2024"},
+		{"en", `{ formatDate(precise, "%H:%M:%S.%f") }`, "09:05:07.123456"},
+		{"ne", `{ formatDate(date) } { formatDate(date, "%Y-%m-%d %A") }`, "२०८१ बैशाख २ २०८१-०१-०२ आइतबार"},
+	} {
+		tmpl, err := set.Parse("i18n", test.template)
+		if err != nil {
+			t.Fatalf("%s: %v", test.template, err)
+		}
+		vars := VarMap{}.Set("date", date).Set("precise", date.Add(7*time.Second+123456789))
+		if test.locale != "" {
+			vars.Set(LocaleVariable, test.locale)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars, nil); err != nil {
+			t.Errorf("%s: %v", test.template, err)
+		} else if got := buf.String(); got != test.want {
+			t.Errorf("%s (%s): got %q, want %q", test.template, test.locale, got, test.want)
+		}
+	}
+
+	// The calendar of a locale can be changed, and the names are those of the language.
+	catalog.SetCalendar("ne", CalendarGregorian).SetCalendar("en", CalendarBikramSambat)
+	for locale, want := range map[string]string{"ne": "१४ अप्रिल २०२४", "en": "2 Baishakh 2081"} {
+		tmpl, _ := set.Parse("calendar", `{ formatDate(date, "%-d %B %Y") }`)
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, VarMap{}.Set("date", date).Set(LocaleVariable, locale), nil); err != nil {
+			t.Fatal(err)
+		} else if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", locale, buf.String(), want)
+		}
+	}
+
+	loader.Set("invalid", `{ formatNumber("many") }`)
+	tmpl, _ := set.GetTemplate("invalid")
+	if err := tmpl.Execute(&bytes.Buffer{}, nil, nil); err == nil {
+		t.Error("expected an error formatting a string that is not a number")
+	}
+	if diagnostics, err := set.Lint("invalid", "date"); err != nil || len(diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %v, %v", diagnostics, err)
+	}
+	loader.Set("arity", `{ formatMoney(1) }`)
+	if diagnostics, _ := set.Lint("arity"); len(diagnostics) != 1 || diagnostics[0].Rule != LintArity {
+		t.Errorf("expected an arity diagnostic, got %v", diagnostics)
+	}
+
+	for locale, cases := range map[string]map[any]string{
+		"ru": {1: "one", 3: "few", 5: "many", 11: "many", 21: "one", 1.5: "other"},
+		"ar": {0: "zero", 2: "two", 5: "few", 11: "many", 100: "other"},
+		"fr": {0: "one", 1.5: "one", 2: "other"},
+		"ja": {1: "other"},
+	} {
+		for count, want := range cases {
+			op, _ := newPluralOperands(count)
+			if got := pluralRuleOf(locale).category(op); got != want {
+				t.Errorf("%s %v: got %s, want %s", locale, count, got, want)
+			}
+		}
+	}
+}
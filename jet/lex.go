@@ -239,6 +239,12 @@ func (l *lexer) lineNumber() int {
 	return 1 + strings.Count(l.input[:l.lastPos], "\n")
 }
 
+// columnNumber reports which column (in bytes, starting at 1) we're on, based on the position of
+// the previous item returned by nextItem.
+func (l *lexer) columnNumber() int {
+	return int(l.lastPos) - strings.LastIndex(l.input[:l.lastPos], "\n")
+}
+
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextItem.
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
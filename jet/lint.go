@@ -0,0 +1,488 @@
+package jet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oarkflow/pkg/jet/utils/e"
+)
+
+// Lint rules reported in Diagnostic.Rule.
+const (
+	LintSyntax            = "syntax"             // the template or one it extends or imports doesn't parse
+	LintCycle             = "cycle"              // extends and import clauses form a cycle
+	LintUndefinedVariable = "undefined-variable" // an identifier is neither declared, passed nor a global
+	LintUndefinedFunction = "undefined-function" // a called identifier is neither declared, passed nor a global
+	LintArity             = "arity"              // a function is called with the wrong number of arguments
+	LintMissingBlock      = "missing-block"      // a block is yielded but never defined
+	LintUnusedBlock       = "unused-block"       // a block overrides nothing the extended templates render
+)
+
+// Diagnostic is a problem Lint found in a template.
+type Diagnostic struct {
+	Template string `json:"template"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.Template, d.Line, d.Column, d.Message, d.Rule)
+}
+
+// defaultArities are the numbers of arguments the default Funcs accept.
+var defaultArities = map[string][2]int{
+	"isset":           {1, -1},
+	"len":             {1, 1},
+	"includeIfExists": {1, 2},
+	"exec":            {1, 2},
+	"ints":            {2, 2},
+}
+
+// DeclareFuncArity declares the numbers of arguments the Func global key accepts, so Lint can check
+// calls of it; pass -1 as max for no upper limit. The arity of plain Go functions is known from their
+// type and doesn't need to be declared.
+func (s *Set) DeclareFuncArity(key string, min, max int) *Set {
+	s.gmx.Lock()
+	defer s.gmx.Unlock()
+	if s.arities == nil {
+		s.arities = map[string][2]int{}
+	}
+	s.arities[key] = [2]int{min, max}
+	return s
+}
+
+// Lint parses the template at templatePath, along with the templates it extends and imports, and reports
+// the problems it finds, ordered by template and position. variables are the names of the variables the
+// template is executed with. Lint doesn't put the parsed templates into the cache.
+//
+// Since Jet resolves variables at runtime, a variable declared anywhere in the templates counts as
+// defined everywhere, so Lint only reports names that can't be resolved in any case.
+func (s *Set) Lint(templatePath string, variables ...string) ([]Diagnostic, error) {
+	templatePath = path.Join("/", filepath.ToSlash(templatePath))
+	canonical, found := s.findTemplate(templatePath)
+	if !found {
+		return nil, fmt.Errorf("template %s could not be found", templatePath)
+	}
+
+	l := &linter{set: s, declared: map[string]bool{}, called: map[*IdentifierNode]bool{}}
+	if !l.cycles(canonical, nil, map[string]bool{}) {
+		t, err := s.getTemplateFromLoader(templatePath, false)
+		if err != nil {
+			l.syntaxError(canonical, err)
+		} else {
+			for _, name := range variables {
+				l.declared[name] = true
+			}
+			l.lint(t)
+		}
+	}
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.diagnostics, nil
+}
+
+// findTemplate returns the path the loader has the template at, trying the Set's extensions.
+func (s *Set) findTemplate(templatePath string) (string, bool) {
+	for _, extension := range s.extensions {
+		if s.loader.Exists(templatePath + extension) {
+			return templatePath + extension, true
+		}
+	}
+	return "", false
+}
+
+type linter struct {
+	set         *Set
+	declared    map[string]bool
+	called      map[*IdentifierNode]bool
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(t *Template, pos Pos, rule, message string) {
+	line, column := position(t.text, pos)
+	l.diagnostics = append(l.diagnostics, Diagnostic{Template: t.Name, Line: line, Column: column, Rule: rule, Message: message})
+}
+
+// position returns the line and column of pos in text, both starting at 1.
+func position(text string, pos Pos) (line, column int) {
+	if int(pos) > len(text) {
+		pos = Pos(len(text))
+	}
+	before := text[:pos]
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return strings.Count(before, "\n") + 1, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+func (l *linter) syntaxError(templatePath string, err error) {
+	d := Diagnostic{Template: templatePath, Line: 1, Column: 1, Rule: LintSyntax, Message: err.Error()}
+	var parseErr e.Error
+	if errors.As(err, &parseErr) {
+		d.Message = parseErr.Message()
+		if p := parseErr.Position(); p != nil {
+			d.Line, d.Column = p.L, max(p.C, 1)
+		}
+	}
+	l.diagnostics = append(l.diagnostics, d)
+}
+
+// header returns the extends and import clauses at the start of a template, which the parser loads
+// recursively while parsing.
+func (l *linter) header(templatePath, text string) (paths []string, positions []Pos) {
+	lexer := newLexer(templatePath, text, false)
+	lexer.setDelimiters(l.set.leftDelim, l.set.rightDelim)
+	lexer.lex()
+	var items []item
+	for _, it := range lexer.items {
+		if it.typ == itemSpace || it.typ == itemText && strings.TrimSpace(it.val) == "" {
+			continue
+		}
+		items = append(items, it)
+	}
+	for i := 0; i+3 < len(items); i += 4 {
+		if items[i].typ != itemLeftDelim || items[i+1].typ != itemExtends && items[i+1].typ != itemImport ||
+			items[i+2].typ != itemString && items[i+2].typ != itemRawString || items[i+3].typ != itemRightDelim {
+			break
+		}
+		s, err := unquote(items[i+2].val)
+		if err != nil {
+			break
+		}
+		paths = append(paths, s)
+		positions = append(positions, items[i+1].pos)
+	}
+	return paths, positions
+}
+
+// cycles reports the cycles formed by the extends and import clauses reachable from the template at
+// templatePath, which would keep the parser from terminating, and returns whether there are any.
+func (l *linter) cycles(templatePath string, stack []string, done map[string]bool) (found bool) {
+	if done[templatePath] {
+		return false
+	}
+	f, err := l.set.loader.Open(templatePath)
+	if err != nil {
+		return false
+	}
+	content, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return false
+	}
+	text := string(content)
+	stack = append(stack, templatePath)
+	paths, positions := l.header(templatePath, text)
+	for i, p := range paths {
+		if !path.IsAbs(p) {
+			p = path.Join(path.Dir(templatePath), p)
+		}
+		canonical, ok := l.set.findTemplate(p)
+		if !ok {
+			continue // reported by the parser
+		}
+		if j := slices.Index(stack, canonical); j >= 0 {
+			cycle := append(slices.Clone(stack[j:]), canonical)
+			t := &Template{Name: templatePath, text: text}
+			l.report(t, positions[i], LintCycle, "extends/import cycle: "+strings.Join(cycle, " -> "))
+			found = true
+			continue
+		}
+		found = l.cycles(canonical, stack, done) || found
+	}
+	done[templatePath] = true
+	return found
+}
+
+// lint checks the parsed template t and the templates it extends and imports.
+func (l *linter) lint(t *Template) {
+	var templates, ancestors []*Template
+	seen := map[*Template]bool{}
+	var add func(*Template)
+	add = func(t *Template) {
+		if t == nil || seen[t] {
+			return
+		}
+		seen[t] = true
+		templates = append(templates, t)
+		add(t.extends)
+		for _, imported := range t.imports {
+			add(imported)
+		}
+	}
+	add(t)
+	for parent := t.extends; parent != nil; parent = parent.extends {
+		ancestors = append(ancestors, parent)
+	}
+
+	yielded := map[string]bool{}
+	for _, t := range templates {
+		walkNodes(t.Root, func(n Node) {
+			switch n := n.(type) {
+			case *SetNode:
+				for _, left := range n.Left {
+					if ident, ok := left.(*IdentifierNode); ok {
+						l.declared[ident.Ident] = true
+					}
+				}
+			case *RangeNode:
+				if n.Set != nil {
+					for _, left := range n.Set.Left {
+						if ident, ok := left.(*IdentifierNode); ok {
+							l.declared[ident.Ident] = true
+						}
+					}
+				}
+			case *BlockNode:
+				for _, p := range n.Parameters.List {
+					l.declared[p.Identifier] = true
+				}
+			case *catchNode:
+				if n.Err != nil {
+					l.declared[n.Err.Ident] = true
+				}
+			case *YieldNode:
+				if !n.IsContent {
+					yielded[n.Name] = true
+				}
+			}
+		})
+	}
+
+	for _, t := range templates {
+		walkNodes(t.Root, func(n Node) {
+			switch n := n.(type) {
+			case *PipeNode:
+				// commands after the first are called with the piped value
+				for i, cmd := range n.Cmds {
+					args := len(cmd.Exprs)
+					if i > 0 && !cmd.HasPipeSlot {
+						args++
+					} else if i == 0 && cmd.Exprs == nil {
+						continue
+					}
+					l.call(t, cmd.BaseExpr, args)
+				}
+			case *CallExprNode:
+				l.call(t, n.BaseExpr, len(n.Exprs))
+			case *IdentifierNode:
+				l.identifier(t, n)
+			case *YieldNode:
+				if !n.IsContent && l.block(templates, n.Name) == nil {
+					l.report(t, n.Pos, LintMissingBlock, fmt.Sprintf("yield of missing block %s", n.Name))
+				}
+			}
+		})
+	}
+
+	// blocks of an extending template only render if the templates it extends render them
+	if len(ancestors) > 0 {
+		for _, n := range t.Root.Nodes {
+			block, ok := n.(*BlockNode)
+			if !ok || yielded[block.Name] || l.block(ancestors, block.Name) != nil {
+				continue
+			}
+			l.report(t, block.Pos, LintUnusedBlock, fmt.Sprintf("block %s is never rendered by the templates %s extends", block.Name, t.Name))
+		}
+	}
+}
+
+// block returns the block name defined in one of templates.
+func (l *linter) block(templates []*Template, name string) *BlockNode {
+	for _, t := range templates {
+		if block := t.passedBlocks[name]; block != nil {
+			return block
+		}
+	}
+	return nil
+}
+
+// global returns the global or default variable name, when templates may use it.
+func (l *linter) global(name string) (reflect.Value, bool) {
+	if sb := l.set.sandbox; sb != nil && !sb.allowsGlobal(name) {
+		return reflect.Value{}, false
+	}
+	l.set.gmx.RLock()
+	v, ok := l.set.globals[name]
+	l.set.gmx.RUnlock()
+	if !ok {
+		v, ok = defaultVariables[name]
+	}
+	return indirectEface(v), ok
+}
+
+func (l *linter) identifier(t *Template, n *IdentifierNode) {
+	if l.declared[n.Ident] {
+		return
+	}
+	if _, ok := l.global(n.Ident); ok {
+		return
+	}
+	if l.called[n] {
+		l.report(t, n.Pos, LintUndefinedFunction, fmt.Sprintf("undefined function %s", n.Ident))
+	} else {
+		l.report(t, n.Pos, LintUndefinedVariable, fmt.Sprintf("undefined variable %s", n.Ident))
+	}
+}
+
+// call checks a call of base with args arguments.
+func (l *linter) call(t *Template, base Expression, args int) {
+	ident, ok := base.(*IdentifierNode)
+	if !ok {
+		return
+	}
+	l.called[ident] = true
+	if l.declared[ident.Ident] {
+		return // a variable, which may hold any function
+	}
+	fn, ok := l.global(ident.Ident)
+	if !ok || !fn.IsValid() || fn.Kind() != reflect.Func {
+		return
+	}
+
+	lo, hi := -1, -1
+	switch typ := fn.Type(); {
+	case typ == funcType:
+		l.set.gmx.RLock()
+		arity, declared := l.set.arities[ident.Ident]
+		l.set.gmx.RUnlock()
+		if !declared {
+			_, global := l.set.LookupGlobal(ident.Ident)
+			arity, declared = defaultArities[ident.Ident]
+			declared = declared && !global
+		}
+		if !declared {
+			return
+		}
+		lo, hi = arity[0], arity[1]
+	case typ == safeWriterType:
+		return
+	case typ.IsVariadic():
+		lo = typ.NumIn() - 1
+	default:
+		lo, hi = typ.NumIn(), typ.NumIn()
+	}
+	if args >= lo && (hi < 0 || args <= hi) {
+		return
+	}
+	var expected string
+	switch {
+	case hi < 0:
+		expected = fmt.Sprintf("at least %d", lo)
+	case lo == hi:
+		expected = fmt.Sprint(lo)
+	default:
+		expected = fmt.Sprintf("%d to %d", lo, hi)
+	}
+	l.report(t, ident.Pos, LintArity, fmt.Sprintf("%s expects %s arguments, got %d", ident.Ident, expected, args))
+}
+
+// walkNodes calls fn for n and all the nodes below it, parents before their children.
+func walkNodes(n Node, fn func(Node)) {
+	if n == nil || reflect.ValueOf(n).IsNil() {
+		return
+	}
+	fn(n)
+	walk := func(nodes ...Node) {
+		for _, node := range nodes {
+			walkNodes(node, fn)
+		}
+	}
+	walkExprs := func(exprs []Expression) {
+		for _, expr := range exprs {
+			walkNodes(expr, fn)
+		}
+	}
+	params := func(list *BlockParameterList) {
+		if list != nil {
+			for _, p := range list.List {
+				walk(p.Expression)
+			}
+		}
+	}
+	switch n := n.(type) {
+	case *ListNode:
+		walk(n.Nodes...)
+	case *ActionNode:
+		walk(n.Set, n.Pipe)
+	case *SetNode:
+		for _, left := range n.Left {
+			if _, ok := left.(*IdentifierNode); ok {
+				continue // a declaration, not a use
+			}
+			walk(left)
+		}
+		walkExprs(n.Right)
+	case *PipeNode:
+		for _, cmd := range n.Cmds {
+			walk(cmd)
+		}
+	case *CommandNode:
+		walk(n.BaseExpr)
+		walkExprs(n.Exprs)
+	case *CallExprNode:
+		walk(n.BaseExpr)
+		walkExprs(n.Exprs)
+	case *ChainNode:
+		walk(n.Node)
+	case *AdditiveExprNode:
+		walk(n.Left, n.Right)
+	case *MultiplicativeExprNode:
+		walk(n.Left, n.Right)
+	case *LogicalExprNode:
+		walk(n.Left, n.Right)
+	case *ComparativeExprNode:
+		walk(n.Left, n.Right)
+	case *NumericComparativeExprNode:
+		walk(n.Left, n.Right)
+	case *NotExprNode:
+		walk(n.Expr)
+	case *TernaryExprNode:
+		walk(n.Boolean, n.Left, n.Right)
+	case *IndexExprNode:
+		walk(n.Base, n.Index)
+	case *SliceExprNode:
+		walk(n.Base, n.Index, n.EndIndex)
+	case *IfNode:
+		walk(n.Set, n.Expression, n.List, n.ElseList)
+	case *RangeNode:
+		if n.Set != nil {
+			walkExprs(n.Set.Right)
+		}
+		walk(n.Expression, n.List, n.ElseList)
+	case *BlockNode:
+		params(n.Parameters)
+		walk(n.Expression, n.List, n.Content)
+	case *YieldNode:
+		params(n.Parameters)
+		walk(n.Expression, n.Content)
+	case *IncludeNode:
+		walk(n.Name, n.Context)
+	case *ReturnNode:
+		walk(n.Value)
+	case *TryNode:
+		walk(n.List)
+		if n.Catch != nil {
+			walk(n.Catch)
+		}
+	case *catchNode:
+		walk(n.List)
+	}
+}
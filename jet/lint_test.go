@@ -0,0 +1,55 @@
+package jet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	loader := NewInMemLoader()
+	loader.Set("/layout.jet", "<html>{ yield body() }\n{ yield sidebar() }</html>")
+	loader.Set("/macros.jet", `{ block badge(label) }{ label | upper }{ end }`)
+	loader.Set("/page.jet", `{ extends "layout.jet" }
+{ import "macros.jet" }
+{ block body() }{ x := 1 }{ title | repeat(x) }{ yield badge(label="new") }
+  { len(title, x) } { add(1) } { unknown(1) } { user.Name }{ range i, v := items }{ i }{ v }{ end }
+{ end }
+{ block footer() }{ end }`)
+	loader.Set("/a.jet", `{ extends "b.jet" }`)
+	loader.Set("/b.jet", `{ import "./a" }`)
+	loader.Set("/broken.jet", "ok\n  { if }")
+
+	set := NewSet(loader)
+	set.AddGlobal("add", func(a, b int) int { return a + b })
+
+	got, err := set.Lint("/page", "title", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Diagnostic{
+		{Template: "/layout.jet", Line: 2, Column: 9, Rule: LintMissingBlock, Message: "yield of missing block sidebar"},
+		{Template: "/page.jet", Line: 4, Column: 5, Rule: LintArity, Message: "len expects 1 arguments, got 2"},
+		{Template: "/page.jet", Line: 4, Column: 23, Rule: LintArity, Message: "add expects 2 arguments, got 1"},
+		{Template: "/page.jet", Line: 4, Column: 34, Rule: LintUndefinedFunction, Message: "undefined function unknown"},
+		{Template: "/page.jet", Line: 4, Column: 49, Rule: LintUndefinedVariable, Message: "undefined variable user"},
+		{Template: "/page.jet", Line: 6, Column: 9, Rule: LintUnusedBlock, Message: "block footer is never rendered by the templates /page.jet extends"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	got, _ = set.Lint("/a.jet")
+	want = []Diagnostic{{Template: "/b.jet", Line: 1, Column: 3, Rule: LintCycle, Message: "extends/import cycle: /a.jet -> /b.jet -> /a.jet"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, _ = set.Lint("/broken.jet")
+	if len(got) != 1 || got[0].Rule != LintSyntax || got[0].Line != 2 || got[0].Column != 8 {
+		t.Errorf("unexpected syntax diagnostics %v", got)
+	}
+
+	if _, err := set.Lint("/missing.jet"); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+}
@@ -16,8 +16,10 @@ package jet
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
@@ -153,3 +155,86 @@ func (l *InMemLoader) Delete(templatePath string) {
 	defer l.lock.Unlock()
 	delete(l.files, templatePath)
 }
+
+// FSLoader implements Loader interface on top of an fs.FS, like an embed.FS or the result of os.DirFS().
+// Template paths are looked up relative to the root of the file system, so "/views/foo.jet" opens
+// "views/foo.jet". Use fs.Sub() to serve templates from a subdirectory of an embedded file system.
+type FSLoader struct {
+	fsys fs.FS
+}
+
+// compile time check that we implement Loader
+var _ Loader = (*FSLoader)(nil)
+
+// NewFSLoader returns an initialized FSLoader. NewFSLoader panics if a nil fs.FS is passed.
+func NewFSLoader(fsys fs.FS) *FSLoader {
+	if fsys == nil {
+		panic(errors.New("jet: NewFSLoader() must not be called with a nil fs.FS"))
+	}
+	return &FSLoader{fsys: fsys}
+}
+
+// normalize turns a template path into the unrooted, slash-delimited path fs.FS expects.
+func (l *FSLoader) normalize(templatePath string) string {
+	templatePath = path.Join("/", filepath.ToSlash(templatePath))
+	if templatePath == "/" {
+		return "."
+	}
+	return templatePath[1:]
+}
+
+// Exists returns true if a regular file is found under the template path in the file system.
+func (l *FSLoader) Exists(templatePath string) bool {
+	stat, err := fs.Stat(l.fsys, l.normalize(templatePath))
+	return err == nil && !stat.IsDir()
+}
+
+// Open returns the result of opening the template path in the file system.
+func (l *FSLoader) Open(templatePath string) (io.ReadCloser, error) {
+	return l.fsys.Open(l.normalize(templatePath))
+}
+
+// MultiLoader implements Loader interface by searching a list of loaders in order, so templates of
+// the first loaders override the ones with the same path in later loaders, for example a tenant's
+// templates on disk in front of the defaults embedded in the binary.
+//
+// MultiLoader does not remember which loader a template was found in: every call to Exists() and
+// Open() asks the loaders again, so in development mode templates added to, changed in or removed
+// from any layer are picked up by the next lookup.
+type MultiLoader struct {
+	loaders []Loader
+}
+
+// compile time check that we implement Loader
+var _ Loader = (*MultiLoader)(nil)
+
+// NewMultiLoader returns a MultiLoader searching loaders in the order they are passed.
+// NewMultiLoader panics if one of the loaders is nil.
+func NewMultiLoader(loaders ...Loader) *MultiLoader {
+	for _, l := range loaders {
+		if l == nil {
+			panic(errors.New("jet: NewMultiLoader() must not be called with a nil loader"))
+		}
+	}
+	return &MultiLoader{loaders: loaders}
+}
+
+// Exists returns true if any of the loaders has a template under the requested path.
+func (l *MultiLoader) Exists(templatePath string) bool {
+	for _, loader := range l.loaders {
+		if loader.Exists(templatePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens the template in the first loader it exists in.
+func (l *MultiLoader) Open(templatePath string) (io.ReadCloser, error) {
+	for _, loader := range l.loaders {
+		if loader.Exists(templatePath) {
+			return loader.Open(templatePath)
+		}
+	}
+	return nil, fmt.Errorf("%s does not exist", templatePath)
+}
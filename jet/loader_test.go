@@ -0,0 +1,50 @@
+package jet
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func renderTemplate(t *testing.T, set *Set, name string) string {
+	t.Helper()
+	tmpl, err := set.GetTemplate(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestMultiLoader(t *testing.T) {
+	defaults := NewFSLoader(fstest.MapFS{
+		"layout.jet":       {Data: []byte(`<main>{ yield body() }</main>`)},
+		"views/home.jet":   {Data: []byte(`{ extends "../layout.jet" }{ block body() }default{ end }`)},
+		"views/footer.jet": {Data: []byte(`footer`)},
+	})
+	tenant := NewInMemLoader()
+	loader := NewMultiLoader(tenant, defaults)
+
+	if !loader.Exists("/views/footer.jet") || !loader.Exists("views/home.jet") || loader.Exists("/views") {
+		t.Error("unexpected Exists results")
+	}
+	if _, err := loader.Open("/missing.jet"); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+
+	set := NewSet(loader, InDevelopmentMode())
+	if got := renderTemplate(t, set, "/views/home"); got != "<main>default</main>" {
+		t.Errorf("got %q", got)
+	}
+	tenant.Set("/layout.jet", `<div>{ yield body() }</div>`)
+	if got := renderTemplate(t, set, "/views/home"); got != "<div>default</div>" {
+		t.Errorf("override not picked up: got %q", got)
+	}
+	tenant.Delete("/layout.jet")
+	if got := renderTemplate(t, set, "/views/home"); got != "<main>default</main>" {
+		t.Errorf("removed override still used: got %q", got)
+	}
+}
@@ -199,7 +199,7 @@ func (t *Template) error(reason, message string) e.Error {
 		reason,
 		t.ParseName,
 		message,
-		&e.Position{L: t.lex.lineNumber(), C: 0},
+		&e.Position{L: t.lex.lineNumber(), C: t.lex.columnNumber()},
 	)
 }
 
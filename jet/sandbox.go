@@ -0,0 +1,216 @@
+package jet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrOutputLimit is returned by sandboxed renders writing more than Sandbox.MaxOutputBytes.
+	ErrOutputLimit = errors.New("jet: output limit exceeded")
+	// ErrIterationLimit is returned by sandboxed renders running more than Sandbox.MaxIterations loop iterations.
+	ErrIterationLimit = errors.New("jet: loop iteration limit exceeded")
+	// ErrDepthLimit is returned by sandboxed renders nesting includes, yields, blocks and exec calls deeper
+	// than Sandbox.MaxDepth.
+	ErrDepthLimit = errors.New("jet: nesting depth limit exceeded")
+)
+
+// DefaultMaxDepth is the nesting depth sandboxed renders are limited to when Sandbox.MaxDepth is not set.
+const DefaultMaxDepth = 100
+
+// Sandbox restricts what the templates of a Set may do, for rendering templates written by untrusted users.
+// Limits left at their zero value are not enforced, except MaxDepth.
+type Sandbox struct {
+	// Globals lists the globals and default functions (see AddGlobal, AddGlobalFunc and the builtins like
+	// len or exec) templates may use. Variables passed to Execute are always available.
+	Globals []string
+
+	// Types lists the types whose methods templates may call. Methods of other types are not resolved,
+	// so map keys and struct fields named like them stay unreachable too.
+	Types []reflect.Type
+
+	// Root is the directory templates may extend, import and include templates from, for example
+	// "/tenants/acme". Templates outside of it can't be loaded either.
+	Root string
+
+	// MaxOutputBytes limits the bytes a render may write.
+	MaxOutputBytes int64
+
+	// MaxIterations limits the iterations of all the range loops of a render together.
+	MaxIterations int
+
+	// MaxDepth limits the nesting of includes, yields, blocks and exec calls, which would otherwise let
+	// a recursive template overflow the stack. It defaults to DefaultMaxDepth.
+	MaxDepth int
+
+	// Timeout limits the duration of a render, in addition to the deadline of the context passed to
+	// ExecuteContext.
+	Timeout time.Duration
+}
+
+// WithSandbox returns an option function that restricts the templates of the Set to sb.
+func WithSandbox(sb Sandbox) Option {
+	if sb.Root != "" {
+		sb.Root = path.Join("/", sb.Root)
+	}
+	return func(s *Set) {
+		s.sandbox = &sb
+	}
+}
+
+// allowsGlobal reports whether templates may use the global or default variable name.
+func (sb *Sandbox) allowsGlobal(name string) bool {
+	return slices.Contains(sb.Globals, name)
+}
+
+// allowsPath reports whether templates may load the template at the absolute path templatePath.
+func (sb *Sandbox) allowsPath(templatePath string) bool {
+	return sb.Root == "" || sb.Root == "/" || templatePath == sb.Root || strings.HasPrefix(templatePath, sb.Root+"/")
+}
+
+// allowsMethod reports whether resolving name on v may call a method of v.
+func (sb *Sandbox) allowsMethod(v reflect.Value, name string) bool {
+	v, isNil := indirect(v)
+	if isNil || !v.IsValid() {
+		return true
+	}
+	ptr := v
+	if ptr.Kind() != reflect.Interface && ptr.Kind() != reflect.Ptr && ptr.CanAddr() {
+		ptr = ptr.Addr()
+	}
+	if !ptr.MethodByName(name).IsValid() {
+		return true
+	}
+	for _, typ := range sb.Types {
+		if typ == v.Type() || typ == ptr.Type() {
+			return true
+		}
+	}
+	return false
+}
+
+// limitError aborts a render exceeding a limit. It is not caught by try blocks.
+type limitError struct {
+	err error
+}
+
+func (err *limitError) Error() string { return err.err.Error() }
+func (err *limitError) Unwrap() error { return err.err }
+
+// renderLimits is the budget left to a render.
+type renderLimits struct {
+	ctx        context.Context
+	written    int64
+	maxWritten int64
+	iterations int
+	maxIter    int
+	depth      int
+	maxDepth   int
+}
+
+// check aborts the render once its context is done.
+func (l *renderLimits) check() {
+	if err := l.ctx.Err(); err != nil {
+		panic(&limitError{err: fmt.Errorf("jet: render aborted: %w", err)})
+	}
+}
+
+// iterate counts a loop iteration.
+func (l *renderLimits) iterate() {
+	l.iterations++
+	if l.maxIter > 0 && l.iterations > l.maxIter {
+		panic(&limitError{err: ErrIterationLimit})
+	}
+	l.check()
+}
+
+// enter counts a nested include, yield, block or exec call; leave ends it.
+func (l *renderLimits) enter() {
+	l.depth++
+	if l.maxDepth > 0 && l.depth > l.maxDepth {
+		panic(&limitError{err: ErrDepthLimit})
+	}
+	l.check()
+}
+
+func (l *renderLimits) leave() {
+	l.depth--
+}
+
+// limitWriter counts the bytes written to w against the limits of the render.
+type limitWriter struct {
+	w io.Writer
+	*renderLimits
+}
+
+func (w *limitWriter) Write(b []byte) (int, error) {
+	w.written += int64(len(b))
+	if w.maxWritten > 0 && w.written > w.maxWritten {
+		panic(&limitError{err: ErrOutputLimit})
+	}
+	return w.w.Write(b)
+}
+
+// ExecuteContext executes the template into w like Execute, aborting the render once ctx is done.
+func (t *Template) ExecuteContext(ctx context.Context, w io.Writer, variables VarMap, data interface{}) error {
+	limits := &renderLimits{ctx: ctx}
+	if sb := t.set.sandbox; sb != nil {
+		if sb.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, sb.Timeout)
+			defer cancel()
+			limits.ctx = ctx
+		}
+		limits.maxWritten, limits.maxIter, limits.maxDepth = sb.MaxOutputBytes, sb.MaxIterations, sb.MaxDepth
+		if limits.maxDepth == 0 {
+			limits.maxDepth = DefaultMaxDepth
+		}
+	}
+	return t.execute(w, variables, data, limits)
+}
+
+// bufferOutput redirects the render output into buf until flush or discard is called, keeping track of the
+// parse state and the output limit: flush writes buf to the output, discard rolls them back.
+func (rt *Runtime) bufferOutput(buf *bytes.Buffer) (flush, discard func()) {
+	writer := rt.Writer
+	var target io.Writer = buf
+	out := writer
+	var rollbacks []func()
+
+	cw, contextual := out.(*contextWriter)
+	if contextual {
+		out = cw.w
+	}
+	if lw, ok := out.(*limitWriter); ok {
+		written := lw.written
+		rollbacks = append(rollbacks, func() { lw.written = written })
+		target = &limitWriter{w: buf, renderLimits: lw.renderLimits}
+		out = lw.w
+	}
+	if contextual {
+		state := cw.save()
+		rollbacks = append(rollbacks, func() { *cw.escapeContext = state })
+		target = &contextWriter{w: target, escapeContext: cw.escapeContext}
+	}
+
+	rt.Writer = target
+	flush = func() {
+		rt.Writer = writer
+		io.Copy(out, buf)
+	}
+	discard = func() {
+		rt.Writer = writer
+		for _, rollback := range rollbacks {
+			rollback()
+		}
+	}
+	return flush, discard
+}
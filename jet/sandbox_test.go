@@ -0,0 +1,111 @@
+package jet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type invoice struct {
+	Number string
+	Total  float64
+}
+
+func (invoice) Secret() string { return "secret" }
+
+func (i invoice) Label() string { return "#" + i.Number }
+
+type label struct{ Text string }
+
+func (l label) Upper() string { return strings.ToUpper(l.Text) }
+
+func TestSandbox(t *testing.T) {
+	loader := NewInMemLoader()
+	loader.Set("/tenants/acme/layout.jet", `<h1>{ yield body() }</h1>`)
+	loader.Set("/tenants/acme/footer.jet", `footer`)
+	loader.Set("/tenants/acme/self.jet", `{ include "./self.jet" }`)
+	loader.Set("/shared/secret.jet", `secret`)
+	set := NewSet(loader, WithSandbox(Sandbox{
+		Globals:        []string{"len", "ints", "company"},
+		Types:          []reflect.Type{reflect.TypeOf(label{})},
+		Root:           "tenants/acme",
+		MaxOutputBytes: 64,
+		MaxIterations:  10,
+	}))
+	set.AddGlobal("company", "ACME")
+	set.AddGlobal("internal", "hidden")
+
+	render := func(template string) (string, error) {
+		tmpl, err := set.Parse("/tenants/acme/test.jet", template)
+		if err != nil {
+			return "", err
+		}
+		vars := VarMap{}
+		vars.Set("invoice", invoice{Number: "7", Total: 12.5}).Set("title", label{Text: "due"})
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, vars, nil)
+		return buf.String(), err
+	}
+
+	for _, test := range []struct{ template, want string }{
+		{`{ company }: { invoice.Number } { invoice.Total } { title.Upper() } { len(invoice.Number) }`, `ACME: 7 12.5 DUE 1`},
+		{`{ extends "layout.jet" }{ block body() }{ include "./footer.jet" }{ end }`, `<h1>footer</h1>`},
+		{`{ range ints(0, 3) }{ . }{ end }`, `012`},
+		{`{ try }{ range ints(0, 3) }x{ end }{ end }`, `xxx`},
+	} {
+		got, err := render(test.template)
+		if err != nil {
+			t.Errorf("%s: %v", test.template, err)
+		} else if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.template, got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		template string
+		want     error
+	}{
+		{`{ internal }`, nil},
+		{`{ exec("/tenants/acme/footer.jet") }`, nil},
+		{`{ invoice.Secret() }`, nil},
+		{`{ invoice.Label() }`, nil},
+		{`{ include "/shared/secret.jet" }`, nil},
+		{`{ extends "../../shared/secret.jet" }`, nil},
+		{`{ range ints(0, 100) }{ end }`, ErrIterationLimit},
+		{`{ try }{ range ints(0, 100) }{ end }{ catch }caught{ end }`, ErrIterationLimit},
+		{`{ include "./self.jet" }`, ErrDepthLimit},
+		{`{ block b() }{ yield b() }{ end }`, ErrDepthLimit},
+		{`{ range ints(0, 5) }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ invoice.Number }{ end }`, ErrOutputLimit},
+	} {
+		_, err := render(test.template)
+		if err == nil {
+			t.Errorf("%s: expected an error", test.template)
+		} else if test.want != nil && !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.template, err, test.want)
+		}
+	}
+
+	tmpl, err := set.Parse("/tenants/acme/test.jet", `{ range ints(0, 5) }x{ end }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tmpl.ExecuteContext(ctx, new(bytes.Buffer), nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the render to be canceled, got %v", err)
+	}
+
+	slow := NewSet(NewInMemLoader(), WithSandbox(Sandbox{Globals: []string{"ints", "sleep"}, Timeout: 10 * time.Millisecond}))
+	slow.AddGlobal("sleep", func() string { time.Sleep(5 * time.Millisecond); return "" })
+	tmpl, err = slow.Parse("/slow.jet", `{ range ints(0, 100) }{ sleep() }{ end }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Execute(new(bytes.Buffer), nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the render to time out, got %v", err)
+	}
+}
@@ -24,6 +24,9 @@ type Set struct {
 	gmx               *sync.RWMutex // global variables map mutex
 	extensions        []string
 	developmentMode   bool
+	contextual        bool // escape values for the HTML context they are written in
+	sandbox           *Sandbox
+	arities           map[string][2]int // argument counts of Func globals, see DeclareFuncArity
 	leftDelim         string
 	rightDelim        string
 	placeholderParser *regexp.Regexp
@@ -132,6 +135,9 @@ func (s *Set) getSiblingTemplate(templatePath, siblingPath string, cacheAfterPar
 		siblingDir := path.Dir(siblingPath)
 		templatePath = path.Join(siblingDir, templatePath)
 	}
+	if s.sandbox != nil && !s.sandbox.allowsPath(path.Clean(templatePath)) {
+		return nil, fmt.Errorf("template %s is outside of the sandbox root %s", templatePath, s.sandbox.Root)
+	}
 	return s.getTemplate(templatePath, cacheAfterParsing)
 }
 
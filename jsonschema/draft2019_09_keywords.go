@@ -1,5 +1,8 @@
 package jsonschema
 
+// Draft2019_09 is the meta-schema URI of draft2019_09, used with $schema
+const Draft2019_09 = "https://json-schema.org/draft/2019-09/schema"
+
 // LoadDraft2019_09 loads the keywords for schema validation
 // based on draft2019_09
 // this is also the default keyword set loaded automatically
@@ -24,6 +27,8 @@ func (r *KeywordRegistry) DefaultIfEmpty() {
 // this is also the default keyword set loaded automatically
 // if no other is loaded
 func (r *KeywordRegistry) LoadDraft2019_09() {
+	r.draft = Draft2019_09
+
 	// core keywords
 	r.RegisterKeyword("$schema", NewSchemaURI)
 	r.RegisterKeyword("$id", NewID)
@@ -0,0 +1,110 @@
+package jsonschema
+
+// Draft2020_12 is the meta-schema URI of draft2020_12, used with $schema
+const Draft2020_12 = "https://json-schema.org/draft/2020-12/schema"
+
+// LoadDraft2020_12 loads the keywords for schema validation
+// based on draft2020_12 into the global registry, making it the
+// default for schemas that don't declare a draft with $schema
+func LoadDraft2020_12() {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.LoadDraft2020_12()
+}
+
+// LoadDraft2020_12 loads the keywords for schema validation
+// based on draft2020_12.
+// Compared to draft2019_09 prefixItems takes over the array form
+// of items, which only applies to the items after them,
+// $dynamicRef and $dynamicAnchor replace $recursiveRef and
+// $recursiveAnchor, additionalItems is gone and format only
+// annotates the instance, unless NewFormat is registered for it
+func (r *KeywordRegistry) LoadDraft2020_12() {
+	r.draft = Draft2020_12
+
+	// core keywords
+	r.RegisterKeyword("$schema", NewSchemaURI)
+	r.RegisterKeyword("$id", NewID)
+	r.RegisterKeyword("description", NewDescription)
+	r.RegisterKeyword("title", NewTitle)
+	r.RegisterKeyword("$comment", NewComment)
+	r.RegisterKeyword("examples", NewExamples)
+	r.RegisterKeyword("readOnly", NewReadOnly)
+	r.RegisterKeyword("writeOnly", NewWriteOnly)
+	r.RegisterKeyword("$ref", NewRef)
+	r.RegisterKeyword("$dynamicRef", NewDynamicRef)
+	r.RegisterKeyword("$anchor", NewAnchor)
+	r.RegisterKeyword("$dynamicAnchor", NewDynamicAnchor)
+	r.RegisterKeyword("$defs", NewDefs)
+	r.RegisterKeyword("default", NewDefault)
+
+	r.SetKeywordOrder("$ref", 0)
+	r.SetKeywordOrder("$dynamicRef", 0)
+
+	// standard keywords
+	r.RegisterKeyword("type", NewType)
+	r.RegisterKeyword("enum", NewEnum)
+	r.RegisterKeyword("const", NewConst)
+
+	// numeric keywords
+	r.RegisterKeyword("multipleOf", NewMultipleOf)
+	r.RegisterKeyword("maximum", NewMaximum)
+	r.RegisterKeyword("exclusiveMaximum", NewExclusiveMaximum)
+	r.RegisterKeyword("minimum", NewMinimum)
+	r.RegisterKeyword("exclusiveMinimum", NewExclusiveMinimum)
+
+	// string keywords
+	r.RegisterKeyword("maxLength", NewMaxLength)
+	r.RegisterKeyword("minLength", NewMinLength)
+	r.RegisterKeyword("pattern", NewPattern)
+
+	// boolean keywords
+	r.RegisterKeyword("allOf", NewAllOf)
+	r.RegisterKeyword("anyOf", NewAnyOf)
+	r.RegisterKeyword("oneOf", NewOneOf)
+	r.RegisterKeyword("not", NewNot)
+
+	// object keywords
+	r.RegisterKeyword("properties", NewProperties)
+	r.RegisterKeyword("patternProperties", NewPatternProperties)
+	r.RegisterKeyword("additionalProperties", NewAdditionalProperties)
+	r.RegisterKeyword("required", NewRequired)
+	r.RegisterKeyword("propertyNames", NewPropertyNames)
+	r.RegisterKeyword("maxProperties", NewMaxProperties)
+	r.RegisterKeyword("minProperties", NewMinProperties)
+	r.RegisterKeyword("dependentSchemas", NewDependentSchemas)
+	r.RegisterKeyword("dependentRequired", NewDependentRequired)
+	r.RegisterKeyword("unevaluatedProperties", NewUnevaluatedProperties)
+
+	r.SetKeywordOrder("properties", 2)
+	r.SetKeywordOrder("additionalProperties", 3)
+	r.SetKeywordOrder("unevaluatedProperties", 4)
+
+	// array keywords
+	r.RegisterKeyword("prefixItems", NewPrefixItems)
+	r.RegisterKeyword("items", NewItems)
+	r.RegisterKeyword("maxItems", NewMaxItems)
+	r.RegisterKeyword("minItems", NewMinItems)
+	r.RegisterKeyword("uniqueItems", NewUniqueItems)
+	r.RegisterKeyword("contains", NewContains)
+	r.RegisterKeyword("maxContains", NewMaxContains)
+	r.RegisterKeyword("minContains", NewMinContains)
+	r.RegisterKeyword("unevaluatedItems", NewUnevaluatedItems)
+
+	r.SetKeywordOrder("items", 2)
+	r.SetKeywordOrder("maxContains", 2)
+	r.SetKeywordOrder("minContains", 2)
+	r.SetKeywordOrder("unevaluatedItems", 4)
+
+	// conditional keywords
+	r.RegisterKeyword("if", NewIf)
+	r.RegisterKeyword("then", NewThen)
+	r.RegisterKeyword("else", NewElse)
+
+	r.SetKeywordOrder("then", 2)
+	r.SetKeywordOrder("else", 2)
+
+	// format annotation
+	r.RegisterKeyword("format", NewFormatAnnotation)
+}
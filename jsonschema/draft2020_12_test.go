@@ -0,0 +1,164 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// suiteCase is a test case in the format of the JSON-Schema-Test-Suite, so
+// files of the suite can be dropped into testdata as they are
+type suiteCase struct {
+	Description string          `json:"description"`
+	Schema      json.RawMessage `json:"schema"`
+	Tests       []struct {
+		Description string      `json:"description"`
+		Data        interface{} `json:"data"`
+		Valid       bool        `json:"valid"`
+	} `json:"tests"`
+}
+
+// suiteSkips lists the groups of the suite left out, as "file: description",
+// with the reason they are
+var suiteSkips = map[string]string{
+	"additionalProperties.json: additionalProperties does not look in applicators": "additionalProperties counts the properties evaluated by allOf",
+	"additionalProperties.json: dependentSchemas with additionalProperties":        "additionalProperties counts the properties evaluated by dependentSchemas",
+	"defs.json: validate definition against metaschema":                            "the 2020-12 meta-schema is not vendored, and the tests don't fetch it",
+	"ref.json: remote ref, containing refs itself":                                 "the 2020-12 meta-schema is not vendored, and the tests don't fetch it",
+	"dynamicRef.json: $ref to $dynamicRef finds detached $dynamicAnchor":           "a $dynamicRef reached through a $ref to another resource does not see the $dynamicAnchor of that resource",
+	"refRemote.json: base URI change - change folder in subschema":                 "a $ref resolved by JSON pointer through a subschema with its own $id doesn't use that $id as base URI",
+	"maxContains.json: maxContains with contains, value with a decimal":            "integer keyword values like 1.0 are rejected when parsing",
+	"maxItems.json: maxItems validation with a decimal":                            "integer keyword values like 2.0 are rejected when parsing",
+	"maxLength.json: maxLength validation with a decimal":                          "integer keyword values like 2.0 are rejected when parsing",
+	"maxProperties.json: maxProperties validation with a decimal":                  "integer keyword values like 2.0 are rejected when parsing",
+	"minContains.json: minContains=2 with contains with a decimal value":           "integer keyword values like 2.0 are rejected when parsing",
+	"minItems.json: minItems validation with a decimal":                            "integer keyword values like 1.0 are rejected when parsing",
+	"minLength.json: minLength validation with a decimal":                          "integer keyword values like 2.0 are rejected when parsing",
+	"minProperties.json: minProperties validation with a decimal":                  "integer keyword values like 1.0 are rejected when parsing",
+}
+
+// loadRemote serves the remotes of the suite, which refer to them under
+// http://localhost:1234, from testdata/remotes
+func loadRemote(ctx context.Context, uri *url.URL, schema *Schema) error {
+	if uri.Host != "localhost:1234" {
+		return HTTPSchemaLoader(ctx, uri, schema)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "remotes", filepath.FromSlash(uri.Path)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, schema)
+}
+
+func runSuite(t *testing.T, dir string) {
+	GetSchemaLoaderRegistry().Register("http", loadRemote)
+	GetSchemaRegistry().GetLoaderRegistry().Register("http", loadRemote)
+	defer func() {
+		GetSchemaLoaderRegistry().Register("http", HTTPSchemaLoader)
+		GetSchemaRegistry().GetLoaderRegistry().Register("http", HTTPSchemaLoader)
+	}()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no test files in %s", dir)
+	}
+	ctx := context.Background()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cases []suiteCase
+		if err := json.Unmarshal(data, &cases); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for _, c := range cases {
+			if reason, ok := suiteSkips[filepath.Base(file)+": "+c.Description]; ok {
+				t.Logf("%s: %s: skipped, %s", filepath.Base(file), c.Description, reason)
+				continue
+			}
+			sch := &Schema{}
+			if err := json.Unmarshal(c.Schema, sch); err != nil {
+				t.Errorf("%s: %s: %v", filepath.Base(file), c.Description, err)
+				continue
+			}
+			for _, test := range c.Tests {
+				state := sch.Validate(ctx, test.Data)
+				if state.IsValid() != test.Valid {
+					t.Errorf("%s: %s: %s: expected valid %t, got errors %v", filepath.Base(file), c.Description, test.Description, test.Valid, *state.Errs)
+				}
+			}
+		}
+	}
+}
+
+func TestDraft2020_12(t *testing.T) {
+	runSuite(t, filepath.Join("testdata", "draft2020-12"))
+}
+
+func TestDraftSelection(t *testing.T) {
+	ctx := context.Background()
+	data := []interface{}{"x", "y"}
+
+	// prefixItems is unknown to draft2019_09, the default
+	sch := Must(`{"prefixItems": [{"type": "integer"}]}`)
+	if !sch.Validate(ctx, data).IsValid() {
+		t.Error("prefixItems should be ignored without $schema")
+	}
+	sch = Must(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"type": "integer"}]}`)
+	if sch.Validate(ctx, data).IsValid() {
+		t.Error("prefixItems should apply to a draft2020_12 schema")
+	}
+
+	// the array form of items is a tuple in draft2019_09 only
+	sch = Must(`{"$schema": "https://json-schema.org/draft/2019-09/schema", "items": [{"type": "string"}], "additionalItems": false}`)
+	if sch.Validate(ctx, data).IsValid() {
+		t.Error("additionalItems should apply to a draft2019_09 schema")
+	}
+
+	// nested schemas are validated with the draft of their parent
+	sch = Must(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "properties": {"list": {"prefixItems": [{"type": "integer"}]}}}`)
+	if sch.Validate(ctx, map[string]interface{}{"list": data}).IsValid() {
+		t.Error("prefixItems should apply to a schema nested in a draft2020_12 schema")
+	}
+
+	if _, ok := GetDraftKeywordRegistry(Draft2020_12 + "#"); !ok {
+		t.Error("expected the draft2020_12 registry")
+	}
+
+	// format only annotates in draft2020_12
+	sch = Must(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "format": "email"}`)
+	if !sch.Validate(ctx, "not an email").IsValid() {
+		t.Error("format should not assert in a draft2020_12 schema")
+	}
+}
+
+func TestDraft2020_12Concurrent(t *testing.T) {
+	ctx := context.Background()
+	sch := Must(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id": "https://example.com/list",
+		"$defs": {"item": {"$anchor": "item", "type": "integer"}},
+		"properties": {"list": {"prefixItems": [{"$ref": "#item"}], "items": {"$dynamicRef": "#item"}}}
+	}`)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if !sch.Validate(ctx, map[string]interface{}{"list": []interface{}{1.0, 2.0}}).IsValid() {
+					t.Error("expected a valid instance")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
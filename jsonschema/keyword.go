@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	jptr "github.com/oarkflow/pkg/jsonpointer"
@@ -34,16 +36,24 @@ type KeywordRegistry struct {
 	keywordRegistry    map[string]KeyMaker
 	keywordOrder       map[string]int
 	keywordInsertOrder map[string]int
+
+	// draft is the meta-schema URI of the draft last loaded
+	draft string
+}
+
+// NewKeywordRegistry allocates a new, empty KeywordRegistry
+func NewKeywordRegistry() *KeywordRegistry {
+	return &KeywordRegistry{
+		keywordRegistry:    make(map[string]KeyMaker, 0),
+		keywordOrder:       make(map[string]int, 0),
+		keywordInsertOrder: make(map[string]int, 0),
+	}
 }
 
 func getGlobalKeywordRegistry() (*KeywordRegistry, func()) {
 	krLock.Lock()
 	if kr == nil {
-		kr = &KeywordRegistry{
-			keywordRegistry:    make(map[string]KeyMaker, 0),
-			keywordOrder:       make(map[string]int, 0),
-			keywordInsertOrder: make(map[string]int, 0),
-		}
+		kr = NewKeywordRegistry()
 	}
 	return kr, func() { krLock.Unlock() }
 }
@@ -60,6 +70,7 @@ func (r *KeywordRegistry) Copy() *KeywordRegistry {
 		keywordRegistry:    make(map[string]KeyMaker, len(r.keywordRegistry)),
 		keywordOrder:       make(map[string]int, len(r.keywordOrder)),
 		keywordInsertOrder: make(map[string]int, len(r.keywordInsertOrder)),
+		draft:              r.draft,
 	}
 
 	for k, v := range r.keywordRegistry {
@@ -149,6 +160,121 @@ func RegisterKeyword(prop string, maker KeyMaker) {
 	r.RegisterKeyword(prop, maker)
 }
 
+// orderKeywords returns the keys of keywords registered with the registry
+// in the order they are validated in
+func (r *KeywordRegistry) orderKeywords(keywords map[string]Keyword) []string {
+	ordered := make([]string, 0, len(keywords))
+	for key := range keywords {
+		if r.IsRegisteredKeyword(key) {
+			ordered = append(ordered, key)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		oi, oj := r.GetKeywordOrder(ordered[i]), r.GetKeywordOrder(ordered[j])
+		if oi == oj {
+			return r.GetKeywordInsertOrder(ordered[i]) < r.GetKeywordInsertOrder(ordered[j])
+		}
+		return oi < oj
+	})
+	return ordered
+}
+
+var draftRegistries map[string]*KeywordRegistry
+var drLock sync.Mutex
+
+func getDraftRegistries() (map[string]*KeywordRegistry, func()) {
+	drLock.Lock()
+	if draftRegistries == nil {
+		draftRegistries = map[string]*KeywordRegistry{}
+		for uri, load := range map[string]func(*KeywordRegistry){
+			Draft2019_09: (*KeywordRegistry).LoadDraft2019_09,
+			Draft2020_12: (*KeywordRegistry).LoadDraft2020_12,
+		} {
+			r := NewKeywordRegistry()
+			load(r)
+			draftRegistries[uri] = r
+		}
+	}
+	return draftRegistries, func() { drLock.Unlock() }
+}
+
+// RegisterDraft registers the keywords of the draft identified by the
+// meta-schema uri. Schemas declaring the uri with $schema, and the schemas
+// nested in them, are validated with those keywords instead of the ones of
+// the global registry
+func RegisterDraft(uri string, r *KeywordRegistry) {
+	drafts, release := getDraftRegistries()
+	defer release()
+
+	drafts[strings.TrimRight(uri, "#")] = r
+}
+
+// GetDraftKeywordRegistry returns a copy of the keyword registry of the draft
+// identified by the meta-schema uri
+func GetDraftKeywordRegistry(uri string) (*KeywordRegistry, bool) {
+	drafts, release := getDraftRegistries()
+	defer release()
+
+	r, ok := drafts[strings.TrimRight(uri, "#")]
+	if !ok {
+		return nil, false
+	}
+	return r.Copy(), true
+}
+
+// draftKeywordRegistry returns the registry for schemas declaring the
+// meta-schema uri: the keywords of the draft, plus the custom keywords of the
+// global registry, that is the ones no draft registers
+func draftKeywordRegistry(uri string, global *KeywordRegistry) (*KeywordRegistry, bool) {
+	drafts, release := getDraftRegistries()
+	defer release()
+
+	draft, ok := drafts[strings.TrimRight(uri, "#")]
+	if !ok {
+		return nil, false
+	}
+	r := draft.Copy()
+	for prop, maker := range global.keywordRegistry {
+		if isDraftKeyword(drafts, prop) {
+			continue
+		}
+		r.RegisterKeyword(prop, maker)
+		if order, ok := global.keywordOrder[prop]; ok {
+			r.SetKeywordOrder(prop, order)
+		}
+	}
+	return r, true
+}
+
+// draftKeywordMaker returns the maker of a keyword of any draft, so schemas
+// are parsed with the keywords of all drafts before knowing which one
+// applies to them
+func draftKeywordMaker(prop string) (KeyMaker, bool) {
+	drafts, release := getDraftRegistries()
+	defer release()
+
+	for _, uri := range []string{Draft2020_12, Draft2019_09} {
+		if r, ok := drafts[uri]; ok && r.IsRegisteredKeyword(prop) {
+			return r.keywordRegistry[prop], true
+		}
+	}
+	for _, r := range drafts {
+		if r.IsRegisteredKeyword(prop) {
+			return r.keywordRegistry[prop], true
+		}
+	}
+	return nil, false
+}
+
+func isDraftKeyword(drafts map[string]*KeywordRegistry, prop string) bool {
+	for _, r := range drafts {
+		if r.IsRegisteredKeyword(prop) {
+			return true
+		}
+	}
+	return false
+}
+
 // MaxKeywordErrStringLen sets how long a value can be before it's length is truncated
 // when printing error strings
 // a special value of -1 disables output trimming
@@ -55,10 +55,15 @@ func (it Items) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 	schemaDebug("[Items] Validating")
 	if arr, ok := data.([]interface{}); ok {
 		if it.single {
+			// items following prefixItems only apply to the rest of the array
+			start, _ := currentState.Misc["prefixItemsCount"].(int)
 			subState := currentState.NewSubState()
 			subState.DescendBase("items")
 			subState.DescendRelative("items")
 			for i, elem := range arr {
+				if i < start {
+					continue
+				}
 				subState.ClearState()
 				subState.DescendInstanceFromState(currentState, strconv.Itoa(i))
 				it.Schemas[0].ValidateKeyword(ctx, subState, elem)
@@ -67,6 +72,9 @@ func (it Items) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 				// Properties/Items, should separate out
 				currentState.UpdateEvaluatedPropsAndItems(subState)
 			}
+			if start < len(arr) {
+				currentState.AddAnnotation(true)
+			}
 		} else {
 			subState := currentState.NewSubState()
 			subState.DescendBase("items")
@@ -81,10 +89,21 @@ func (it Items) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 					currentState.UpdateEvaluatedPropsAndItems(subState)
 				}
 			}
+			addItemsAnnotation(currentState, len(it.Schemas), len(arr))
 		}
 	}
 }
 
+// addItemsAnnotation annotates the evaluation of the first n items of an
+// array of length l with the largest index evaluated, or true for all items
+func addItemsAnnotation(currentState *ValidationState, n, l int) {
+	if n >= l {
+		currentState.AddAnnotation(true)
+	} else if n > 0 {
+		currentState.AddAnnotation(n - 1)
+	}
+}
+
 // JSONProp implements the JSONPather for Items
 func (it Items) JSONProp(name string) interface{} {
 	idx, err := strconv.Atoi(name)
@@ -129,6 +148,86 @@ func (it Items) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]*Schema(it.Schemas))
 }
 
+// PrefixItems defines the prefixItems JSON Schema keyword
+type PrefixItems []*Schema
+
+// NewPrefixItems allocates a new PrefixItems keyword
+func NewPrefixItems() Keyword {
+	return &PrefixItems{}
+}
+
+// Register implements the Keyword interface for PrefixItems
+func (p *PrefixItems) Register(uri string, registry *SchemaRegistry) {
+	for _, v := range *p {
+		v.Register(uri, registry)
+	}
+}
+
+// Resolve implements the Keyword interface for PrefixItems
+func (p *PrefixItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	if pointer == nil {
+		return nil
+	}
+	current := pointer.Head()
+	if current == nil {
+		return nil
+	}
+
+	pos, err := strconv.Atoi(*current)
+	if err != nil {
+		return nil
+	}
+
+	if pos < 0 || pos >= len(*p) {
+		return nil
+	}
+
+	return (*p)[pos].Resolve(pointer.Tail(), uri)
+}
+
+// ValidateKeyword implements the Keyword interface for PrefixItems
+func (p PrefixItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[PrefixItems] Validating")
+	if arr, ok := data.([]interface{}); ok {
+		subState := currentState.NewSubState()
+		subState.DescendBase("prefixItems")
+		for i, vs := range p {
+			if i < len(arr) {
+				subState.ClearState()
+				subState.DescendRelativeFromState(currentState, "prefixItems", strconv.Itoa(i))
+				subState.DescendInstanceFromState(currentState, strconv.Itoa(i))
+
+				vs.ValidateKeyword(ctx, subState, arr[i])
+				subState.SetEvaluatedIndex(i)
+				currentState.UpdateEvaluatedPropsAndItems(subState)
+			}
+		}
+		currentState.Misc["prefixItemsCount"] = len(p)
+		addItemsAnnotation(currentState, len(p), len(arr))
+	}
+}
+
+// JSONProp implements the JSONPather for PrefixItems
+func (p PrefixItems) JSONProp(name string) interface{} {
+	idx, err := strconv.Atoi(name)
+	if err != nil {
+		return nil
+	}
+	if idx >= len(p) || idx < 0 {
+		return nil
+	}
+	return p[idx]
+}
+
+// JSONChildren implements the JSONContainer interface for PrefixItems
+func (p PrefixItems) JSONChildren() (res map[string]JSONPather) {
+	res = map[string]JSONPather{}
+	for i, sch := range p {
+		res[strconv.Itoa(i)] = sch
+	}
+	return
+}
+
 // MaxItems defines the maxItems JSON Schema keyword
 type MaxItems int
 
@@ -202,6 +301,9 @@ func (u *UniqueItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for UniqueItems
 func (u UniqueItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[UniqueItems] Validating")
+	if !u {
+		return
+	}
 	if arr, ok := data.([]interface{}); ok {
 		found := []interface{}{}
 		for _, elem := range arr {
@@ -241,7 +343,8 @@ func (c *Contains) ValidateKeyword(ctx context.Context, currentState *Validation
 	if arr, ok := data.([]interface{}); ok {
 		valid := false
 		matchCount := 0
-		subState := currentState.NewSubState()
+		matched := []int{}
+		subState := currentState.NewSubState().speculative()
 		subState.ClearState()
 		subState.DescendBase("contains")
 		subState.DescendRelative("contains")
@@ -253,10 +356,15 @@ func (c *Contains) ValidateKeyword(ctx context.Context, currentState *Validation
 			if subState.IsValid() {
 				valid = true
 				matchCount++
+				matched = append(matched, i)
+				(*currentState.EvaluatedItemIndexes)[i] = true
 			}
 		}
 		if valid {
 			currentState.Misc["containsCount"] = matchCount
+			currentState.AddAnnotation(matched)
+		} else if min, ok := currentState.Local.keywords["minContains"].(*MinContains); ok && *min == 0 {
+			currentState.Misc["containsCount"] = 0
 		} else {
 			currentState.AddError(data, fmt.Sprintf("must contain at least one of: %v", c))
 		}
@@ -377,6 +485,7 @@ func (ai *AdditionalItems) ValidateKeyword(ctx context.Context, currentState *Va
 				(*Schema)(ai).ValidateKeyword(ctx, subState, arr[i])
 				currentState.UpdateEvaluatedPropsAndItems(subState)
 			}
+			currentState.AddAnnotation(true)
 		}
 	}
 }
@@ -413,8 +522,13 @@ func (ui *UnevaluatedItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (ui *UnevaluatedItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[UnevaluatedItems] Validating")
 	if arr, ok := data.([]interface{}); ok {
+		// since draft2020_12 the items matching contains count as evaluated
+		containsEvaluates := currentState.draft() == Draft2020_12
 		if currentState.LastEvaluatedIndex < len(arr) {
 			for i := currentState.LastEvaluatedIndex + 1; i < len(arr); i++ {
+				if containsEvaluates && (*currentState.EvaluatedItemIndexes)[i] {
+					continue
+				}
 				if ui.schemaType == schemaTypeFalse {
 					currentState.AddError(data, "unevaluated items are not allowed")
 					return
@@ -427,6 +541,8 @@ func (ui *UnevaluatedItems) ValidateKeyword(ctx context.Context, currentState *V
 
 				(*Schema)(ui).ValidateKeyword(ctx, subState, arr[i])
 			}
+			currentState.SetEvaluatedIndex(len(arr) - 1)
+			currentState.AddAnnotation(true)
 		}
 	}
 }
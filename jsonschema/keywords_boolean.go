@@ -130,8 +130,11 @@ func (a *AnyOf) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for AnyOf
 func (a *AnyOf) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[AnyOf] Validating")
+	// every subschema is evaluated, for the properties and items the
+	// matching ones evaluate
+	matched := false
 	for i, sch := range *a {
-		subState := currentState.NewSubState()
+		subState := currentState.NewSubState().speculative()
 		subState.ClearState()
 		subState.DescendBase("anyOf", strconv.Itoa(i))
 		subState.DescendRelative("anyOf", strconv.Itoa(i))
@@ -139,11 +142,13 @@ func (a *AnyOf) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 		sch.ValidateKeyword(ctx, subState, data)
 		if subState.IsValid() {
 			currentState.UpdateEvaluatedPropsAndItems(subState)
-			return
+			matched = true
 		}
 	}
 
-	currentState.AddError(data, "did Not match any specified AnyOf schemas")
+	if !matched {
+		currentState.AddError(data, "did Not match any specified AnyOf schemas")
+	}
 }
 
 // JSONProp implements the JSONPather for AnyOf
@@ -211,14 +216,14 @@ func (o *OneOf) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 	stateCopy := currentState.NewSubState()
 	stateCopy.ClearState()
 	for i, sch := range *o {
-		subState := currentState.NewSubState()
+		subState := currentState.NewSubState().speculative()
 		subState.ClearState()
 		subState.DescendBase("oneOf", strconv.Itoa(i))
 		subState.DescendRelative("oneOf", strconv.Itoa(i))
 		subState.Errs = &[]KeyError{}
 		sch.ValidateKeyword(ctx, subState, data)
-		stateCopy.UpdateEvaluatedPropsAndItems(subState)
 		if subState.IsValid() {
+			stateCopy.UpdateEvaluatedPropsAndItems(subState)
 			if matched {
 				currentState.AddError(data, "matched more than one specified OneOf schemas")
 				return
@@ -275,7 +280,8 @@ func (n *Not) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for Not
 func (n *Not) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Not] Validating")
-	subState := currentState.NewSubState()
+	subState := currentState.NewSubState().speculative()
+	subState.ClearState()
 	subState.DescendBase("not")
 	subState.DescendRelative("not")
 
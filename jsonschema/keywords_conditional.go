@@ -28,16 +28,9 @@ func (f *If) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for If
 func (f *If) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[If] Validating")
-	thenKW := currentState.Local.keywords["then"]
-	elseKW := currentState.Local.keywords["else"]
-
-	if thenKW == nil && elseKW == nil {
-		// no then or else for if, aborting validation
-		schemaDebug("[If] Aborting validation as no then or else is present")
-		return
-	}
-
-	subState := currentState.NewSubState()
+	// if is evaluated even without then or else, for the properties and
+	// items it evaluates
+	subState := currentState.NewSubState().speculative()
 	subState.ClearState()
 	subState.DescendBase("if")
 	subState.DescendRelative("if")
@@ -47,6 +40,9 @@ func (f *If) ValidateKeyword(ctx context.Context, currentState *ValidationState,
 	sch.ValidateKeyword(ctx, subState, data)
 
 	currentState.Misc["ifResult"] = subState.IsValid()
+	if subState.IsValid() {
+		currentState.UpdateEvaluatedPropsAndItems(subState)
+	}
 }
 
 // JSONProp implements the JSONPather for If
@@ -178,6 +174,7 @@ func (e *Else) ValidateKeyword(ctx context.Context, currentState *ValidationStat
 
 	sch := Schema(*e)
 	sch.ValidateKeyword(ctx, subState, data)
+	currentState.UpdateEvaluatedPropsAndItems(subState)
 }
 
 // JSONProp implements the JSONPather for Else
@@ -64,6 +64,7 @@ func NewDescription() Keyword {
 // ValidateKeyword implements the Keyword interface for Description
 func (d *Description) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Description] Validating")
+	currentState.AddAnnotation(string(*d))
 }
 
 // Register implements the Keyword interface for Description
@@ -85,6 +86,7 @@ func NewTitle() Keyword {
 // ValidateKeyword implements the Keyword interface for Title
 func (t *Title) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Title] Validating")
+	currentState.AddAnnotation(string(*t))
 }
 
 // Register implements the Keyword interface for Title
@@ -129,6 +131,7 @@ func NewDefault() Keyword {
 // ValidateKeyword implements the Keyword interface for Default
 func (d *Default) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Default] Validating")
+	currentState.AddAnnotation(d.data)
 }
 
 // Register implements the Keyword interface for Default
@@ -162,6 +165,7 @@ func NewExamples() Keyword {
 // ValidateKeyword implements the Keyword interface for Examples
 func (e *Examples) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Examples] Validating")
+	currentState.AddAnnotation([]interface{}(*e))
 }
 
 // Register implements the Keyword interface for Examples
@@ -183,6 +187,7 @@ func NewReadOnly() Keyword {
 // ValidateKeyword implements the Keyword interface for ReadOnly
 func (r *ReadOnly) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[ReadOnly] Validating")
+	currentState.AddAnnotation(bool(*r))
 }
 
 // Register implements the Keyword interface for ReadOnly
@@ -204,6 +209,7 @@ func NewWriteOnly() Keyword {
 // ValidateKeyword implements the Keyword interface for WriteOnly
 func (w *WriteOnly) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[WriteOnly] Validating")
+	currentState.AddAnnotation(bool(*w))
 }
 
 // Register implements the Keyword interface for WriteOnly
@@ -231,31 +237,57 @@ func NewRef() Keyword {
 // ValidateKeyword implements the Keyword interface for Ref
 func (r *Ref) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Ref] Validating")
+	resolved, root, fragment := r.resolve(ctx, currentState)
+	if resolved == nil {
+		currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", r.reference))
+	}
+
+	validateRef(ctx, currentState, data, "$ref", resolved, root, fragment)
+}
+
+// resolve resolves the reference on first use and returns the schema it
+// points to, the schema resource holding it and the fragment within it
+func (r *Ref) resolve(ctx context.Context, currentState *ValidationState) (*Schema, *Schema, *jptr.Pointer) {
+	resolveLock.Lock()
+	defer resolveLock.Unlock()
 	if r.resolved == nil {
 		r._resolveRef(ctx, currentState)
-		if r.resolved == nil {
-			currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", r.reference))
-		}
 	}
+	return r.resolved, r.resolvedRoot, r.resolvedFragment
+}
 
+// validateRef validates data against the schema a reference resolved to,
+// root being the schema resource holding it
+func validateRef(ctx context.Context, currentState *ValidationState, data interface{}, keyword string, resolved, root *Schema, fragment *jptr.Pointer) {
 	subState := currentState.NewSubState()
 	subState.ClearState()
-	if r.resolvedRoot != nil {
-		subState.BaseURI = r.resolvedRoot.docPath
-		subState.Root = r.resolvedRoot
+	if root != nil {
+		subState.BaseURI = root.docPath
+		subState.Root = root
+		subState.enterResource(root)
 	}
-	if r.resolvedFragment != nil && !r.resolvedFragment.IsEmpty() {
-		subState.BaseRelativeLocation = r.resolvedFragment
+	if fragment != nil && !fragment.IsEmpty() {
+		subState.BaseRelativeLocation = fragment
 	}
-	subState.DescendRelative("$ref")
+	subState.DescendRelative(keyword)
 
-	r.resolved.ValidateKeyword(ctx, subState, data)
+	resolved.ValidateKeyword(ctx, subState, data)
 
 	currentState.UpdateEvaluatedPropsAndItems(subState)
 }
 
 // _resolveRef attempts to resolve the reference from the top-level context
 func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
+	if strings.HasPrefix(r.reference, "#") && !strings.HasPrefix(r.reference, "#/") {
+		// plain name fragments name an anchor of the current schema resource
+		if n := len(currentState.DynamicScope); n > 0 {
+			if a, ok := currentState.DynamicScope[n-1].anchors()[r.reference[1:]]; ok {
+				r.resolved = a.schema
+				return
+			}
+		}
+	}
+
 	if IsLocalSchemaID(r.reference) {
 		r.resolved = currentState.LocalRegistry.GetLocal(r.reference)
 		if r.resolved != nil {
@@ -266,6 +298,7 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 	docPath := currentState.BaseURI
 	refParts := strings.Split(r.reference, "#")
 	address := ""
+	anchorName := ""
 	if refParts != nil && len(strings.TrimSpace(refParts[0])) > 0 {
 		address = refParts[0]
 	} else if docPath != "" {
@@ -275,6 +308,7 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 	if len(refParts) > 1 {
 		frag := refParts[1]
 		if len(frag) > 0 && frag[0] != '/' {
+			anchorName = frag
 			frag = "/" + frag
 			r.fragmentLocalized = true
 		}
@@ -291,18 +325,11 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 	if address != "" {
 		if u, err := url.Parse(address); err == nil {
 			if !u.IsAbs() {
-				address = currentState.Local.id + address
+				// the base URI already accounts for the $id of the schema
 				if docPath != "" {
-					uriFolder := ""
-					if docPath[len(docPath)-1] == '/' {
-						uriFolder = docPath
-					} else {
-						corePath := strings.Split(docPath, "#")[0]
-						pathComponents := strings.Split(corePath, "/")
-						pathComponents = pathComponents[:len(pathComponents)-1]
-						uriFolder = strings.Join(pathComponents, "/") + "/"
-					}
-					address, _ = SafeResolveURL(uriFolder, address)
+					address, _ = SafeResolveURL(docPath, address)
+				} else {
+					address = currentState.Local.id + address
 				}
 			}
 		}
@@ -315,6 +342,13 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 		return
 	}
 
+	if anchorName != "" {
+		if a, ok := r.resolvedRoot.anchors()[anchorName]; ok {
+			r.resolved = a.schema
+			return
+		}
+	}
+
 	knownSchema := currentState.TopSchemaRegistry.GetKnown(r.reference)
 	if knownSchema != nil {
 		r.resolved = knownSchema
@@ -374,6 +408,49 @@ func (r Ref) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r.reference)
 }
 
+// DynamicRef defines the $dynamicRef JSON Schema keyword
+type DynamicRef struct {
+	Ref
+}
+
+// NewDynamicRef allocates a new DynamicRef keyword
+func NewDynamicRef() Keyword {
+	return new(DynamicRef)
+}
+
+// ValidateKeyword implements the Keyword interface for DynamicRef
+func (d *DynamicRef) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[DynamicRef] Validating")
+	resolved, root, fragment := d.resolveDynamic(ctx, currentState)
+	if resolved == nil {
+		currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", d.reference))
+		return
+	}
+
+	validateRef(ctx, currentState, data, "$dynamicRef", resolved, root, fragment)
+}
+
+// resolveDynamic resolves the reference like resolve, then looks the
+// dynamic anchor it names up in the dynamic scope of the state
+func (d *DynamicRef) resolveDynamic(ctx context.Context, currentState *ValidationState) (*Schema, *Schema, *jptr.Pointer) {
+	resolved, root, fragment := d.resolve(ctx, currentState)
+	if resolved == nil {
+		return nil, nil, nil
+	}
+
+	// a reference to a $dynamicAnchor resolves to the outermost schema
+	// resource of the dynamic scope with a $dynamicAnchor of the same name
+	if name, ok := resolved.keywords["$dynamicAnchor"].(*DynamicAnchor); ok && d.reference[strings.Index(d.reference, "#")+1:] == string(*name) {
+		for _, resource := range currentState.DynamicScope {
+			if a, ok := resource.anchors()[string(*name)]; ok && a.dynamic {
+				resolved, root = a.schema, resource
+				break
+			}
+		}
+	}
+	return resolved, root, fragment
+}
+
 // RecursiveRef defines the $recursiveRef JSON Schema keyword
 type RecursiveRef struct {
 	reference        string
@@ -397,11 +474,13 @@ func (r *RecursiveRef) ValidateKeyword(ctx context.Context, currentState *Valida
 		return
 	}
 
+	resolveLock.Lock()
 	if r.resolved == nil {
 		r._resolveRef(ctx, currentState)
-		if r.resolved == nil {
-			currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", r.reference))
-		}
+	}
+	resolveLock.Unlock()
+	if r.resolved == nil {
+		currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", r.reference))
 	}
 
 	subState := currentState.NewSubState()
@@ -582,6 +661,27 @@ func (a *Anchor) Resolve(pointer jptr.Pointer, uri string) *Schema {
 	return nil
 }
 
+// DynamicAnchor defines the $dynamicAnchor JSON Schema keyword
+type DynamicAnchor string
+
+// NewDynamicAnchor allocates a new DynamicAnchor keyword
+func NewDynamicAnchor() Keyword {
+	return new(DynamicAnchor)
+}
+
+// ValidateKeyword implements the Keyword interface for DynamicAnchor
+func (a *DynamicAnchor) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[DynamicAnchor] Validating")
+}
+
+// Register implements the Keyword interface for DynamicAnchor
+func (a *DynamicAnchor) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for DynamicAnchor
+func (a *DynamicAnchor) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
 // RecursiveAnchor defines the $recursiveAnchor JSON Schema keyword
 type RecursiveAnchor Schema
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 
 	jptr "github.com/oarkflow/pkg/jsonpointer"
@@ -47,8 +48,10 @@ func (p Properties) ValidateKeyword(ctx context.Context, currentState *Validatio
 	schemaDebug("[Properties] Validating")
 	if obj, ok := data.(map[string]interface{}); ok {
 		subState := currentState.NewSubState()
+		evaluated := []string{}
 		for key := range p {
 			if _, ok := obj[key]; ok {
+				evaluated = append(evaluated, key)
 				currentState.SetEvaluatedKey(key)
 				subState.ClearState()
 				subState.DescendBaseFromState(currentState, "properties", key)
@@ -63,6 +66,8 @@ func (p Properties) ValidateKeyword(ctx context.Context, currentState *Validatio
 				}
 			}
 		}
+		sort.Strings(evaluated)
+		currentState.AddAnnotation(evaluated)
 	}
 }
 
@@ -219,9 +224,11 @@ func (p *PatternProperties) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (p PatternProperties) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[PatternProperties] Validating")
 	if obj, ok := data.(map[string]interface{}); ok {
+		evaluated := map[string]bool{}
 		for key, val := range obj {
 			for _, ptn := range p {
 				if ptn.re.Match([]byte(key)) {
+					evaluated[key] = true
 					currentState.SetEvaluatedKey(key)
 					subState := currentState.NewSubState()
 					subState.DescendBase("patternProperties", key)
@@ -238,6 +245,7 @@ func (p PatternProperties) ValidateKeyword(ctx context.Context, currentState *Va
 				}
 			}
 		}
+		currentState.AddAnnotation(sortedKeys(evaluated))
 	}
 }
 
@@ -321,10 +329,15 @@ func (ap *AdditionalProperties) ValidateKeyword(ctx context.Context, currentStat
 		subState.ClearState()
 		subState.DescendBase("additionalProperties")
 		subState.DescendRelative("additionalProperties")
+		evaluated := map[string]bool{}
 		for key := range obj {
 			if currentState.IsLocallyEvaluatedKey(key) {
 				continue
 			}
+			if m := currentState.mutation; m != nil && m.additional(ctx, currentState, ap, obj, key) {
+				continue
+			}
+			evaluated[key] = true
 
 			currentState.SetEvaluatedKey(key)
 			subState.ClearState()
@@ -338,6 +351,7 @@ func (ap *AdditionalProperties) ValidateKeyword(ctx context.Context, currentStat
 			(*Schema)(ap).ValidateKeyword(ctx, subState, obj[key])
 			currentState.UpdateEvaluatedPropsAndItems(subState)
 		}
+		currentState.AddAnnotation(sortedKeys(evaluated))
 	}
 }
 
@@ -379,7 +393,7 @@ func (p *PropertyNames) ValidateKeyword(ctx context.Context, currentState *Valid
 	schemaDebug("[PropertyNames] Validating")
 	if obj, ok := data.(map[string]interface{}); ok {
 		for key := range obj {
-			subState := currentState.NewSubState()
+			subState := currentState.NewSubState().speculative()
 			subState.DescendBase("propertyNames")
 			subState.DescendRelative("propertyNames")
 			subState.DescendInstance(key)
@@ -671,6 +685,7 @@ func (up *UnevaluatedProperties) ValidateKeyword(ctx context.Context, currentSta
 		subState.ClearState()
 		subState.DescendBase("unevaluatedProperties")
 		subState.DescendRelative("unevaluatedProperties")
+		evaluated := map[string]bool{}
 		for key := range obj {
 			if currentState.IsEvaluatedKey(key) {
 				continue
@@ -679,10 +694,17 @@ func (up *UnevaluatedProperties) ValidateKeyword(ctx context.Context, currentSta
 				currentState.AddError(data, "unevaluated properties are not allowed")
 				return
 			}
+			evaluated[key] = true
 			subState.DescendInstanceFromState(currentState, key)
 
 			(*Schema)(up).ValidateKeyword(ctx, subState, obj[key])
 		}
+		// the properties are evaluated for the unevaluatedProperties of
+		// the schemas applying this one too
+		for key := range evaluated {
+			currentState.SetEvaluatedKey(key)
+		}
+		currentState.AddAnnotation(sortedKeys(evaluated))
 	}
 }
 
@@ -54,6 +54,7 @@ func (f *Format) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for Format
 func (f Format) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Format] Validating")
+	currentState.AddAnnotation(string(f))
 	var err error
 	if str, ok := data.(string); ok {
 		switch f {
@@ -102,6 +103,30 @@ func (f Format) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 	}
 }
 
+// FormatAnnotation defines the format JSON Schema keyword of the
+// format-annotation vocabulary of draft2020_12, which annotates the
+// instance with the format without asserting it
+type FormatAnnotation string
+
+// NewFormatAnnotation allocates a new FormatAnnotation keyword
+func NewFormatAnnotation() Keyword {
+	return new(FormatAnnotation)
+}
+
+// Register implements the Keyword interface for FormatAnnotation
+func (f *FormatAnnotation) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for FormatAnnotation
+func (f *FormatAnnotation) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for FormatAnnotation
+func (f FormatAnnotation) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[FormatAnnotation] Validating")
+	currentState.AddAnnotation(string(f))
+}
+
 // A string instance is valid against "date-time" if it is a valid
 // representation according to the "date-time" production derived
 // from RFC 3339, section 5.6 [RFC3339]
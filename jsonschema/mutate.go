@@ -0,0 +1,362 @@
+package jsonschema
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// MutationOptions selects the changes ValidateAndMutate makes to the instance
+type MutationOptions struct {
+	// ApplyDefaults sets the missing properties of objects to the default of
+	// their schema
+	ApplyDefaults bool
+	// CoerceTypes converts scalars to the type of their schema, when it
+	// doesn't allow the type they have: "42" to 42, "true" to true, "" to
+	// null, and numbers and booleans to strings
+	CoerceTypes bool
+	// RemoveAdditional deletes the properties additionalProperties: false
+	// rejects
+	RemoveAdditional bool
+}
+
+// ModificationKind is the kind of change made to the instance
+type ModificationKind string
+
+const (
+	// ModificationDefault is a missing property set to its default
+	ModificationDefault ModificationKind = "default"
+	// ModificationCoerce is a value converted to the type of its schema
+	ModificationCoerce ModificationKind = "coerce"
+	// ModificationRemove is an additional property deleted
+	ModificationRemove ModificationKind = "remove"
+)
+
+// Modification is a change ValidateAndMutate made to the instance
+type Modification struct {
+	Kind ModificationKind `json:"kind"`
+	// InstanceLocation is the JSON pointer of the changed value
+	InstanceLocation string      `json:"instanceLocation"`
+	OldValue         interface{} `json:"oldValue,omitempty"`
+	NewValue         interface{} `json:"newValue,omitempty"`
+}
+
+// MutationResult is the result of ValidateAndMutate
+type MutationResult struct {
+	// Data is the changed instance. Objects and arrays are changed in place,
+	// scalars at the root are replaced
+	Data          interface{}
+	Errs          []KeyError
+	Modifications []Modification
+}
+
+// IsValid returns if the changed instance is valid
+func (r *MutationResult) IsValid() bool {
+	return len(r.Errs) == 0
+}
+
+// ValidateAndMutate validates data like Validate, changing it first as
+// selected by opts. The changes are made before each schema validates its
+// part of the instance, but not by the subschemas that may fail without
+// failing the instance (anyOf, oneOf, not, if, contains and propertyNames)
+func (s *Schema) ValidateAndMutate(ctx context.Context, data interface{}, opts MutationOptions) *MutationResult {
+	m := &mutation{MutationOptions: opts, mutated: map[mutationTarget]bool{}}
+	currentState := NewValidationState(s)
+	if opts.CoerceTypes {
+		data = m.coerce(ctx, currentState, s, data, "")
+	}
+	currentState.mutation = m
+	s.ValidateKeyword(ctx, currentState, data)
+	return &MutationResult{
+		Data:          data,
+		Errs:          *currentState.Errs,
+		Modifications: m.modifications,
+	}
+}
+
+// mutation holds the options and the changes of ValidateAndMutate
+type mutation struct {
+	MutationOptions
+	modifications []Modification
+	// mutated holds the schemas that changed the instance at a location, so
+	// the subschemas of allOf, changed ahead of their validation, are not
+	// changed again
+	mutated map[mutationTarget]bool
+}
+
+type mutationTarget struct {
+	schema   *Schema
+	location string
+}
+
+// record adds a change to the modifications, with copies of the values so
+// later changes to the instance don't show in it
+func (m *mutation) record(kind ModificationKind, location string, oldValue, newValue interface{}) {
+	m.modifications = append(m.modifications, Modification{
+		Kind:             kind,
+		InstanceLocation: location,
+		OldValue:         copyValue(oldValue),
+		NewValue:         copyValue(newValue),
+	})
+}
+
+// speculative disables the changes to the instance in a sub state, which
+// evaluates a subschema that may fail without failing the schema
+func (vs *ValidationState) speculative() *ValidationState {
+	vs.mutation = nil
+	return vs
+}
+
+// mutate changes the object properties or the array items of data as
+// selected by the mutation of the state, before s validates data. The
+// subschemas of allOf always apply, so their changes are made first. The
+// additional properties are changed by additionalProperties, which knows
+// the properties the other keywords evaluated
+func (s *Schema) mutate(ctx context.Context, currentState *ValidationState, data interface{}) {
+	m := currentState.mutation
+	target := mutationTarget{s, currentState.InstanceLocation.String()}
+	if m.mutated[target] {
+		return
+	}
+	m.mutated[target] = true
+	if allOf, ok := s.keywords["allOf"].(*AllOf); ok {
+		for _, sch := range *allOf {
+			if sch != nil && sch.schemaType == schemaTypeObject {
+				sch.mutate(ctx, currentState, data)
+			}
+		}
+	}
+
+	switch instance := data.(type) {
+	case map[string]interface{}:
+		properties, _ := s.keywords["properties"].(*Properties)
+		patterns, _ := s.keywords["patternProperties"].(*PatternProperties)
+
+		if properties != nil && m.ApplyDefaults {
+			for _, key := range sortedKeys(*properties) {
+				if _, ok := instance[key]; ok {
+					continue
+				}
+				if def := defaultOf(ctx, currentState, (*properties)[key]); def != nil {
+					instance[key] = copyValue(def.data)
+					m.record(ModificationDefault, currentState.InstanceLocation.RawDescendant(key).String(), nil, instance[key])
+				}
+			}
+		}
+
+		for _, key := range sortedKeys(instance) {
+			location := currentState.InstanceLocation.RawDescendant(key).String()
+			var schemas []*Schema
+			if properties != nil {
+				if sch, ok := (*properties)[key]; ok {
+					schemas = append(schemas, sch)
+				}
+			}
+			if patterns != nil {
+				for _, ptn := range *patterns {
+					if ptn.re.MatchString(key) {
+						schemas = append(schemas, ptn.schema)
+					}
+				}
+			}
+			if m.CoerceTypes {
+				for _, sch := range schemas {
+					instance[key] = m.coerce(ctx, currentState, sch, instance[key], location)
+				}
+			}
+		}
+
+	case []interface{}:
+		if !m.CoerceTypes {
+			return
+		}
+		prefix := 0
+		if prefixItems, ok := s.keywords["prefixItems"].(*PrefixItems); ok {
+			prefix = len(*prefixItems)
+			for i, sch := range *prefixItems {
+				if i < len(instance) {
+					instance[i] = m.coerce(ctx, currentState, sch, instance[i], currentState.InstanceLocation.RawDescendant(strconv.Itoa(i)).String())
+				}
+			}
+		}
+		if items, ok := s.keywords["items"].(*Items); ok {
+			for i := range instance {
+				sch := items.Schemas[0]
+				if !items.single {
+					if i >= len(items.Schemas) {
+						break
+					}
+					sch = items.Schemas[i]
+				} else if i < prefix {
+					continue
+				}
+				instance[i] = m.coerce(ctx, currentState, sch, instance[i], currentState.InstanceLocation.RawDescendant(strconv.Itoa(i)).String())
+			}
+		}
+	}
+}
+
+// additional changes the property key of obj, which additionalProperties ap
+// applies to: it removes the property when ap allows none, and coerces it to
+// the type of ap otherwise. It returns if the property was removed
+func (m *mutation) additional(ctx context.Context, currentState *ValidationState, ap *AdditionalProperties, obj map[string]interface{}, key string) bool {
+	location := currentState.InstanceLocation.RawDescendant(key).String()
+	if ap.schemaType == schemaTypeFalse {
+		if !m.RemoveAdditional {
+			return false
+		}
+		m.record(ModificationRemove, location, obj[key], nil)
+		delete(obj, key)
+		return true
+	}
+	if m.CoerceTypes {
+		obj[key] = m.coerce(ctx, currentState, (*Schema)(ap), obj[key], location)
+	}
+	return false
+}
+
+// coerce converts the scalar value to a type sch allows, when it doesn't
+// allow the type value has
+func (m *mutation) coerce(ctx context.Context, currentState *ValidationState, sch *Schema, value interface{}, location string) interface{} {
+	types := schemaTypes(ctx, currentState, sch, map[*Schema]bool{})
+	if len(types) == 0 {
+		return value
+	}
+	dataType := DataType(value)
+	for _, typ := range types {
+		if typ == dataType || typ == "number" && dataType == "integer" {
+			return value
+		}
+	}
+	for _, typ := range types {
+		if coerced, ok := coerceValue(value, typ); ok {
+			m.record(ModificationCoerce, location, value, coerced)
+			return coerced
+		}
+	}
+	return value
+}
+
+// schemaTypes returns the types sch, the subschemas of its allOf and the
+// schema its reference resolves to allow. seen holds the schemas already
+// visited, so circular references end
+func schemaTypes(ctx context.Context, currentState *ValidationState, sch *Schema, seen map[*Schema]bool) []string {
+	if sch == nil || sch.schemaType != schemaTypeObject || seen[sch] {
+		return nil
+	}
+	seen[sch] = true
+	var types []string
+	if t, ok := sch.keywords["type"].(*Type); ok {
+		types = append(types, t.vals...)
+	}
+	if allOf, ok := sch.keywords["allOf"].(*AllOf); ok {
+		for _, sub := range *allOf {
+			types = append(types, schemaTypes(ctx, currentState, sub, seen)...)
+		}
+	}
+	if ref, refState := referenced(ctx, currentState, sch); ref != nil {
+		types = append(types, schemaTypes(ctx, refState, ref, seen)...)
+	}
+	return types
+}
+
+// defaultOf returns the default of sch, or of the schema its reference
+// resolves to when sch has none
+func defaultOf(ctx context.Context, currentState *ValidationState, sch *Schema) *Default {
+	for seen := map[*Schema]bool{}; sch != nil && !seen[sch]; {
+		seen[sch] = true
+		if def, ok := sch.keywords["default"].(*Default); ok {
+			return def
+		}
+		sch, currentState = referenced(ctx, currentState, sch)
+	}
+	return nil
+}
+
+// referenced returns the schema the $ref or the $dynamicRef of sch resolves
+// to, with the state it resolved in, or nil when sch has no reference. The
+// reference is resolved in the scope sch is validated in
+func referenced(ctx context.Context, currentState *ValidationState, sch *Schema) (*Schema, *ValidationState) {
+	ref, _ := sch.keywords["$ref"].(*Ref)
+	dynamicRef, _ := sch.keywords["$dynamicRef"].(*DynamicRef)
+	if ref == nil && dynamicRef == nil {
+		return nil, nil
+	}
+
+	subState := currentState.NewSubState()
+	resolveLock.Lock()
+	sch.Register("", subState.LocalRegistry)
+	resolveLock.Unlock()
+	subState.enterScope(sch)
+
+	var resolved, root *Schema
+	if ref != nil {
+		resolved, root, _ = ref.resolve(ctx, subState)
+	} else {
+		resolved, root, _ = dynamicRef.resolveDynamic(ctx, subState)
+	}
+	if resolved == nil {
+		return nil, nil
+	}
+	if root != nil {
+		subState.BaseURI = root.docPath
+		subState.Root = root
+		subState.enterResource(root)
+	}
+	return resolved, subState
+}
+
+// coerceValue converts a scalar to the JSON type typ, the way the value
+// would have been decoded had it been sent with that type
+func coerceValue(value interface{}, typ string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		switch typ {
+		case "integer":
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return float64(i), true
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, true
+			}
+		case "boolean":
+			if v == "true" || v == "false" {
+				return v == "true", true
+			}
+		case "null":
+			if v == "" || v == "null" {
+				return nil, true
+			}
+		}
+	case float64:
+		if typ == "string" {
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		}
+	case bool:
+		if typ == "string" {
+			return strconv.FormatBool(v), true
+		}
+	}
+	return nil, false
+}
+
+// copyValue returns a deep copy of a decoded JSON value, so defaults are not
+// shared between instances
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[key] = copyValue(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			res[i] = copyValue(val)
+		}
+		return res
+	}
+	return value
+}
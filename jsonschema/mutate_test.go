@@ -0,0 +1,142 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateAndMutate(t *testing.T) {
+	ctx := context.Background()
+	sch := Must(`{
+		"type": "object",
+		"properties": {
+			"age": { "type": "integer" },
+			"subscribed": { "type": "boolean", "default": false },
+			"zip": { "type": "string" },
+			"address": {
+				"type": "object",
+				"default": {},
+				"properties": {
+					"country": { "type": "string", "default": "NP" },
+					"lines": { "type": "array", "items": { "type": "string" } }
+				}
+			},
+			"tags": { "type": "array", "items": { "type": ["integer", "null"] } },
+			"role": { "type": "string" }
+		},
+		"allOf": [
+			{ "properties": { "role": { "default": "member" } } }
+		],
+		"anyOf": [
+			{ "properties": { "plan": { "default": "free" } } }
+		],
+		"required": ["role"],
+		"additionalProperties": false
+	}`)
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"age": "42", "zip": 44600, "tags": ["1", ""], "role": "admin", "extra": true}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	res := sch.ValidateAndMutate(ctx, data, MutationOptions{ApplyDefaults: true, CoerceTypes: true, RemoveAdditional: true})
+	if !res.IsValid() {
+		t.Fatalf("unexpected errors %v", res.Errs)
+	}
+	want := map[string]interface{}{
+		"age":        float64(42),
+		"subscribed": false,
+		"zip":        "44600",
+		"address":    map[string]interface{}{"country": "NP"},
+		"tags":       []interface{}{float64(1), nil},
+		"role":       "admin",
+	}
+	if !reflect.DeepEqual(res.Data, want) {
+		t.Errorf("got %v, want %v", res.Data, want)
+	}
+
+	got := map[string]ModificationKind{}
+	for _, m := range res.Modifications {
+		got[m.InstanceLocation] = m.Kind
+	}
+	wantMods := map[string]ModificationKind{
+		"/age":             ModificationCoerce,
+		"/zip":             ModificationCoerce,
+		"/tags/0":          ModificationCoerce,
+		"/tags/1":          ModificationCoerce,
+		"/subscribed":      ModificationDefault,
+		"/address":         ModificationDefault,
+		"/address/country": ModificationDefault,
+		"/extra":           ModificationRemove,
+	}
+	if !reflect.DeepEqual(got, wantMods) {
+		t.Errorf("got modifications %v, want %v", got, wantMods)
+	}
+
+	// defaults under allOf satisfy required, and the rest is left untouched
+	// unless asked
+	data = map[string]interface{}{"extra": true, "age": "x"}
+	res = sch.ValidateAndMutate(ctx, data, MutationOptions{ApplyDefaults: true})
+	if data["role"] != "member" || data["extra"] != true || len(res.Errs) != 2 {
+		t.Errorf("got %v, errors %v", data, res.Errs)
+	}
+
+	res = Must(`{"type": "number"}`).ValidateAndMutate(ctx, "2.5", MutationOptions{CoerceTypes: true})
+	if res.Data != 2.5 || len(res.Modifications) != 1 {
+		t.Errorf("got %v, modifications %v", res.Data, res.Modifications)
+	}
+
+	// defaults and types are looked up through references
+	sch = Must(`{
+		"$defs": {
+			"int": { "type": "integer" },
+			"flag": { "type": "boolean", "default": true },
+			"node": { "$dynamicAnchor": "node", "type": "string" }
+		},
+		"properties": {
+			"age": { "$ref": "#/$defs/int" },
+			"on": { "$ref": "#/$defs/flag" },
+			"name": { "$dynamicRef": "#node" }
+		}
+	}`)
+	data = map[string]interface{}{"age": "42", "name": 7.0}
+	res = sch.ValidateAndMutate(ctx, data, MutationOptions{ApplyDefaults: true, CoerceTypes: true})
+	want = map[string]interface{}{"age": float64(42), "on": true, "name": "7"}
+	if !reflect.DeepEqual(data, want) || len(res.Modifications) != 3 {
+		t.Errorf("got %v, modifications %v, want %v", data, res.Modifications, want)
+	}
+	if !res.IsValid() {
+		t.Errorf("unexpected errors %v", res.Errs)
+	}
+
+	// each schema changes the instance once, additional properties are the
+	// ones the validator counts as such, and changes are recorded as made
+	sch = Must(`{
+		"properties": {
+			"a": {},
+			"addr": { "default": {}, "properties": { "city": { "default": "KTM" } } }
+		},
+		"allOf": [ { "properties": { "x": { "default": 1 } } } ],
+		"additionalProperties": false
+	}`)
+	data = map[string]interface{}{"a": true, "b": true}
+	res = sch.ValidateAndMutate(ctx, data, MutationOptions{ApplyDefaults: true, RemoveAdditional: true})
+	want = map[string]interface{}{"a": true, "x": float64(1), "addr": map[string]interface{}{"city": "KTM"}}
+	if !reflect.DeepEqual(data, want) || !res.IsValid() {
+		t.Errorf("got %v, errors %v, want %v", data, res.Errs, want)
+	}
+	var log []string
+	for _, m := range res.Modifications {
+		log = append(log, fmt.Sprintf("%s %s %v", m.Kind, m.InstanceLocation, m.NewValue))
+	}
+	if wantLog := []string{"default /x 1", "default /addr map[]", "default /addr/city KTM", "remove /b <nil>"}; !reflect.DeepEqual(log, wantLog) {
+		t.Errorf("got modifications %q, want %q", log, wantLog)
+	}
+
+	res = Must(`{"$defs": {"n": {"type": "number"}}, "$ref": "#/$defs/n"}`).ValidateAndMutate(ctx, "2.5", MutationOptions{CoerceTypes: true})
+	if res.Data != 2.5 || !res.IsValid() {
+		t.Errorf("got %v, errors %v", res.Data, res.Errs)
+	}
+}
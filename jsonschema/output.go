@@ -0,0 +1,264 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	jptr "github.com/oarkflow/pkg/jsonpointer"
+)
+
+// OutputFormat is one of the standard output formats of validation results
+type OutputFormat string
+
+const (
+	// OutputFlag only reports whether the instance is valid
+	OutputFlag OutputFormat = "flag"
+	// OutputBasic reports the errors, or the annotations, as a flat list
+	OutputBasic OutputFormat = "basic"
+	// OutputDetailed reports the failing, or annotating, parts of the schema
+	// as a hierarchy, leaving out the levels that only have one child
+	OutputDetailed OutputFormat = "detailed"
+	// OutputVerbose reports the evaluation of every schema and keyword as a
+	// hierarchy matching the schema
+	OutputVerbose OutputFormat = "verbose"
+)
+
+// OutputUnit is a node of the validation result in a standard output format
+type OutputUnit struct {
+	Valid bool `json:"valid"`
+	// KeywordLocation is the JSON pointer of the keyword, following the
+	// references taken to reach it
+	KeywordLocation string `json:"keywordLocation"`
+	// AbsoluteKeywordLocation is the absolute URI of the keyword, set when
+	// the schema resource holding it has one
+	AbsoluteKeywordLocation string `json:"absoluteKeywordLocation,omitempty"`
+	// InstanceLocation is the JSON pointer of the value of the instance the
+	// keyword applies to
+	InstanceLocation string        `json:"instanceLocation"`
+	Error            string        `json:"error,omitempty"`
+	Errors           []*OutputUnit `json:"errors,omitempty"`
+	Annotation       interface{}   `json:"annotation,omitempty"`
+	Annotations      []*OutputUnit `json:"annotations,omitempty"`
+
+	flag bool
+}
+
+// MarshalJSON implements the json.Marshaler interface for OutputUnit
+func (u OutputUnit) MarshalJSON() ([]byte, error) {
+	if u.flag {
+		return json.Marshal(struct {
+			Valid bool `json:"valid"`
+		}{u.Valid})
+	}
+	type outputUnit OutputUnit
+	return json.Marshal(outputUnit(u))
+}
+
+// ValidateWithOutput validates data and reports the result in the
+// requested output format
+func (s *Schema) ValidateWithOutput(ctx context.Context, data interface{}, format OutputFormat) (*OutputUnit, error) {
+	switch format {
+	case OutputFlag:
+		return &OutputUnit{Valid: s.Validate(ctx, data).IsValid(), flag: true}, nil
+	case OutputBasic, OutputDetailed, OutputVerbose:
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+
+	top := &outputNode{}
+	currentState := NewValidationState(s)
+	currentState.node = top
+	s.ValidateKeyword(ctx, currentState, data)
+	if len(top.children) == 0 {
+		// nil and boolean schemas don't record a node
+		return &OutputUnit{Valid: currentState.IsValid(), Error: strings.Join(top.errors, "; ")}, nil
+	}
+	root := top.children[0]
+
+	switch format {
+	case OutputBasic:
+		u := &OutputUnit{Valid: root.valid, KeywordLocation: root.keywordLocation.String(), InstanceLocation: root.instanceLocation}
+		root.collect(u)
+		return u, nil
+	case OutputDetailed:
+		return root.detailed(true), nil
+	default:
+		return root.verbose(), nil
+	}
+}
+
+// outputNode records the evaluation of a schema or a keyword
+type outputNode struct {
+	parent *outputNode
+	// keyword is set for keyword nodes
+	keyword     Keyword
+	keywordName string
+
+	keywordLocation  jptr.Pointer
+	instanceLocation string
+	// resourceURI and resourceLocation locate the node in the schema
+	// resource holding it
+	resourceURI      string
+	resourceLocation jptr.Pointer
+
+	errs       int
+	valid      bool
+	errors     []string
+	annotation interface{}
+	annotated  bool
+	children   []*outputNode
+}
+
+// enterSchema records the evaluation of the schema s by the current node
+func (vs *ValidationState) enterSchema(s *Schema) *outputNode {
+	parent := vs.node
+	node := &outputNode{
+		parent:           parent,
+		keywordLocation:  parent.keywordLocation,
+		instanceLocation: vs.InstanceLocation.String(),
+		resourceURI:      parent.resourceURI,
+		resourceLocation: parent.resourceLocation,
+	}
+	switch parent.keywordName {
+	case "":
+		// the schema validated
+		node.resourceURI = s.docPath
+	case "$ref", "$dynamicRef", "$recursiveRef":
+		// references restart from the resource of the schema they resolve to
+		node.resourceURI, node.resourceLocation = vs.Root.docPath, nil
+		if tokens, ok := vs.Root.locate(s); ok {
+			node.resourceLocation = tokens
+		}
+	default:
+		for _, sub := range subschemas(parent.keyword) {
+			if sub.schema == s {
+				node.keywordLocation = descend(node.keywordLocation, sub.tokens...)
+				node.resourceLocation = descend(node.resourceLocation, sub.tokens...)
+				break
+			}
+		}
+	}
+	if s.isResource() && s.docPath != "" {
+		node.resourceURI, node.resourceLocation = s.docPath, nil
+	}
+	return vs.enterNode(node)
+}
+
+// enterKeyword records the evaluation of the keyword named name by the
+// current node
+func (vs *ValidationState) enterKeyword(name string, keyword Keyword) *outputNode {
+	parent := vs.node
+	return vs.enterNode(&outputNode{
+		parent:           parent,
+		keyword:          keyword,
+		keywordName:      name,
+		keywordLocation:  descend(parent.keywordLocation, name),
+		instanceLocation: vs.InstanceLocation.String(),
+		resourceURI:      parent.resourceURI,
+		resourceLocation: descend(parent.resourceLocation, name),
+	})
+}
+
+// descend returns the pointer to the tokens under p. Unlike RawDescendant it
+// never appends in place, since sibling nodes descend from the same pointer
+func descend(p jptr.Pointer, tokens ...string) jptr.Pointer {
+	return append(slices.Clip(p), tokens...)
+}
+
+func (vs *ValidationState) enterNode(node *outputNode) *outputNode {
+	node.parent.children = append(node.parent.children, node)
+	node.errs = len(*vs.Errs)
+	vs.node = node
+	return node
+}
+
+// leaveNode completes the evaluation of node, which is valid when it added
+// no errors to the state
+func (vs *ValidationState) leaveNode(node *outputNode) {
+	node.valid = len(*vs.Errs) == node.errs
+	vs.node = node.parent
+}
+
+// unit returns the output unit of the node, without its children
+func (n *outputNode) unit() *OutputUnit {
+	u := &OutputUnit{
+		Valid:            n.valid,
+		KeywordLocation:  n.keywordLocation.String(),
+		InstanceLocation: n.instanceLocation,
+	}
+	if n.resourceURI != "" {
+		u.AbsoluteKeywordLocation = strings.TrimRight(n.resourceURI, "#") + "#" + n.resourceLocation.String()
+	}
+	if n.valid {
+		if n.annotated {
+			u.Annotation = n.annotation
+		}
+	} else {
+		u.Error = strings.Join(n.errors, "; ")
+	}
+	return u
+}
+
+// collect adds the errors of a failing node, or the annotations of a valid
+// one, to the flat list of u
+func (n *outputNode) collect(u *OutputUnit) {
+	if n.valid && n.annotated {
+		u.Annotations = append(u.Annotations, n.unit())
+	} else if !n.valid && len(n.errors) > 0 {
+		u.Errors = append(u.Errors, n.unit())
+	}
+	for _, child := range n.children {
+		// annotations of failing schemas are dropped
+		if child.valid == n.valid {
+			child.collect(u)
+		}
+	}
+}
+
+// detailed returns the output unit of the node with its failing, or
+// annotating, children. Nodes with no error or annotation of their own are
+// replaced by their only child or left out when they have none
+func (n *outputNode) detailed(root bool) *OutputUnit {
+	u := n.unit()
+	var children []*OutputUnit
+	for _, child := range n.children {
+		if child.valid != n.valid {
+			continue
+		}
+		if cu := child.detailed(false); cu != nil {
+			children = append(children, cu)
+		}
+	}
+	if !root && u.Error == "" && !(n.valid && n.annotated) {
+		switch len(children) {
+		case 0:
+			if n.valid {
+				return nil
+			}
+		case 1:
+			return children[0]
+		}
+	}
+	if n.valid {
+		u.Annotations = children
+	} else {
+		u.Errors = children
+	}
+	return u
+}
+
+// verbose returns the output unit of the node with all its children
+func (n *outputNode) verbose() *OutputUnit {
+	u := n.unit()
+	for _, child := range n.children {
+		if n.valid {
+			u.Annotations = append(u.Annotations, child.verbose())
+		} else {
+			u.Errors = append(u.Errors, child.verbose())
+		}
+	}
+	return u
+}
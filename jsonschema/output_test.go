@@ -0,0 +1,122 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateWithOutput(t *testing.T) {
+	ctx := context.Background()
+	sch := Must(`{
+		"$id": "https://example.com/polygon",
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$defs": {
+			"point": {
+				"type": "object",
+				"properties": {
+					"x": { "type": "number" },
+					"y": { "type": "number" }
+				},
+				"additionalProperties": false,
+				"required": [ "x", "y" ]
+			}
+		},
+		"type": "array",
+		"items": { "$ref": "#/$defs/point" },
+		"minItems": 3
+	}`)
+	var invalid interface{}
+	if err := json.Unmarshal([]byte(`[{"x": 2.5, "y": 1.3}, {"x": 1, "z": 6.7}]`), &invalid); err != nil {
+		t.Fatal(err)
+	}
+
+	flag, err := sch.ValidateWithOutput(ctx, invalid, OutputFlag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(flag); string(b) != `{"valid":false}` {
+		t.Errorf("flag: got %s", b)
+	}
+
+	basic, err := sch.ValidateWithOutput(ctx, invalid, OutputBasic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"/items/$ref/required https://example.com/polygon#/$defs/point/required /1":                           true,
+		"/items/$ref/additionalProperties https://example.com/polygon#/$defs/point/additionalProperties /1/z": true,
+		"/minItems https://example.com/polygon#/minItems ":                                                    true,
+	}
+	if basic.Valid || len(basic.Errors) != len(want) {
+		t.Fatalf("basic: got %+v", basic)
+	}
+	for _, u := range basic.Errors {
+		if loc := u.KeywordLocation + " " + u.AbsoluteKeywordLocation + " " + u.InstanceLocation; !want[loc] || u.Error == "" {
+			t.Errorf("basic: unexpected error %s: %q", loc, u.Error)
+		}
+	}
+
+	detailed, err := sch.ValidateWithOutput(ctx, invalid, OutputDetailed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detailed.Errors) != 2 {
+		t.Fatalf("detailed: got %d errors", len(detailed.Errors))
+	}
+	for _, u := range detailed.Errors {
+		switch u.KeywordLocation {
+		case "/items/$ref":
+			if u.InstanceLocation != "/1" || u.AbsoluteKeywordLocation != "https://example.com/polygon#/$defs/point" || len(u.Errors) != 2 {
+				t.Errorf("detailed: got %+v", u)
+			}
+		case "/minItems":
+		default:
+			t.Errorf("detailed: unexpected error at %s", u.KeywordLocation)
+		}
+	}
+
+	verbose, err := sch.ValidateWithOutput(ctx, invalid, OutputVerbose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// $id, $schema, $defs, type, items and minItems
+	if verbose.Valid || len(verbose.Errors) != 6 {
+		t.Errorf("verbose: got %d children", len(verbose.Errors))
+	}
+
+	var valid interface{}
+	json.Unmarshal([]byte(`[{"x": 1, "y": 2}, {"x": 3, "y": 4}, {"x": 5, "y": 6}]`), &valid)
+	basic, _ = sch.ValidateWithOutput(ctx, valid, OutputBasic)
+	annotations := map[string]interface{}{}
+	for _, u := range basic.Annotations {
+		annotations[u.KeywordLocation+" "+u.InstanceLocation] = u.Annotation
+	}
+	if !basic.Valid || annotations["/items "] != true || len(annotations["/items/$ref/properties /2"].([]string)) != 2 {
+		t.Errorf("basic: unexpected annotations %v", annotations)
+	}
+
+	if _, err := sch.ValidateWithOutput(ctx, valid, "compact"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+
+	// sibling keywords under a reference keep their own locations
+	sch = Must(`{
+		"$id": "https://example.com/pos",
+		"$defs": { "pos": { "type": "integer", "minimum": 0 } },
+		"properties": { "a": { "$ref": "#/$defs/pos" } }
+	}`)
+	basic, _ = sch.ValidateWithOutput(ctx, map[string]interface{}{"a": -1.5}, OutputBasic)
+	want = map[string]bool{
+		"/properties/a/$ref/type https://example.com/pos#/$defs/pos/type /a":       true,
+		"/properties/a/$ref/minimum https://example.com/pos#/$defs/pos/minimum /a": true,
+	}
+	if basic.Valid || len(basic.Errors) != len(want) {
+		t.Fatalf("basic: got %+v", basic)
+	}
+	for _, u := range basic.Errors {
+		if loc := u.KeywordLocation + " " + u.AbsoluteKeywordLocation + " " + u.InstanceLocation; !want[loc] {
+			t.Errorf("basic: unexpected error %s", loc)
+		}
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"sort"
 	"strings"
+	"sync"
 
 	jptr "github.com/oarkflow/pkg/jsonpointer"
 )
@@ -29,6 +29,11 @@ const (
 	schemaTypeTrue
 )
 
+// resolveLock serializes the registration and reference resolution a schema
+// does lazily on its first validation, so a parsed schema can be validated
+// from several goroutines at once
+var resolveLock sync.Mutex
+
 // Schema is the top-level structure defining a json schema
 type Schema struct {
 	schemaType    schemaType
@@ -41,6 +46,19 @@ type Schema struct {
 	keywords         map[string]Keyword
 	orderedkeywords  []string
 
+	// draft is the meta-schema URI of the keywords the schema was parsed with,
+	// keywordRegistry the registry of the draft the schema declares with $schema
+	draft           string
+	keywordRegistry *KeywordRegistry
+	// orderedFor holds orderedForKeywords, the order of the keywords for the
+	// registry of the closest parent schema declaring a draft. Like
+	// orderedkeywords, it is set when parsing so validation only reads it
+	orderedFor         *KeywordRegistry
+	orderedForKeywords []string
+
+	// anchorIndex holds the anchors of the schema resource, set when parsing
+	anchorIndex map[string]anchor
+
 	// topSchemaRegistry will be shared with all children schemas (on unmarshelling)
 	topSchemaRegistry *SchemaRegistry
 }
@@ -180,7 +198,8 @@ func (s Schema) JSONChildren() map[string]JSONPather {
 
 // _schema is an internal struct for encoding & decoding purposes
 type _schema struct {
-	ID string `json:"$id,omitempty"`
+	ID     string `json:"$id,omitempty"`
+	Schema string `json:"$schema,omitempty"`
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Schema
@@ -215,6 +234,13 @@ func (s *Schema) UnmarshalJSON(data []byte) error {
 		keywords:          map[string]Keyword{},
 		topSchemaRegistry: s.topSchemaRegistry,
 	}
+	if _s.Schema != "" {
+		if r, ok := draftKeywordRegistry(_s.Schema, keywordRegistry); ok {
+			keywordRegistry = r
+			sch.keywordRegistry = r
+		}
+	}
+	sch.draft = keywordRegistry.draft
 
 	valprops := map[string]json.RawMessage{}
 	if err := json.Unmarshal(data, &valprops); err != nil {
@@ -225,6 +251,10 @@ func (s *Schema) UnmarshalJSON(data []byte) error {
 		var keyword Keyword
 		if keywordRegistry.IsRegisteredKeyword(prop) {
 			keyword = keywordRegistry.GetKeyword(prop)
+		} else if maker, ok := draftKeywordMaker(prop); ok {
+			// keywords of other drafts are kept for nested schemas, which
+			// are validated with the draft of their parent
+			keyword = maker()
 		} else if keywordRegistry.IsNotSupportedKeyword(prop) {
 			schemaDebug(fmt.Sprintf("[Schema] WARN: '%s' is not supported and will be ignored\n", prop))
 			continue
@@ -244,37 +274,31 @@ func (s *Schema) UnmarshalJSON(data []byte) error {
 	}
 
 	// ensures proper and stable keyword validation order
-	keyOrders := make([]_keyOrder, len(sch.keywords))
-	i := 0
-	for k := range sch.keywords {
-		keyOrders[i] = _keyOrder{
-			Key:   k,
-			Order: keywordRegistry.GetKeywordOrder(k),
-		}
-		i++
-	}
-	sort.SliceStable(keyOrders, func(i, j int) bool {
-		if keyOrders[i].Order == keyOrders[j].Order {
-			return keywordRegistry.GetKeywordInsertOrder(keyOrders[i].Key) < keywordRegistry.GetKeywordInsertOrder(keyOrders[j].Key)
-		}
-		return keyOrders[i].Order < keyOrders[j].Order
-	})
-	orderedKeys := make([]string, len(sch.keywords))
-	i = 0
-	for _, keyOrder := range keyOrders {
-		orderedKeys[i] = keyOrder.Key
-		i++
-	}
-	sch.orderedkeywords = orderedKeys
+	sch.orderedkeywords = keywordRegistry.orderKeywords(sch.keywords)
 
 	*s = Schema(*sch)
+	if s.keywordRegistry != nil {
+		s.orderNested(s.keywordRegistry)
+	}
+	if s.isResource() {
+		s.anchorIndex = s.collectAnchorIndex()
+	}
 	return nil
 }
 
-// _keyOrder is an internal struct assigning evaluation order of keywords
-type _keyOrder struct {
-	Key   string
-	Order int
+// orderNested orders the keywords of the schemas nested in s for r, the
+// registry they are validated with, down to the ones declaring their own
+// draft
+func (s *Schema) orderNested(r *KeywordRegistry) {
+	for _, keyword := range s.keywords {
+		for _, sub := range subschemas(keyword) {
+			if sub.schema == nil || sub.schema.keywordRegistry != nil {
+				continue
+			}
+			sub.schema.orderedFor, sub.schema.orderedForKeywords = r, r.orderKeywords(sub.schema.keywords)
+			sub.schema.orderNested(r)
+		}
+	}
 }
 
 // Validate initiates a fresh validation state and triggers the evaluation
@@ -300,45 +324,127 @@ func (s *Schema) ValidateKeyword(ctx context.Context, currentState *ValidationSt
 		return
 	}
 
+	resolveLock.Lock()
 	s.Register("", currentState.LocalRegistry)
+	resolveLock.Unlock()
 	currentState.LocalRegistry.RegisterLocal(s)
 
+	if currentState.node != nil {
+		node := currentState.enterSchema(s)
+		defer currentState.leaveNode(node)
+	}
+
 	currentState.Local = s
 
-	refKeyword := s.keywords["$ref"]
-
-	if refKeyword == nil {
-		if currentState.BaseURI == "" {
-			currentState.BaseURI = s.docPath
-		} else if s.docPath != "" {
-			if u, err := url.Parse(s.docPath); err == nil {
-				if u.IsAbs() {
-					currentState.BaseURI = s.docPath
-				} else {
-					currentState.BaseURI, _ = SafeResolveURL(currentState.BaseURI, s.docPath)
-				}
-			}
-		}
+	// the draft and the dynamic scope apply to the subschemas of s only
+	keywordRegistry, dynamicScope := currentState.LocalKeywordRegistry, currentState.DynamicScope
+	defer func() {
+		currentState.LocalKeywordRegistry, currentState.DynamicScope = keywordRegistry, dynamicScope
+	}()
+	if s.keywordRegistry != nil {
+		currentState.LocalKeywordRegistry = s.keywordRegistry
 	}
+	currentState.enterScope(s)
 
-	if currentState.BaseURI != "" && strings.HasSuffix(currentState.BaseURI, "#") {
-		currentState.BaseURI = strings.TrimRight(currentState.BaseURI, "#")
+	if currentState.mutation != nil {
+		s.mutate(ctx, currentState, data)
 	}
 
-	// TODO(arqu): only on versions bellow draft2019_09
-	// if refKeyword != nil {
-	// 	refKeyword.ValidateKeyword(currentState, errs)
-	// 	return
-	// }
-
 	s.validateSchemakeywords(ctx, currentState, data)
 }
 
+// enterScope makes s the current schema resource when it is one, and
+// resolves the base URI of the state against the $id of s
+func (vs *ValidationState) enterScope(s *Schema) {
+	if s.isResource() || len(vs.DynamicScope) == 0 {
+		vs.enterResource(s)
+	}
+
+	if vs.BaseURI == "" {
+		vs.BaseURI = s.docPath
+	} else if s.docPath != "" {
+		if u, err := url.Parse(s.docPath); err == nil {
+			if u.IsAbs() {
+				vs.BaseURI = s.docPath
+			} else {
+				vs.BaseURI, _ = SafeResolveURL(vs.BaseURI, s.docPath)
+			}
+		}
+	}
+
+	if vs.BaseURI != "" && strings.HasSuffix(vs.BaseURI, "#") {
+		vs.BaseURI = strings.TrimRight(vs.BaseURI, "#")
+	}
+}
+
 // validateSchemakeywords triggers validation of sub schemas and keywords
 func (s *Schema) validateSchemakeywords(ctx context.Context, currentState *ValidationState, data interface{}) {
 	if s.keywords != nil {
-		for _, keyword := range s.orderedkeywords {
+		for _, keyword := range s.orderedKeywordsFor(currentState.LocalKeywordRegistry) {
+			if currentState.node == nil {
+				s.keywords[keyword].ValidateKeyword(ctx, currentState, data)
+				continue
+			}
+			node := currentState.enterKeyword(keyword, s.keywords[keyword])
 			s.keywords[keyword].ValidateKeyword(ctx, currentState, data)
+			currentState.leaveNode(node)
+		}
+	}
+}
+
+// orderedKeywordsFor returns the keywords of the schema registered with r,
+// in validation order. A nil registry stands for the one the schema was
+// parsed with
+func (s *Schema) orderedKeywordsFor(r *KeywordRegistry) []string {
+	if r == nil || r == s.keywordRegistry {
+		return s.orderedkeywords
+	}
+	if s.orderedFor == r {
+		return s.orderedForKeywords
+	}
+	return r.orderKeywords(s.keywords)
+}
+
+// isResource reports whether the schema is a schema resource, which has its
+// own base URI and anchors
+func (s *Schema) isResource() bool {
+	return s.id != "" && s.id[0] != '#'
+}
+
+// anchor is a schema named with $anchor or $dynamicAnchor
+type anchor struct {
+	schema  *Schema
+	dynamic bool
+}
+
+// anchors returns the anchors of the schema resource s by name, leaving out
+// the ones of the resources nested in it
+func (s *Schema) anchors() map[string]anchor {
+	if s.anchorIndex != nil {
+		return s.anchorIndex
+	}
+	return s.collectAnchorIndex()
+}
+
+func (s *Schema) collectAnchorIndex() map[string]anchor {
+	index := map[string]anchor{}
+	s.collectAnchors(index, true)
+	return index
+}
+
+func (s *Schema) collectAnchors(index map[string]anchor, root bool) {
+	if s == nil || (!root && s.isResource()) {
+		return
+	}
+	if name, ok := s.keywords["$anchor"].(*Anchor); ok {
+		index[string(*name)] = anchor{schema: s}
+	}
+	if name, ok := s.keywords["$dynamicAnchor"].(*DynamicAnchor); ok {
+		index[string(*name)] = anchor{schema: s, dynamic: true}
+	}
+	for _, keyword := range s.keywords {
+		for _, sub := range subschemas(keyword) {
+			sub.schema.collectAnchors(index, false)
 		}
 	}
 }
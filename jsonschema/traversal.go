@@ -1,5 +1,7 @@
 package jsonschema
 
+import "strconv"
+
 // JSONPather makes validators traversible by JSON-pointers,
 // which is required to support references in JSON schemas.
 type JSONPather interface {
@@ -33,3 +35,101 @@ func walkJSON(elem JSONPather, fn func(elem JSONPather) error) error {
 
 	return nil
 }
+
+// subschema is a schema a keyword applies, with the JSON pointer
+// tokens leading to it from the keyword
+type subschema struct {
+	tokens []string
+	schema *Schema
+}
+
+// subschemas returns the schemas a keyword applies to the instance
+// or its children
+func subschemas(keyword Keyword) []subschema {
+	switch k := keyword.(type) {
+	case *Items:
+		if k.single {
+			return []subschema{{schema: k.Schemas[0]}}
+		}
+		return indexedSubschemas(k.Schemas)
+	case *PrefixItems:
+		return indexedSubschemas(*k)
+	case *AllOf:
+		return indexedSubschemas(*k)
+	case *AnyOf:
+		return indexedSubschemas(*k)
+	case *OneOf:
+		return indexedSubschemas(*k)
+	case *Defs:
+		return namedSubschemas(*k)
+	case *Properties:
+		return namedSubschemas(*k)
+	case *PatternProperties:
+		res := make([]subschema, 0, len(*k))
+		for _, p := range *k {
+			res = append(res, subschema{tokens: []string{p.key}, schema: p.schema})
+		}
+		return res
+	case *DependentSchemas:
+		res := make([]subschema, 0, len(*k))
+		for key, d := range *k {
+			res = append(res, subschema{tokens: []string{key}, schema: d.schema})
+		}
+		return res
+	case *Contains:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *Not:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *If:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *Then:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *Else:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *AdditionalItems:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *UnevaluatedItems:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *AdditionalProperties:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *PropertyNames:
+		return []subschema{{schema: (*Schema)(k)}}
+	case *UnevaluatedProperties:
+		return []subschema{{schema: (*Schema)(k)}}
+	}
+	return nil
+}
+
+func indexedSubschemas(schemas []*Schema) []subschema {
+	res := make([]subschema, len(schemas))
+	for i, sch := range schemas {
+		res[i] = subschema{tokens: []string{strconv.Itoa(i)}, schema: sch}
+	}
+	return res
+}
+
+func namedSubschemas(schemas map[string]*Schema) []subschema {
+	res := make([]subschema, 0, len(schemas))
+	for key, sch := range schemas {
+		res = append(res, subschema{tokens: []string{key}, schema: sch})
+	}
+	return res
+}
+
+// locate returns the JSON pointer tokens leading from s to target
+func (s *Schema) locate(target *Schema) ([]string, bool) {
+	if s == target {
+		return nil, true
+	}
+	if s == nil {
+		return nil, false
+	}
+	for prop, keyword := range s.keywords {
+		for _, sub := range subschemas(keyword) {
+			if tokens, ok := sub.schema.locate(target); ok {
+				return append(append([]string{prop}, sub.tokens...), tokens...), true
+			}
+		}
+	}
+	return nil, false
+}
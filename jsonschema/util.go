@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -47,3 +48,13 @@ func IsLocalSchemaID(id string) bool {
 	}
 	return id != "#" && !strings.HasPrefix(id, "#/") && strings.Contains(id, "#")
 }
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -21,14 +21,26 @@ type ValidationState struct {
 	LocalKeywordRegistry *KeywordRegistry
 	TopSchemaRegistry    *SchemaRegistry
 
+	// DynamicScope lists the schema resources entered so far, outermost first
+	DynamicScope []*Schema
+
 	EvaluatedPropertyNames      *map[string]bool
 	LocalEvaluatedPropertyNames *map[string]bool
 	LastEvaluatedIndex          int
 	LocalLastEvaluatedIndex     int
-	Misc                        map[string]interface{}
+	// EvaluatedItemIndexes holds the items evaluated out of order, by contains
+	EvaluatedItemIndexes *map[int]bool
+	Misc                 map[string]interface{}
 
 	Errs *[]KeyError
 
+	// node records the schema or keyword being evaluated, when validating
+	// with ValidateWithOutput
+	node *outputNode
+	// mutation holds the changes to make to the instance, when validating
+	// with ValidateAndMutate
+	mutation *mutation
+
 	// AdditionalValidationData is a shared storage between all substates of a ValidationState
 	// Its key space won't be cleared and is shared between all substates of a validation operation.
 	AdditionalValidationData *map[string]interface{}
@@ -50,6 +62,7 @@ func NewValidationState(s *Schema) *ValidationState {
 		LocalLastEvaluatedIndex:     -1,
 		EvaluatedPropertyNames:      &map[string]bool{},
 		LocalEvaluatedPropertyNames: &map[string]bool{},
+		EvaluatedItemIndexes:        &map[int]bool{},
 		Misc:                        map[string]interface{}{},
 		Errs:                        &[]KeyError{},
 		AdditionalValidationData:    &map[string]interface{}{},
@@ -69,12 +82,17 @@ func (vs *ValidationState) NewSubState() *ValidationState {
 		RelativeLocation:            vs.RelativeLocation,
 		BaseRelativeLocation:        vs.RelativeLocation,
 		LocalRegistry:               vs.LocalRegistry,
+		LocalKeywordRegistry:        vs.LocalKeywordRegistry,
 		TopSchemaRegistry:           vs.TopSchemaRegistry,
+		DynamicScope:                vs.DynamicScope,
 		EvaluatedPropertyNames:      vs.EvaluatedPropertyNames,
 		LocalEvaluatedPropertyNames: vs.LocalEvaluatedPropertyNames,
+		EvaluatedItemIndexes:        vs.EvaluatedItemIndexes,
 		Misc:                        map[string]interface{}{},
 		Errs:                        vs.Errs,
 		AdditionalValidationData:    vs.AdditionalValidationData,
+		node:                        vs.node,
+		mutation:                    vs.mutation,
 	}
 }
 
@@ -93,6 +111,9 @@ func (vs *ValidationState) SetAdditionalValidationData(key string, value interfa
 func (vs *ValidationState) ClearState() {
 	vs.EvaluatedPropertyNames = &map[string]bool{}
 	vs.LocalEvaluatedPropertyNames = &map[string]bool{}
+	vs.EvaluatedItemIndexes = &map[int]bool{}
+	vs.LastEvaluatedIndex = -1
+	vs.LocalLastEvaluatedIndex = -1
 	if len(vs.Misc) > 0 {
 		vs.Misc = map[string]interface{}{}
 	}
@@ -116,6 +137,24 @@ func (vs *ValidationState) IsLocallyEvaluatedKey(key string) bool {
 	return ok
 }
 
+// enterResource adds the schema resource s to the dynamic scope
+func (vs *ValidationState) enterResource(s *Schema) {
+	if n := len(vs.DynamicScope); n == 0 || vs.DynamicScope[n-1] != s {
+		vs.DynamicScope = append(vs.DynamicScope[:n:n], s)
+	}
+}
+
+// draft returns the meta-schema URI of the draft the current schema is validated with
+func (vs *ValidationState) draft() string {
+	if vs.LocalKeywordRegistry != nil {
+		return vs.LocalKeywordRegistry.draft
+	}
+	if vs.Local != nil {
+		return vs.Local.draft
+	}
+	return ""
+}
+
 // SetEvaluatedIndex sets the evaluation index for the current state
 func (vs *ValidationState) SetEvaluatedIndex(i int) {
 	vs.LastEvaluatedIndex = i
@@ -127,6 +166,9 @@ func (vs *ValidationState) SetEvaluatedIndex(i int) {
 func (vs *ValidationState) UpdateEvaluatedPropsAndItems(subState *ValidationState) {
 	joinSets(vs.EvaluatedPropertyNames, *subState.EvaluatedPropertyNames)
 	joinSets(vs.LocalEvaluatedPropertyNames, *subState.LocalEvaluatedPropertyNames)
+	for i := range *subState.EvaluatedItemIndexes {
+		(*vs.EvaluatedItemIndexes)[i] = true
+	}
 	if subState.LastEvaluatedIndex > vs.LastEvaluatedIndex {
 		vs.LastEvaluatedIndex = subState.LastEvaluatedIndex
 	}
@@ -161,6 +203,30 @@ func (vs *ValidationState) AddError(data interface{}, msg string) {
 		InvalidValue: data,
 		Message:      msg,
 	})
+	if vs.node != nil {
+		if location := vs.InstanceLocation.String(); location != vs.node.instanceLocation {
+			// keywords checking the children of the instance themselves
+			vs.node.children = append(vs.node.children, &outputNode{
+				parent:           vs.node,
+				keywordLocation:  vs.node.keywordLocation,
+				instanceLocation: location,
+				resourceURI:      vs.node.resourceURI,
+				resourceLocation: vs.node.resourceLocation,
+				errors:           []string{msg},
+			})
+		} else {
+			vs.node.errors = append(vs.node.errors, msg)
+		}
+	}
+}
+
+// AddAnnotation attaches an annotation to the keyword being evaluated, which
+// is reported by ValidateWithOutput when the keyword and its parents are valid
+func (vs *ValidationState) AddAnnotation(value interface{}) {
+	if vs.node != nil {
+		vs.node.annotation = value
+		vs.node.annotated = true
+	}
 }
 
 // AddSubErrors appends a list of KeyError to the current state
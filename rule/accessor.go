@@ -0,0 +1,292 @@
+package rule
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// fieldPath resolves a dotted condition field directly on maps, slices and
+// structs. The resolved value is normalized to the shape sjson would return
+// for the marshalled data, so compiled conditions compare the same values as
+// Condition.Validate without encoding the record.
+type fieldPath []string
+
+// sjsonSyntax lists characters that give a path segment special meaning in
+// sjson; fields using them keep going through the JSON path.
+const sjsonSyntax = "#*?|@\\!=<>%:[]{}(),\"'"
+
+func newFieldPath(field string) (fieldPath, bool) {
+	if field == "" || strings.ContainsAny(field, sjsonSyntax) {
+		return nil, false
+	}
+	path := strings.Split(field, ".")
+	for _, segment := range path {
+		if segment == "" {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// resolve walks the path on data. exists mirrors sjson's Result.Exists and
+// ok is false when the data cannot be walked without marshalling it, e.g.
+// when it implements json.Marshaler or uses non string map keys.
+func (p fieldPath) resolve(data any) (val any, exists, ok bool) {
+	if !isObject(data) {
+		return nil, false, false
+	}
+	current := data
+	for _, segment := range p {
+		switch c := current.(type) {
+		case map[string]any:
+			current, exists = c[segment]
+			if !exists {
+				return nil, false, true
+			}
+			continue
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false, true
+			}
+			current = c[i]
+			continue
+		case nil:
+			return nil, false, true
+		}
+		current, exists, ok = resolveReflect(reflect.ValueOf(current), segment)
+		if !ok || !exists {
+			return nil, exists, ok
+		}
+	}
+	val, ok = jsonValue(current)
+	return val, true, ok
+}
+
+func isObject(data any) bool {
+	if _, ok := data.(map[string]any); ok {
+		return true
+	}
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return (rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct) && !implementsMarshaler(rv.Type())
+}
+
+func resolveReflect(rv reflect.Value, segment string) (any, bool, bool) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if implementsMarshaler(rv.Type()) {
+			return nil, false, false
+		}
+		if rv.IsNil() {
+			return nil, false, true
+		}
+		rv = rv.Elem()
+	}
+	if implementsMarshaler(rv.Type()) {
+		return nil, false, false
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false, false
+		}
+		v := rv.MapIndex(reflect.ValueOf(segment).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, false, true
+		}
+		return v.Interface(), true, true
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string
+			return nil, false, true
+		}
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= rv.Len() {
+			return nil, false, true
+		}
+		return rv.Index(i).Interface(), true, true
+	case reflect.Struct:
+		fields, ok := structFields(rv.Type())
+		if !ok {
+			return nil, false, false
+		}
+		f, found := fields[segment]
+		if !found {
+			return nil, false, true
+		}
+		v, walkable := fieldByIndex(rv, f.index)
+		if !walkable || (f.omitEmpty && isEmptyValue(v)) {
+			return nil, false, true
+		}
+		return v.Interface(), true, true
+	}
+	return nil, false, true
+}
+
+func implementsMarshaler(t reflect.Type) bool {
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if t.Kind() != reflect.Pointer {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// jsonValue normalizes a resolved value to what sjson yields after a
+// marshalling round trip: float64 numbers, []any and map[string]any.
+func jsonValue(v any) (any, bool) {
+	switch v := v.(type) {
+	case nil, bool:
+		return v, true
+	case string:
+		if utf8.ValidString(v) {
+			return v, true
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		return v, true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return f, true
+	}
+	bt, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	var out any
+	if err := json.Unmarshal(bt, &out); err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+type structField struct {
+	index     []int
+	omitEmpty bool
+}
+
+var structFieldCache sync.Map // map[reflect.Type]map[string]structField
+
+// structFields maps the JSON names of a struct type to their field indexes,
+// following the encoding/json rules for tags and embedded structs. Types
+// using the ",string" option are reported as not walkable.
+func structFields(t reflect.Type) (map[string]structField, bool) {
+	if cached, ok := structFieldCache.Load(t); ok {
+		fields := cached.(map[string]structField)
+		return fields, fields != nil
+	}
+	fields := make(map[string]structField)
+	depth := make(map[string]int)
+	ok := collectFields(t, nil, fields, depth)
+	if !ok {
+		fields = nil
+	}
+	structFieldCache.Store(t, fields)
+	return fields, ok
+}
+
+func collectFields(t reflect.Type, index []int, fields map[string]structField, depth map[string]int) bool {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(append([]int{}, index...), i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if !collectFields(ft, idx, fields, depth) {
+					return false
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",string,") {
+			return false
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if d, seen := depth[name]; seen && d <= len(idx) {
+			continue
+		}
+		depth[name] = len(idx)
+		fields[name] = structField{
+			index:     idx,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		}
+	}
+	return true
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -48,55 +48,73 @@ func (condition *Condition) Validate(data Data) bool {
 	if condition.Filter.Condition != "" {
 		condition.Value = lookupFiltered
 	}
+	switch condition.Operator {
+	case EQ, NEQ, IsZero, NotZero, IsNull:
+		return condition.check(val.Value(), data)
+	}
+	if condition.Filter.Key != "" {
+		// lookup filters rewrite the data in place, so the field is resolved again
+		dataJson, err = json.Marshal(data)
+		if err != nil {
+			return false
+		}
+		val = sjson.GetBytes(dataJson, condition.Field)
+	}
+	return condition.check(val.Value(), data)
+}
+
+// check applies the condition operator on the resolved field value. NotNull
+// needs the whole data as it inspects sibling counts for "#" paths.
+func (condition *Condition) check(val any, data Data) bool {
 	switch condition.Operator {
 	case EQ:
-		return condition.checkEq(val.Value())
+		return condition.checkEq(val)
 	case NEQ:
-		return condition.checkNeq(val.Value())
+		return condition.checkNeq(val)
 	case GT:
-		return condition.checkGt(data)
+		return condition.checkGt(val)
 	case LT:
-		return condition.checkLt(data)
+		return condition.checkLt(val)
 	case GTE:
-		return condition.checkGte(data)
+		return condition.checkGte(val)
 	case LTE:
-		return condition.checkLte(data)
+		return condition.checkLte(val)
 	case BETWEEN:
-		return condition.checkBetween(data)
+		return condition.checkBetween(val)
 	case IN:
-		return condition.checkIn(data)
+		return condition.checkIn(val)
 	case NotIn:
-		return condition.checkNotIn(data)
+		return condition.checkNotIn(val)
 	case CONTAINS:
-		return condition.checkContains(data)
+		return condition.checkContains(val)
 	case NotContains:
-		return condition.checkNotContains(data)
+		return condition.checkNotContains(val)
 	case StartsWith:
-		return condition.checkStartsWith(data)
+		return condition.checkStartsWith(val)
 	case EndsWith:
-		return condition.checkEndsWith(data)
+		return condition.checkEndsWith(val)
 	case IsZero:
-		return reflect.ValueOf(val.Value()).IsZero()
+		return reflect.ValueOf(val).IsZero()
 	case NotZero:
-		return !reflect.ValueOf(val.Value()).IsZero()
+		return !reflect.ValueOf(val).IsZero()
 	case IsNull:
-		return val.Value() == nil
+		return val == nil
 	case NotNull:
 		return condition.checkNotNull(data)
 	case EqCount:
-		return condition.checkEqCount(data)
+		return condition.checkEqCount(val)
 	case NeqCount:
-		return condition.checkNeqCount(data)
+		return condition.checkNeqCount(val)
 	case GtCount:
-		return condition.checkGtCount(data)
+		return condition.checkGtCount(val)
 	case GteCount:
-		return condition.checkGteCount(data)
+		return condition.checkGteCount(val)
 	case LtCount:
-		return condition.checkLtCount(data)
+		return condition.checkLtCount(val)
 	case LteCount:
-		return condition.checkLteCount(data)
+		return condition.checkLteCount(val)
 	}
-	return false
+	return condition.checkCustom(val)
 }
 
 func (condition *Condition) checkEq(val any) bool {
@@ -201,13 +219,7 @@ func (condition *Condition) checkNeq(val any) bool {
 	return false
 }
 
-func (condition *Condition) checkGt(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkGt(result any) bool {
 	switch val := result.(type) {
 	case string:
 		from, err := timeutil.ParseTime(val)
@@ -244,13 +256,7 @@ func (condition *Condition) checkGt(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkLt(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkLt(result any) bool {
 	switch val := result.(type) {
 	case string:
 		from, err := timeutil.ParseTime(val)
@@ -289,13 +295,7 @@ func (condition *Condition) checkLt(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkGte(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkGte(result any) bool {
 	switch val := result.(type) {
 	case string:
 		from, err := timeutil.ParseTime(val)
@@ -331,13 +331,7 @@ func (condition *Condition) checkGte(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkLte(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkLte(result any) bool {
 	switch val := result.(type) {
 	case string:
 		from, err := timeutil.ParseTime(val)
@@ -374,13 +368,7 @@ func (condition *Condition) checkLte(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkBetween(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkBetween(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -421,13 +409,7 @@ func (condition *Condition) checkBetween(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkIn(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkIn(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -496,13 +478,7 @@ func (condition *Condition) checkIn(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkNotIn(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkNotIn(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -571,13 +547,7 @@ func (condition *Condition) checkNotIn(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkContains(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkContains(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -590,13 +560,7 @@ func (condition *Condition) checkContains(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkNotContains(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkNotContains(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -608,13 +572,7 @@ func (condition *Condition) checkNotContains(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkStartsWith(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkStartsWith(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -626,13 +584,7 @@ func (condition *Condition) checkStartsWith(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkEndsWith(data Data) bool {
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field).Value()
+func (condition *Condition) checkEndsWith(result any) bool {
 	switch val := result.(type) {
 	case string:
 		switch gtVal := condition.Value.(type) {
@@ -644,19 +596,7 @@ func (condition *Condition) checkEndsWith(data Data) bool {
 	return false
 }
 
-func (condition *Condition) checkEqCount(data Data) bool {
-	var d any
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field)
-	if result.Exists() {
-		d = result.Value()
-	} else {
-		d = []string{}
-	}
+func (condition *Condition) checkEqCount(d any) bool {
 	valKind := reflect.ValueOf(d)
 	if valKind.Kind() != reflect.Slice {
 		if d == nil {
@@ -680,19 +620,7 @@ func (condition *Condition) checkEqCount(data Data) bool {
 	return valKind.Len() == gtVal && valKind.Len() != 0
 }
 
-func (condition *Condition) checkNeqCount(data Data) bool {
-	var d any
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field)
-	if result.Exists() {
-		d = result.Value()
-	} else {
-		d = []string{}
-	}
+func (condition *Condition) checkNeqCount(d any) bool {
 	valKind := reflect.ValueOf(d)
 	if valKind.Kind() != reflect.Slice {
 		if d == nil {
@@ -716,19 +644,7 @@ func (condition *Condition) checkNeqCount(data Data) bool {
 	return valKind.Len() != gtVal && valKind.Len() != 0
 }
 
-func (condition *Condition) checkGtCount(data Data) bool {
-	var d any
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field)
-	if result.Exists() {
-		d = result.Value()
-	} else {
-		d = []string{}
-	}
+func (condition *Condition) checkGtCount(d any) bool {
 	valKind := reflect.ValueOf(d)
 	if valKind.Kind() != reflect.Slice {
 		if d == nil {
@@ -752,19 +668,7 @@ func (condition *Condition) checkGtCount(data Data) bool {
 	return valKind.Len() > gtVal && valKind.Len() != 0
 }
 
-func (condition *Condition) checkGteCount(data Data) bool {
-	var d any
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field)
-	if result.Exists() {
-		d = result.Value()
-	} else {
-		d = []string{}
-	}
+func (condition *Condition) checkGteCount(d any) bool {
 	valKind := reflect.ValueOf(d)
 	if valKind.Kind() != reflect.Slice {
 		if d == nil {
@@ -788,19 +692,7 @@ func (condition *Condition) checkGteCount(data Data) bool {
 	return valKind.Len() >= gtVal && valKind.Len() != 0
 }
 
-func (condition *Condition) checkLtCount(data Data) bool {
-	var d any
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field)
-	if result.Exists() {
-		d = result.Value()
-	} else {
-		d = []string{}
-	}
+func (condition *Condition) checkLtCount(d any) bool {
 	valKind := reflect.ValueOf(d)
 	if valKind.Kind() != reflect.Slice {
 		if d == nil {
@@ -824,19 +716,7 @@ func (condition *Condition) checkLtCount(data Data) bool {
 	return valKind.Len() < gtVal && valKind.Len() != 0
 }
 
-func (condition *Condition) checkLteCount(data Data) bool {
-	var d any
-	// use sjson to get the Value
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		return false
-	}
-	result := sjson.GetBytes(dataJson, condition.Field)
-	if result.Exists() {
-		d = result.Value()
-	} else {
-		d = []string{}
-	}
+func (condition *Condition) checkLteCount(d any) bool {
 	valKind := reflect.ValueOf(d)
 	if valKind.Kind() != reflect.Slice {
 		if d == nil {
@@ -0,0 +1,282 @@
+package rule
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/oarkflow/pkg/timeutil"
+)
+
+// CompiledRule is a Rule prepared for repeated evaluation. Field accessors,
+// comparison values and lookups are resolved once, so validating a record
+// no longer marshals it to JSON. Results are identical to the Rule it was
+// compiled from; conditions that rely on lookup filters or on sjson path
+// syntax ("#", queries, modifiers) keep using Condition.Validate.
+//
+// A CompiledRule snapshots the conditions and the custom operators they use,
+// so it must be compiled again after the rule, its conditions or the
+// operator registry change.
+type CompiledRule struct {
+	rule       *Rule
+	conditions map[*Condition]*compiledCondition
+	copyData   bool
+}
+
+type compiledCondition struct {
+	condition *Condition
+	path      fieldPath
+	compare   func(val any) bool
+	fallback  bool
+}
+
+// Compile prepares r for repeated evaluation.
+func Compile(r *Rule) (*CompiledRule, error) {
+	if r == nil {
+		return nil, fmt.Errorf("rule: cannot compile a nil rule")
+	}
+	cr := &CompiledRule{
+		rule:       r,
+		conditions: make(map[*Condition]*compiledCondition),
+	}
+	for _, node := range r.conditionNodes() {
+		if node == nil {
+			continue
+		}
+		for _, condition := range node.Condition {
+			if condition == nil {
+				return nil, fmt.Errorf("rule: nil condition in rule %s", r.ID)
+			}
+			if _, ok := cr.conditions[condition]; ok {
+				continue
+			}
+			c := compileCondition(condition)
+			if c.fallback {
+				cr.copyData = true
+			}
+			cr.conditions[condition] = c
+		}
+	}
+	return cr, nil
+}
+
+// MustCompile is like Compile but panics if the rule cannot be compiled.
+func MustCompile(r *Rule) *CompiledRule {
+	cr, err := Compile(r)
+	if err != nil {
+		panic(err)
+	}
+	return cr
+}
+
+// Rule returns the rule cr was compiled from.
+func (cr *CompiledRule) Rule() *Rule {
+	return cr.rule
+}
+
+// Validate reports whether d satisfies the rule. Besides maps, d may be a
+// struct or a pointer to one; fields are matched by their JSON names.
+func (cr *CompiledRule) Validate(d Data) bool {
+	return cr.rule.validate(d, cr.check)
+}
+
+// Apply behaves like Rule.Apply.
+func (cr *CompiledRule) Apply(d Data, callback ...CallbackFn) (any, error) {
+	return cr.rule.applyWith(cr.check, cr.copyData, d, callback...)
+}
+
+func (cr *CompiledRule) check(condition *Condition, d Data) bool {
+	if c, ok := cr.conditions[condition]; ok {
+		return c.validate(d)
+	}
+	return condition.Validate(d)
+}
+
+func compileCondition(condition *Condition) *compiledCondition {
+	c := &compiledCondition{condition: condition}
+	if usesLookup(condition) {
+		if condition.Filter.LookupData == nil && condition.Filter.LookupHandler != nil {
+			condition.Filter.LookupData = condition.Filter.LookupHandler()
+		}
+		c.fallback = true
+		return c
+	}
+	path, ok := newFieldPath(condition.Field)
+	if !ok {
+		c.fallback = true
+		return c
+	}
+	c.path = path
+	c.compare = compileComparator(condition)
+	return c
+}
+
+// usesLookup reports whether Validate would run the lookup filter, which may
+// rewrite both the record and the condition value.
+func usesLookup(condition *Condition) bool {
+	if condition.Filter.Key != "" || condition.Filter.Condition != "" {
+		return true
+	}
+	switch v := condition.Value.(type) {
+	case Expr:
+		return v.Value != ""
+	case map[string]any:
+		t, ok := v["expr"].(string)
+		return ok && t != ""
+	}
+	return false
+}
+
+func (c *compiledCondition) validate(d Data) bool {
+	if c.fallback {
+		return c.condition.Validate(d)
+	}
+	val, exists, ok := c.path.resolve(d)
+	if !ok {
+		return c.condition.Validate(d)
+	}
+	if !exists {
+		return c.condition.Operator == IsNull
+	}
+	if c.condition.Operator == NotNull {
+		return notNull(val)
+	}
+	return c.compare(val)
+}
+
+// notNull is checkNotNull for plain dotted fields, where no "#" count exists.
+func notNull(val any) bool {
+	switch v := val.(type) {
+	case []any:
+		flat := flattenSlice(v)
+		return !slices.Contains(flat, nil) && len(flat) != 0
+	case map[string]any:
+		return len(v) != 0
+	}
+	return val != nil
+}
+
+// compileComparator returns the operator check with the condition value
+// converted up front where the outcome does not depend on the record.
+func compileComparator(condition *Condition) func(val any) bool {
+	generic := func(val any) bool {
+		return condition.check(val, nil)
+	}
+	switch condition.Operator {
+	case GT, LT, GTE, LTE:
+		return compileOrdering(condition.Operator, condition.Value, generic)
+	case EqCount, NeqCount, GtCount, GteCount, LtCount, LteCount:
+		return compileCount(condition.Operator, condition.Value)
+	}
+	if _, ok := builtinOperators[condition.Operator]; !ok {
+		fn, ok := LookupOperator(condition.Operator)
+		if !ok {
+			return func(any) bool { return false }
+		}
+		value := condition.Value
+		return func(val any) bool {
+			result, err := fn(val, value)
+			return err == nil && result
+		}
+	}
+	return generic
+}
+
+func compileOrdering(operator ConditionOperator, value any, generic func(val any) bool) func(val any) bool {
+	var target float64
+	switch v := value.(type) {
+	case int:
+		target = float64(v)
+	case float64:
+		target = v
+	case string:
+		t, err := timeutil.ParseTime(v)
+		if err != nil {
+			return func(any) bool { return false }
+		}
+		return func(val any) bool {
+			s, ok := val.(string)
+			if !ok {
+				return false
+			}
+			from, err := timeutil.ParseTime(s)
+			if err != nil {
+				return false
+			}
+			return compareTime(operator, from, t)
+		}
+	default:
+		return generic
+	}
+	return func(val any) bool {
+		f, ok := val.(float64)
+		if !ok {
+			return false
+		}
+		switch operator {
+		case GT:
+			return f > target
+		case LT:
+			return f < target
+		case GTE:
+			return f >= target
+		}
+		return f <= target
+	}
+}
+
+func compareTime(operator ConditionOperator, from, target time.Time) bool {
+	switch operator {
+	case GT:
+		return from.After(target)
+	case LT:
+		return from.Before(target)
+	case GTE:
+		return from.After(target) || from.Equal(target)
+	}
+	return from.Before(target) || from.Equal(target)
+}
+
+func compileCount(operator ConditionOperator, value any) func(val any) bool {
+	var target int
+	switch v := value.(type) {
+	case []any:
+		target = len(v)
+	default:
+		g, err := strconv.Atoi(fmt.Sprintf("%v", value))
+		if err != nil {
+			return func(any) bool { return false }
+		}
+		target = g
+	}
+	return func(val any) bool {
+		var n int
+		if s, ok := val.([]any); ok {
+			n = len(s)
+		} else if val == nil {
+			return false
+		} else if rv := reflect.ValueOf(val); rv.Kind() == reflect.Slice {
+			n = rv.Len()
+		} else {
+			n = 1
+		}
+		if n == 0 {
+			return false
+		}
+		switch operator {
+		case EqCount:
+			return n == target
+		case NeqCount:
+			return n != target
+		case GtCount:
+			return n > target
+		case GteCount:
+			return n >= target
+		case LtCount:
+			return n < target
+		}
+		return n <= target
+	}
+}
@@ -0,0 +1,177 @@
+package rule_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/pkg/rule"
+)
+
+func compileRecords() []map[string]any {
+	return []map[string]any{
+		{"name": "John", "age": 32, "salary": 25000.5, "active": true, "dob": "1989-04-19", "tags": []any{"a", "b"}, "address": map[string]any{"city": "Kathmandu", "zip": 44600}},
+		{"name": "michelle", "age": 17, "salary": 0, "active": false, "dob": "2006-10-01", "tags": []any{}, "address": map[string]any{"city": "Pokhara"}},
+		{"name": "Ram", "age": 45.0, "active": "true", "dob": "bad date", "tags": []any{"a", nil}, "address": nil},
+		{"name": nil, "age": "40", "tags": "single", "address": map[string]any{}},
+		{},
+	}
+}
+
+func compileConditions() []*rule.Condition {
+	return []*rule.Condition{
+		rule.NewCondition("name", rule.EQ, "john"),
+		rule.NewCondition("name", rule.NEQ, "ram"),
+		rule.NewCondition("age", rule.EQ, "32"),
+		rule.NewCondition("age", rule.GT, 18),
+		rule.NewCondition("age", rule.LT, 40.5),
+		rule.NewCondition("age", rule.GTE, 45),
+		rule.NewCondition("age", rule.LTE, "x"),
+		rule.NewCondition("salary", rule.IsZero, nil),
+		rule.NewCondition("salary", rule.NotZero, nil),
+		rule.NewCondition("active", rule.EQ, true),
+		rule.NewCondition("active", rule.NEQ, "false"),
+		rule.NewCondition("dob", rule.GT, "2000-01-01"),
+		rule.NewCondition("dob", rule.LTE, "1989-04-19"),
+		rule.NewCondition("dob", rule.BETWEEN, []string{"1980-01-01", "1990-01-01"}),
+		rule.NewCondition("age", rule.BETWEEN, []int{18, 40}),
+		rule.NewCondition("name", rule.IN, []string{"JOHN", "Ram"}),
+		rule.NewCondition("age", rule.NotIn, []any{17, 45}),
+		rule.NewCondition("tags", rule.IN, []any{"b"}),
+		rule.NewCondition("name", rule.CONTAINS, "oh"),
+		rule.NewCondition("name", rule.NotContains, "a"),
+		rule.NewCondition("name", rule.StartsWith, "mi"),
+		rule.NewCondition("name", rule.EndsWith, "m"),
+		rule.NewCondition("name", rule.IsNull, nil),
+		rule.NewCondition("address", rule.NotNull, nil),
+		rule.NewCondition("tags", rule.NotNull, nil),
+		rule.NewCondition("address.city", rule.EQ, "pokhara"),
+		rule.NewCondition("address.zip", rule.GT, 40000),
+		rule.NewCondition("tags.0", rule.EQ, "a"),
+		rule.NewCondition("tags", rule.EqCount, 2),
+		rule.NewCondition("tags", rule.GtCount, []any{1}),
+		rule.NewCondition("tags", rule.LteCount, "1"),
+		rule.NewCondition("missing", rule.IsNull, nil),
+		rule.NewCondition("missing", rule.NotNull, nil),
+	}
+}
+
+func TestCompileMatchesApply(t *testing.T) {
+	for _, condition := range compileConditions() {
+		for _, operator := range []rule.JoinOperator{rule.AND, rule.OR} {
+			r := rule.New()
+			r.And(rule.NewCondition("age", rule.NotNull, nil))
+			if operator == rule.AND {
+				r.And(condition)
+			} else {
+				r.Or(condition)
+			}
+			cr, err := rule.Compile(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, record := range compileRecords() {
+				want := r.Validate(record)
+				if got := cr.Validate(record); got != want {
+					t.Errorf("%s %s %v on record %d: got %v, want %v", condition.Field, condition.Operator, condition.Value, i, got, want)
+				}
+			}
+			want, wantErr := r.Apply(compileRecords())
+			got, gotErr := cr.Apply(compileRecords())
+			if !reflect.DeepEqual(got, want) || fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("%s %s: Apply differs: got %v, want %v", condition.Field, condition.Operator, got, want)
+			}
+		}
+	}
+}
+
+type compileAddress struct {
+	City string `json:"city"`
+	Zip  int    `json:"zip,omitempty"`
+}
+
+type compileBase struct {
+	ID int `json:"id"`
+}
+
+type compilePerson struct {
+	compileBase
+	Name    string          `json:"name"`
+	Age     int             `json:"age"`
+	Address *compileAddress `json:"address"`
+	Tags    []string        `json:"tags"`
+	secret  string
+}
+
+func TestCompileStructs(t *testing.T) {
+	people := []compilePerson{
+		{compileBase: compileBase{ID: 1}, Name: "John", Age: 32, Address: &compileAddress{City: "Kathmandu", Zip: 44600}, Tags: []string{"a"}},
+		{compileBase: compileBase{ID: 2}, Name: "Sita", Age: 17, Address: &compileAddress{City: "Pokhara"}},
+		{Name: "Hari", Age: 60},
+	}
+	for _, condition := range []*rule.Condition{
+		rule.NewCondition("id", rule.EQ, 1),
+		rule.NewCondition("age", rule.GTE, 18),
+		rule.NewCondition("address.city", rule.EQ, "pokhara"),
+		rule.NewCondition("address.zip", rule.IsNull, nil),
+		rule.NewCondition("address", rule.NotNull, nil),
+		rule.NewCondition("tags", rule.EqCount, 1),
+		rule.NewCondition("secret", rule.IsNull, nil),
+	} {
+		r := rule.New()
+		r.And(condition)
+		cr := rule.MustCompile(r)
+		for i, person := range people {
+			want := r.Validate(person)
+			if got := cr.Validate(person); got != want {
+				t.Errorf("%s %s on person %d: got %v, want %v", condition.Field, condition.Operator, i, got, want)
+			}
+			if got := cr.Validate(&person); got != want {
+				t.Errorf("%s %s on *person %d: got %v, want %v", condition.Field, condition.Operator, i, got, want)
+			}
+		}
+	}
+}
+
+func benchmarkRule() *rule.Rule {
+	r := rule.New()
+	r.And(
+		rule.NewCondition("age", rule.GTE, 18),
+		rule.NewCondition("address.city", rule.EQ, "kathmandu"),
+		rule.NewCondition("dob", rule.LT, "2000-01-01"),
+		rule.NewCondition("tags", rule.GteCount, 1),
+	)
+	return r
+}
+
+func benchmarkRows(n int) []map[string]any {
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		rows[i] = map[string]any{
+			"name":    fmt.Sprintf("user-%d", i),
+			"age":     i % 90,
+			"dob":     "1989-04-19",
+			"tags":    []any{"a", "b"},
+			"address": map[string]any{"city": "Kathmandu", "zip": 44600},
+		}
+	}
+	return rows
+}
+
+func BenchmarkRuleApply(b *testing.B) {
+	r := benchmarkRule()
+	rows := benchmarkRows(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = r.Apply(rows)
+	}
+}
+
+func BenchmarkCompiledRuleApply(b *testing.B) {
+	cr := rule.MustCompile(benchmarkRule())
+	rows := benchmarkRows(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = cr.Apply(rows)
+	}
+}
@@ -0,0 +1,506 @@
+package rule
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HitPolicy decides which rows of a DecisionTable produce outputs, as in DMN.
+type HitPolicy string
+
+const (
+	// HitFirst returns the output of the first matching row.
+	HitFirst HitPolicy = "FIRST"
+	// HitUnique requires rows not to overlap and returns the single match.
+	HitUnique HitPolicy = "UNIQUE"
+	// HitCollect returns the outputs of every matching row.
+	HitCollect HitPolicy = "COLLECT"
+	// HitPriority returns the output of the matching row with the highest
+	// "@priority" cell.
+	HitPriority HitPolicy = "PRIORITY"
+)
+
+// ErrNotUnique is returned when more than one row of a UNIQUE table, or
+// more than one rule of a MatchUnique group, matches.
+var ErrNotUnique = errors.New("rule: more than one rule matched")
+
+// maxGapCombinations bounds the input combinations checked for gaps.
+const maxGapCombinations = 100000
+
+// DecisionInput is an input column of a DecisionTable.
+type DecisionInput struct {
+	Field    string
+	Operator ConditionOperator
+}
+
+// DecisionRow is a row of a DecisionTable compiled into a rule.
+type DecisionRow struct {
+	Rule     *Rule
+	Output   map[string]any
+	Priority int
+	// Line is the CSV line the row was read from.
+	Line  int
+	cells []*Condition
+}
+
+// DecisionIssue reports a problem found while loading a table. Rows holds
+// the CSV lines involved; Example is an input that triggers the issue.
+type DecisionIssue struct {
+	Kind    string
+	Rows    []int
+	Example map[string]any
+}
+
+func (i DecisionIssue) String() string {
+	return fmt.Sprintf("%s rows %v for %v", i.Kind, i.Rows, i.Example)
+}
+
+// DecisionTable is a rule matrix loaded from CSV.
+//
+// The header row names the columns. An input column is a field optionally
+// followed by an operator, e.g. "age gte", "country" or "score >=", and
+// defaults to eq. Output columns start with "=>", e.g. "=> discount", and
+// "@priority" holds the row priority used by HitPriority. Cells are
+// numbers, booleans or strings; "-" or an empty cell matches anything, a
+// leading operator such as ">= 18" overrides the column operator, "18..30"
+// is a between range and "a|b" is a list for in, not_in and between.
+//
+// Overlapping rows and input combinations no row covers are reported in
+// Issues. The analysis is exact for equality and in cells and for numeric
+// ordering and between cells; other cells are approximated by the values
+// used in the table.
+type DecisionTable struct {
+	HitPolicy HitPolicy
+	Inputs    []DecisionInput
+	Outputs   []string
+	Rows      []*DecisionRow
+	Issues    []DecisionIssue
+}
+
+// LoadDecisionTable reads a decision table from CSV. Tables using
+// HitUnique are rejected when rows overlap.
+func LoadDecisionTable(r io.Reader, policy HitPolicy) (*DecisionTable, error) {
+	switch policy {
+	case HitFirst, HitUnique, HitCollect, HitPriority:
+	case "":
+		policy = HitFirst
+	default:
+		return nil, fmt.Errorf("rule: unknown hit policy %q", policy)
+	}
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("rule: reading decision table header: %w", err)
+	}
+	dt := &DecisionTable{HitPolicy: policy}
+	type column struct {
+		input    int
+		output   string
+		priority bool
+	}
+	columns := make([]column, len(header))
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		switch {
+		case h == "@priority":
+			columns[i] = column{input: -1, priority: true}
+		case strings.HasPrefix(h, "=>"):
+			name := strings.TrimSpace(strings.TrimPrefix(h, "=>"))
+			if name == "" {
+				return nil, fmt.Errorf("rule: decision table column %d has no output name", i+1)
+			}
+			columns[i] = column{input: -1, output: name}
+			dt.Outputs = append(dt.Outputs, name)
+		default:
+			field, op, _ := strings.Cut(h, " ")
+			if field == "" {
+				return nil, fmt.Errorf("rule: decision table column %d has no field", i+1)
+			}
+			operator := EQ
+			if op = strings.TrimSpace(op); op != "" {
+				operator = parseCellOperator(op)
+				if operator == "" {
+					return nil, fmt.Errorf("rule: decision table column %q has unknown operator %q", h, op)
+				}
+			}
+			columns[i] = column{input: len(dt.Inputs)}
+			dt.Inputs = append(dt.Inputs, DecisionInput{Field: field, Operator: operator})
+		}
+	}
+	if len(dt.Inputs) == 0 || len(dt.Outputs) == 0 {
+		return nil, fmt.Errorf("rule: decision table needs input and output columns")
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule: reading decision table: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := &DecisionRow{
+			Rule:   New(fmt.Sprintf("row-%d", line)),
+			Output: make(map[string]any),
+			Line:   line,
+			cells:  make([]*Condition, len(dt.Inputs)),
+		}
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			col := columns[i]
+			switch {
+			case col.priority:
+				if cell != "" {
+					p, err := strconv.Atoi(cell)
+					if err != nil {
+						return nil, fmt.Errorf("rule: line %d: invalid priority %q", line, cell)
+					}
+					row.Priority = p
+				}
+			case col.output != "":
+				row.Output[col.output] = parseCellValue(cell)
+			default:
+				condition, err := parseCell(dt.Inputs[col.input], cell)
+				if err != nil {
+					return nil, fmt.Errorf("rule: line %d: %w", line, err)
+				}
+				row.cells[col.input] = condition
+			}
+		}
+		var conditions []*Condition
+		for _, c := range row.cells {
+			if c != nil {
+				conditions = append(conditions, c)
+			}
+		}
+		if len(conditions) > 0 {
+			row.Rule.And(conditions...)
+		} else {
+			// a row of wildcards matches whatever the first field holds
+			field := dt.Inputs[0].Field
+			row.Rule.Or(NewCondition(field, IsNull, nil), NewCondition(field, IsZero, nil), NewCondition(field, NotZero, nil))
+		}
+		output := row.Output
+		row.Rule.OnSuccess(func(Data) any {
+			return output
+		})
+		dt.Rows = append(dt.Rows, row)
+	}
+	dt.analyze()
+	if policy == HitUnique {
+		for _, issue := range dt.Issues {
+			if issue.Kind == "overlap" {
+				return dt, fmt.Errorf("rule: UNIQUE decision table has overlapping rows %v", issue.Rows)
+			}
+		}
+	}
+	return dt, nil
+}
+
+var cellOperators = []struct {
+	symbol   string
+	operator ConditionOperator
+}{
+	{">=", GTE}, {"<=", LTE}, {"!=", NEQ}, {">", GT}, {"<", LT}, {"=", EQ},
+}
+
+func parseCellOperator(op string) ConditionOperator {
+	for _, c := range cellOperators {
+		if op == c.symbol {
+			return c.operator
+		}
+	}
+	operator := ConditionOperator(strings.ToLower(op))
+	if _, ok := builtinOperators[operator]; ok {
+		return operator
+	}
+	if _, ok := LookupOperator(operator); ok {
+		return operator
+	}
+	return ""
+}
+
+func parseCell(input DecisionInput, cell string) (*Condition, error) {
+	if cell == "" || cell == "-" {
+		return nil, nil
+	}
+	operator := input.Operator
+	for _, c := range cellOperators {
+		if strings.HasPrefix(cell, c.symbol) {
+			operator = c.operator
+			cell = strings.TrimSpace(cell[len(c.symbol):])
+			break
+		}
+	}
+	if from, to, ok := strings.Cut(cell, ".."); ok && operator == input.Operator {
+		operator = BETWEEN
+		cell = from + "|" + to
+	}
+	var value any
+	switch operator {
+	case IN, NotIn, BETWEEN, InFold:
+		value = parseCellList(strings.Split(cell, "|"))
+		if operator == BETWEEN {
+			if n := listLen(value); n != 2 {
+				return nil, fmt.Errorf("between on %s needs two bounds, got %d", input.Field, n)
+			}
+		}
+	default:
+		value = parseCellValue(cell)
+	}
+	return NewCondition(input.Field, operator, value), nil
+}
+
+func parseCellValue(cell string) any {
+	if len(cell) >= 2 && cell[0] == '"' && cell[len(cell)-1] == '"' {
+		return cell[1 : len(cell)-1]
+	}
+	if i, err := strconv.Atoi(cell); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil {
+		return f
+	}
+	if strings.EqualFold(cell, "true") || strings.EqualFold(cell, "false") {
+		return strings.EqualFold(cell, "true")
+	}
+	return cell
+}
+
+// parseCellList keeps lists typed the way the in and between checks expect
+// them: numbers as []float64, like the fields they are compared with.
+func parseCellList(items []string) any {
+	floats := make([]float64, 0, len(items))
+	strs := make([]string, 0, len(items))
+	numeric := true
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		strs = append(strs, item)
+		switch v := parseCellValue(item).(type) {
+		case int:
+			floats = append(floats, float64(v))
+		case float64:
+			floats = append(floats, v)
+		default:
+			numeric = false
+		}
+	}
+	if numeric {
+		return floats
+	}
+	return strs
+}
+
+func listLen(v any) int {
+	switch v := v.(type) {
+	case []float64:
+		return len(v)
+	case []string:
+		return len(v)
+	}
+	return 0
+}
+
+// Evaluate returns the outputs selected by the hit policy for data.
+func (dt *DecisionTable) Evaluate(data map[string]any) ([]map[string]any, error) {
+	var matched []*DecisionRow
+	for _, row := range dt.Rows {
+		if row.Rule.Validate(data) {
+			matched = append(matched, row)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	switch dt.HitPolicy {
+	case HitUnique:
+		if len(matched) > 1 {
+			return nil, fmt.Errorf("%w: lines %d and %d", ErrNotUnique, matched[0].Line, matched[1].Line)
+		}
+	case HitPriority:
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].Priority > matched[j].Priority })
+	case HitCollect:
+		outputs := make([]map[string]any, len(matched))
+		for i, row := range matched {
+			outputs[i] = row.Output
+		}
+		return outputs, nil
+	}
+	return []map[string]any{matched[0].Output}, nil
+}
+
+// GroupRule compiles the table into a rule group applying the hit policy;
+// each row's rule returns its output. FIRST and PRIORITY groups return the
+// output of the selected row, COLLECT groups the []any of the outputs of
+// every matching row and UNIQUE groups fail with ErrNotUnique when more
+// than one row matches.
+func (dt *DecisionTable) GroupRule() *GroupRule {
+	config := Config{Priority: HighestPriority}
+	switch dt.HitPolicy {
+	case HitCollect:
+		config.Match = MatchAll
+	case HitUnique:
+		config.Match = MatchUnique
+	}
+	group := NewRuleGroup(config)
+	for i, row := range dt.Rows {
+		priority := len(dt.Rows) - i
+		if dt.HitPolicy == HitPriority {
+			priority = row.Priority
+		}
+		group.AddRule(row.Rule, priority)
+	}
+	return group
+}
+
+// analyze records overlapping rows and uncovered input combinations. Each
+// column is reduced to representative values: the constants in its cells,
+// the points between them and values outside them. Two cells can match the
+// same input exactly when they share a representative.
+func (dt *DecisionTable) analyze() {
+	reps := make([][]any, len(dt.Inputs))
+	for i := range dt.Inputs {
+		reps[i] = dt.representatives(i)
+	}
+	matches := func(cell *Condition, v any) bool {
+		return cell == nil || cell.check(v, nil)
+	}
+	for a := 0; a < len(dt.Rows); a++ {
+		for b := a + 1; b < len(dt.Rows); b++ {
+			example := make(map[string]any)
+			overlap := true
+			for col, values := range reps {
+				shared := false
+				for _, v := range values {
+					if matches(dt.Rows[a].cells[col], v) && matches(dt.Rows[b].cells[col], v) {
+						example[dt.Inputs[col].Field] = v
+						shared = true
+						break
+					}
+				}
+				if !shared {
+					overlap = false
+					break
+				}
+			}
+			if overlap {
+				dt.Issues = append(dt.Issues, DecisionIssue{Kind: "overlap", Rows: []int{dt.Rows[a].Line, dt.Rows[b].Line}, Example: example})
+			}
+		}
+	}
+	combinations := 1
+	for _, values := range reps {
+		combinations *= len(values)
+		if combinations > maxGapCombinations {
+			return
+		}
+	}
+	index := make([]int, len(reps))
+	for {
+		covered := false
+		for _, row := range dt.Rows {
+			all := true
+			for col, cell := range row.cells {
+				if !matches(cell, reps[col][index[col]]) {
+					all = false
+					break
+				}
+			}
+			if all {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			example := make(map[string]any)
+			for col, i := range index {
+				example[dt.Inputs[col].Field] = reps[col][i]
+			}
+			dt.Issues = append(dt.Issues, DecisionIssue{Kind: "gap", Example: example})
+		}
+		col := 0
+		for ; col < len(index); col++ {
+			index[col]++
+			if index[col] < len(reps[col]) {
+				break
+			}
+			index[col] = 0
+		}
+		if col == len(index) {
+			return
+		}
+	}
+}
+
+// otherValue stands for any string not mentioned in a column.
+const otherValue = "\x00other"
+
+func (dt *DecisionTable) representatives(col int) []any {
+	var numbers []float64
+	var strs []string
+	hasBool := false
+	add := func(v any) {
+		switch v := v.(type) {
+		case int:
+			numbers = append(numbers, float64(v))
+		case float64:
+			numbers = append(numbers, v)
+		case bool:
+			hasBool = true
+		case string:
+			strs = append(strs, v)
+		}
+	}
+	for _, row := range dt.Rows {
+		cell := row.cells[col]
+		if cell == nil {
+			continue
+		}
+		switch v := cell.Value.(type) {
+		case []float64:
+			for _, x := range v {
+				add(x)
+			}
+		case []string:
+			for _, x := range v {
+				add(x)
+			}
+		default:
+			add(v)
+		}
+	}
+	var reps []any
+	if len(numbers) > 0 {
+		sort.Float64s(numbers)
+		reps = append(reps, numbers[0]-1)
+		for i, n := range numbers {
+			if i > 0 && n == numbers[i-1] {
+				continue
+			}
+			if i > 0 {
+				reps = append(reps, numbers[i-1]+(n-numbers[i-1])/2)
+			}
+			reps = append(reps, n)
+		}
+		reps = append(reps, numbers[len(numbers)-1]+1)
+	}
+	if hasBool {
+		reps = append(reps, true, false)
+	}
+	sort.Strings(strs)
+	for i, s := range strs {
+		if i == 0 || s != strs[i-1] {
+			reps = append(reps, s)
+		}
+	}
+	if len(strs) > 0 || len(reps) == 0 {
+		reps = append(reps, otherValue)
+	}
+	return reps
+}
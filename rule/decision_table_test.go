@@ -0,0 +1,123 @@
+package rule_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/pkg/rule"
+)
+
+const eligibilityTable = `age gte,country,plan in,=> eligible,=> discount,@priority
+18,NP,basic|pro,true,5,1
+65,NP,-,true,20,3
+18,IN,pro,true,10,2
+-,-,-,false,0,0
+`
+
+func TestDecisionTable(t *testing.T) {
+	dt, err := rule.LoadDecisionTable(strings.NewReader(eligibilityTable), rule.HitPriority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		data map[string]any
+		want map[string]any
+	}{
+		{map[string]any{"age": 30, "country": "NP", "plan": "pro"}, map[string]any{"eligible": true, "discount": 5}},
+		{map[string]any{"age": 70, "country": "NP", "plan": "pro"}, map[string]any{"eligible": true, "discount": 20}},
+		{map[string]any{"age": 30, "country": "IN", "plan": "basic"}, map[string]any{"eligible": false, "discount": 0}},
+		{map[string]any{}, map[string]any{"eligible": false, "discount": 0}},
+	}
+	group := dt.GroupRule()
+	for _, tt := range tests {
+		outputs, err := dt.Evaluate(tt.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(outputs) != 1 || !reflect.DeepEqual(outputs[0], tt.want) {
+			t.Errorf("Evaluate(%v) = %v, want %v", tt.data, outputs, tt.want)
+		}
+		got, err := group.Apply(tt.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GroupRule.Apply(%v) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+
+	collect, err := rule.LoadDecisionTable(strings.NewReader(eligibilityTable), rule.HitCollect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, _ := collect.Evaluate(map[string]any{"age": 70, "country": "NP", "plan": "pro"})
+	if len(outputs) != 3 {
+		t.Errorf("COLLECT returned %v", outputs)
+	}
+	got, err := collect.GroupRule().Apply(map[string]any{"age": 70, "country": "NP", "plan": "pro"})
+	if all, ok := got.([]any); err != nil || !ok || len(all) != 3 || !reflect.DeepEqual(all[0], outputs[0]) {
+		t.Errorf("COLLECT GroupRule.Apply returned %v, %v", got, err)
+	}
+	got, err = collect.GroupRule().Apply([]map[string]any{{"age": 70, "country": "NP", "plan": "pro"}})
+	if all, ok := got.([]any); err != nil || !ok || len(all) != 3 {
+		t.Errorf("COLLECT GroupRule.Apply on a list returned %v, %v", got, err)
+	}
+}
+
+func TestDecisionTableIssues(t *testing.T) {
+	table := `score,=> grade
+< 50,F
+50..75,C
+>= 70,A
+`
+	_, err := rule.LoadDecisionTable(strings.NewReader(table), rule.HitUnique)
+	if err == nil {
+		t.Fatal("expected overlapping rows to be rejected")
+	}
+	dt, err := rule.LoadDecisionTable(strings.NewReader(table), rule.HitFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dt.Issues) != 1 || dt.Issues[0].Kind != "overlap" || !reflect.DeepEqual(dt.Issues[0].Rows, []int{3, 4}) {
+		t.Errorf("issues %v", dt.Issues)
+	}
+
+	gaps := `country,=> region
+NP,asia
+IN,asia
+`
+	dt, err = rule.LoadDecisionTable(strings.NewReader(gaps), rule.HitUnique)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dt.Issues) != 1 || dt.Issues[0].Kind != "gap" {
+		t.Errorf("issues %v", dt.Issues)
+	}
+	if got, err := dt.GroupRule().Apply(map[string]any{"country": "IN"}); err != nil || !reflect.DeepEqual(got, map[string]any{"region": "asia"}) {
+		t.Errorf("UNIQUE GroupRule.Apply returned %v, %v", got, err)
+	}
+	// rows matching no record of a list don't count as matches
+	if got, err := dt.GroupRule().Apply([]map[string]any{{"country": "IN"}}); err != nil || !reflect.DeepEqual(got, map[string]any{"region": "asia"}) {
+		t.Errorf("UNIQUE GroupRule.Apply on a list returned %v, %v", got, err)
+	}
+	if got, err := dt.GroupRule().Apply([]any{map[string]any{"country": "US"}}); err != nil || got != nil {
+		t.Errorf("UNIQUE GroupRule.Apply on a list without a match returned %v, %v", got, err)
+	}
+	dt.HitPolicy = rule.HitCollect
+	if got, err := dt.GroupRule().Apply([]map[string]any{{"country": "US"}}); err != nil || got != nil {
+		t.Errorf("COLLECT GroupRule.Apply on a list without a match returned %v, %v", got, err)
+	}
+	dt, _ = rule.LoadDecisionTable(strings.NewReader("a,=> b\n1,x\n1,y\n"), rule.HitFirst)
+	dt.HitPolicy = rule.HitUnique
+	if _, err := dt.Evaluate(map[string]any{"a": 1}); !errors.Is(err, rule.ErrNotUnique) {
+		t.Errorf("got %v, want ErrNotUnique", err)
+	}
+	if _, err := dt.GroupRule().Apply(map[string]any{"a": 1}); !errors.Is(err, rule.ErrNotUnique) {
+		t.Errorf("GroupRule.Apply: got %v, want ErrNotUnique", err)
+	}
+	if got, err := dt.GroupRule().Apply(map[string]any{"a": 2}); err != nil || got != nil {
+		t.Errorf("GroupRule.Apply without a match: got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,280 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/oarkflow/pkg/dipper"
+	"github.com/oarkflow/pkg/maputil"
+)
+
+// ConflictStrategy decides which activation fires when several rules match
+// the working memory in the same cycle.
+type ConflictStrategy int
+
+const (
+	// BySalience fires the rule with the highest priority first, breaking
+	// ties by recency.
+	BySalience ConflictStrategy = iota
+	// ByRecency fires the rule whose conditions reference the most recently
+	// modified facts first, breaking ties by priority.
+	ByRecency
+)
+
+// DefaultMaxCycles bounds GroupRule.Run when Config.MaxCycles is not set.
+const DefaultMaxCycles = 1000
+
+// ErrMaxCycles is returned by GroupRule.Run when rules keep firing without
+// the working memory reaching a fixpoint.
+var ErrMaxCycles = errors.New("rule: max cycles reached")
+
+// Action mutates the working memory when its rule fires.
+type Action func(wm *WorkingMemory) error
+
+// Then adds actions run by GroupRule.Run each time the rule fires.
+func (r *Rule) Then(actions ...Action) *Rule {
+	r.actions = append(r.actions, actions...)
+	return r
+}
+
+// SetFact returns an action that sets field to value.
+func SetFact(field string, value any) Action {
+	return func(wm *WorkingMemory) error {
+		return wm.Set(field, value)
+	}
+}
+
+// RetractFact returns an action that removes field.
+func RetractFact(field string) Action {
+	return func(wm *WorkingMemory) error {
+		return wm.Delete(field)
+	}
+}
+
+// WorkingMemory is the fact set rules match against during GroupRule.Run.
+// Every modification is stamped so conflict resolution can prefer rules
+// depending on recent facts.
+type WorkingMemory struct {
+	facts  map[string]any
+	stamps map[string]int
+	clock  int
+}
+
+func newWorkingMemory(facts map[string]any) *WorkingMemory {
+	return &WorkingMemory{
+		facts:  maputil.CopyMap(facts),
+		stamps: make(map[string]int),
+	}
+}
+
+// Facts returns the current facts.
+func (wm *WorkingMemory) Facts() map[string]any {
+	return wm.facts
+}
+
+// Get returns the value of a dotted field, or nil when it does not exist.
+func (wm *WorkingMemory) Get(field string) any {
+	v := dipper.Get(wm.facts, field)
+	if dipper.Error(v) != nil {
+		return nil
+	}
+	return v
+}
+
+// Set stores value under a dotted field, creating intermediate objects as
+// needed. Setting a field to the value it already holds is not a change.
+func (wm *WorkingMemory) Set(field string, value any) error {
+	if field == "" {
+		return fmt.Errorf("rule: fact field is empty")
+	}
+	path := strings.Split(field, ".")
+	m := wm.facts
+	for i, key := range path[:len(path)-1] {
+		next, ok := m[key]
+		if !ok || next == nil {
+			child := make(map[string]any)
+			m[key] = child
+			m = child
+			continue
+		}
+		child, ok := next.(map[string]any)
+		if !ok {
+			if reflect.DeepEqual(dipper.Get(wm.facts, field), value) {
+				return nil
+			}
+			if err := dipper.Set(wm.facts, field, value); err != nil {
+				return fmt.Errorf("rule: cannot set fact %s below %s: %w", field, strings.Join(path[:i+1], "."), err)
+			}
+			wm.touch(field)
+			return nil
+		}
+		m = child
+	}
+	last := path[len(path)-1]
+	if old, ok := m[last]; ok && reflect.DeepEqual(old, value) {
+		return nil
+	}
+	m[last] = value
+	wm.touch(field)
+	return nil
+}
+
+// Delete removes a dotted field if it exists.
+func (wm *WorkingMemory) Delete(field string) error {
+	path := strings.Split(field, ".")
+	m := wm.facts
+	for _, key := range path[:len(path)-1] {
+		child, ok := m[key].(map[string]any)
+		if !ok {
+			return nil
+		}
+		m = child
+	}
+	last := path[len(path)-1]
+	if _, ok := m[last]; !ok {
+		return nil
+	}
+	delete(m, last)
+	wm.touch(field)
+	return nil
+}
+
+func (wm *WorkingMemory) touch(field string) {
+	wm.clock++
+	wm.stamps[field] = wm.clock
+}
+
+// recency is the latest stamp among the facts the rule's conditions read,
+// including changes to their parents and children.
+func (wm *WorkingMemory) recency(fields []string) int {
+	latest := 0
+	for _, field := range fields {
+		for changed, stamp := range wm.stamps {
+			if stamp > latest && overlaps(field, changed) {
+				latest = stamp
+			}
+		}
+	}
+	return latest
+}
+
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+// RunResult describes a GroupRule.Run.
+type RunResult struct {
+	Facts  map[string]any
+	Fired  []string
+	Cycles int
+}
+
+type activation struct {
+	rule     *PriorityRule
+	compiled *CompiledRule
+	fields   []string
+	order    int
+	// firedAt is the working memory clock the rule last fired on; a rule
+	// is not reactivated until the facts it reads change (refraction).
+	firedAt int
+	fired   bool
+}
+
+// Run executes the group as a forward-chaining production system. Each
+// cycle every rule is matched against the working memory, one activation is
+// picked using Config.Strategy and its actions are run. A rule that fired is
+// not reactivated until the facts it reads change, so a rule updating its
+// own inputs fires again. Run stops at a fixpoint, when no rule is
+// activated, and fails with ErrMaxCycles after Config.MaxCycles firings.
+// Actions work on a copy of the facts' objects.
+func (r *GroupRule) Run(facts map[string]any) (*RunResult, error) {
+	r.mu.RLock()
+	rules := make([]*activation, 0, len(r.Rules))
+	for i, pr := range r.Rules {
+		compiled, err := Compile(pr.Rule)
+		if err != nil {
+			r.mu.RUnlock()
+			return nil, err
+		}
+		rules = append(rules, &activation{rule: pr, compiled: compiled, fields: ruleFields(pr.Rule), order: i})
+	}
+	strategy, maxCycles := r.config.Strategy, r.config.MaxCycles
+	r.mu.RUnlock()
+	if maxCycles <= 0 {
+		maxCycles = DefaultMaxCycles
+	}
+
+	wm := newWorkingMemory(facts)
+	result := &RunResult{}
+	for {
+		var agenda []*activation
+		for _, a := range rules {
+			if a.fired && wm.recency(a.fields) <= a.firedAt {
+				continue
+			}
+			if a.compiled.Validate(wm.facts) {
+				agenda = append(agenda, a)
+			}
+		}
+		if len(agenda) == 0 {
+			result.Facts = wm.facts
+			return result, nil
+		}
+		if result.Cycles == maxCycles {
+			result.Facts = wm.facts
+			return result, fmt.Errorf("%w (%d)", ErrMaxCycles, maxCycles)
+		}
+		sort.SliceStable(agenda, func(i, j int) bool {
+			return agenda[i].before(agenda[j], wm, strategy)
+		})
+		selected := agenda[0]
+		selected.fired = true
+		selected.firedAt = wm.clock
+		result.Cycles++
+		result.Fired = append(result.Fired, selected.rule.Rule.ID)
+		for _, action := range selected.rule.Rule.actions {
+			if err := action(wm); err != nil {
+				result.Facts = wm.facts
+				return result, fmt.Errorf("rule: action of %s failed: %w", selected.rule.Rule.ID, err)
+			}
+		}
+	}
+}
+
+func (a *activation) before(b *activation, wm *WorkingMemory, strategy ConflictStrategy) bool {
+	ra, rb := wm.recency(a.fields), wm.recency(b.fields)
+	pa, pb := a.rule.Priority, b.rule.Priority
+	if strategy == ByRecency {
+		if ra != rb {
+			return ra > rb
+		}
+		if pa != pb {
+			return pa > pb
+		}
+	} else {
+		if pa != pb {
+			return pa > pb
+		}
+		if ra != rb {
+			return ra > rb
+		}
+	}
+	return a.order < b.order
+}
+
+// ruleFields lists the fields read by the rule's conditions.
+func ruleFields(r *Rule) []string {
+	var fields []string
+	for _, node := range r.conditionNodes() {
+		if node == nil {
+			continue
+		}
+		for _, condition := range node.Condition {
+			fields = append(fields, condition.Field)
+		}
+	}
+	return fields
+}
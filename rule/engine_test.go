@@ -0,0 +1,92 @@
+package rule_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/pkg/rule"
+)
+
+func TestGroupRuleRunChainsRules(t *testing.T) {
+	gold := rule.New("gold")
+	gold.And(rule.NewCondition("customer.tier", rule.EQ, "gold"))
+	gold.Then(rule.SetFact("order.discount", 10))
+
+	shipping := rule.New("free-shipping")
+	shipping.And(rule.NewCondition("order.discount", rule.GTE, 10))
+	shipping.Then(rule.SetFact("order.free_shipping", true))
+
+	eligible := rule.New("eligible")
+	eligible.And(rule.NewCondition("order.free_shipping", rule.EQ, true))
+	eligible.Then(rule.SetFact("eligible", true), rule.RetractFact("order.discount"))
+
+	group := rule.NewRuleGroup()
+	group.AddRule(eligible, 1)
+	group.AddRule(shipping, 2)
+	group.AddRule(gold, 3)
+
+	facts := map[string]any{"customer": map[string]any{"tier": "gold"}, "order": map[string]any{"total": 120}}
+	result, err := group.Run(facts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"gold", "free-shipping", "eligible"}; !reflect.DeepEqual(result.Fired, want) {
+		t.Errorf("fired %v, want %v", result.Fired, want)
+	}
+	if result.Facts["eligible"] != true {
+		t.Errorf("facts %v", result.Facts)
+	}
+	if _, ok := facts["order"].(map[string]any)["discount"]; ok {
+		t.Error("input facts were modified")
+	}
+}
+
+func TestGroupRuleRunConflictResolution(t *testing.T) {
+	build := func(strategy rule.ConflictStrategy) *rule.GroupRule {
+		seed := rule.New("seed")
+		seed.And(rule.NewCondition("start", rule.EQ, true))
+		seed.Then(rule.SetFact("fresh", 1))
+
+		old := rule.New("old")
+		old.And(rule.NewCondition("start", rule.EQ, true))
+
+		recent := rule.New("recent")
+		recent.And(rule.NewCondition("fresh", rule.EQ, 1))
+
+		group := rule.NewRuleGroup(rule.Config{Strategy: strategy})
+		group.AddRule(seed, 10)
+		group.AddRule(old, 5)
+		group.AddRule(recent, 1)
+		return group
+	}
+	for strategy, want := range map[rule.ConflictStrategy][]string{
+		rule.BySalience: {"seed", "old", "recent"},
+		rule.ByRecency:  {"seed", "recent", "old"},
+	} {
+		result, err := build(strategy).Run(map[string]any{"start": true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Fired, want) {
+			t.Errorf("strategy %d fired %v, want %v", strategy, result.Fired, want)
+		}
+	}
+}
+
+func TestGroupRuleRunMaxCycles(t *testing.T) {
+	loop := rule.New("loop")
+	loop.And(rule.NewCondition("n", rule.GTE, 0))
+	loop.Then(func(wm *rule.WorkingMemory) error {
+		return wm.Set("n", wm.Get("n").(int)+1)
+	})
+	group := rule.NewRuleGroup(rule.Config{MaxCycles: 5})
+	group.AddRule(loop, 1)
+	result, err := group.Run(map[string]any{"n": 0})
+	if !errors.Is(err, rule.ErrMaxCycles) {
+		t.Fatalf("got %v, want ErrMaxCycles", err)
+	}
+	if result.Cycles != 5 || result.Facts["n"] != 5 {
+		t.Errorf("cycles %d, facts %v", result.Cycles, result.Facts)
+	}
+}
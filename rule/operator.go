@@ -37,3 +37,19 @@ const (
 	IsNull      ConditionOperator = "is_null"
 	NotNull     ConditionOperator = "not_null"
 )
+
+// Operators registered through RegisterOperator by this package.
+const (
+	Matches         ConditionOperator = "matches"
+	Before          ConditionOperator = "before"
+	After           ConditionOperator = "after"
+	WithinDays      ConditionOperator = "within_days"
+	GeoWithin       ConditionOperator = "geo_within"
+	SemverGte       ConditionOperator = "semver_gte"
+	EqFold          ConditionOperator = "eq_ci"
+	ContainsFold    ConditionOperator = "contains_ci"
+	NotContainsFold ConditionOperator = "not_contains_ci"
+	StartsWithFold  ConditionOperator = "starts_with_ci"
+	EndsWithFold    ConditionOperator = "ends_with_ci"
+	InFold          ConditionOperator = "in_ci"
+)
@@ -0,0 +1,428 @@
+package rule
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oarkflow/pkg/cache/policy/lru"
+	"github.com/oarkflow/pkg/dateparse"
+)
+
+func init() {
+	for name, fn := range map[ConditionOperator]OperatorFunc{
+		Matches:         matchesOperator,
+		Before:          beforeOperator,
+		After:           afterOperator,
+		WithinDays:      withinDaysOperator,
+		GeoWithin:       geoWithinOperator,
+		SemverGte:       semverGteOperator,
+		EqFold:          eqFoldOperator,
+		ContainsFold:    containsFoldOperator,
+		NotContainsFold: notContainsFoldOperator,
+		StartsWithFold:  startsWithFoldOperator,
+		EndsWithFold:    endsWithFoldOperator,
+		InFold:          inFoldOperator,
+	} {
+		if err := RegisterOperator(name, fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// regexCacheSize bounds the patterns kept compiled; patterns come from rule
+// data, so the least recently used ones are dropped.
+const regexCacheSize = 256
+
+var regexCache = struct {
+	sync.Mutex
+	*lru.Cache[string, *regexp.Regexp]
+}{Cache: lru.NewCache[string, *regexp.Regexp](lru.WithCapacity(regexCacheSize))}
+
+func compiledRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	re, ok := regexCache.Get(pattern)
+	regexCache.Unlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Lock()
+	regexCache.Set(pattern, re)
+	regexCache.Unlock()
+	return re, nil
+}
+
+// scalarString formats numbers, strings and booleans; other values have no
+// string form for the string operators.
+func scalarString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func matchesOperator(fieldVal, condVal any) (bool, error) {
+	pattern, ok := condVal.(string)
+	if !ok {
+		return false, fmt.Errorf("rule: matches expects a pattern string, got %T", condVal)
+	}
+	re, err := compiledRegex(pattern)
+	if err != nil {
+		return false, err
+	}
+	s, ok := scalarString(fieldVal)
+	if !ok {
+		return false, nil
+	}
+	return re.MatchString(s), nil
+}
+
+// toTime accepts time.Time, date strings in any layout dateparse knows,
+// "now", and unix seconds.
+func toTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if strings.EqualFold(v, "now") {
+			return time.Now(), nil
+		}
+		return dateparse.ParseAny(v)
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	case int:
+		return time.Unix(int64(v), 0), nil
+	case int64:
+		return time.Unix(v, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("rule: %v is not a date", v)
+}
+
+func beforeOperator(fieldVal, condVal any) (bool, error) {
+	target, err := toTime(condVal)
+	if err != nil {
+		return false, err
+	}
+	t, err := toTime(fieldVal)
+	if err != nil {
+		return false, nil
+	}
+	return t.Before(target), nil
+}
+
+func afterOperator(fieldVal, condVal any) (bool, error) {
+	target, err := toTime(condVal)
+	if err != nil {
+		return false, err
+	}
+	t, err := toTime(fieldVal)
+	if err != nil {
+		return false, nil
+	}
+	return t.After(target), nil
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// withinDaysOperator reports whether the field date lies within the given
+// number of days from now, in either direction.
+func withinDaysOperator(fieldVal, condVal any) (bool, error) {
+	days, ok := toFloat(condVal)
+	if !ok || days < 0 {
+		return false, fmt.Errorf("rule: within_days expects a non negative number of days, got %v", condVal)
+	}
+	t, err := toTime(fieldVal)
+	if err != nil {
+		return false, nil
+	}
+	diff := time.Since(t)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Duration(days*float64(24*time.Hour)), nil
+}
+
+type geoPoint struct {
+	lat, lng float64
+}
+
+// toGeoPoint reads {"lat": .., "lng"|"lon": ..} maps and [lat, lng] pairs.
+func toGeoPoint(v any) (geoPoint, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		lat, ok := toFloat(v["lat"])
+		if !ok {
+			return geoPoint{}, false
+		}
+		lng, ok := toFloat(v["lng"])
+		if !ok {
+			lng, ok = toFloat(v["lon"])
+		}
+		return geoPoint{lat, lng}, ok
+	case []any:
+		if len(v) != 2 {
+			return geoPoint{}, false
+		}
+		lat, ok1 := toFloat(v[0])
+		lng, ok2 := toFloat(v[1])
+		return geoPoint{lat, lng}, ok1 && ok2
+	case []float64:
+		if len(v) != 2 {
+			return geoPoint{}, false
+		}
+		return geoPoint{v[0], v[1]}, true
+	}
+	return geoPoint{}, false
+}
+
+const earthRadiusKm = 6371.0
+
+func haversineKm(a, b geoPoint) float64 {
+	rad := math.Pi / 180
+	dLat := (b.lat - a.lat) * rad
+	dLng := (b.lng - a.lng) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.lat*rad)*math.Cos(b.lat*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// geoWithinOperator checks the field point against either a circle,
+// {"lat": .., "lng": .., "radius_km": ..}, or a polygon given as a list of
+// [lat, lng] vertices.
+func geoWithinOperator(fieldVal, condVal any) (bool, error) {
+	var area []any
+	switch c := condVal.(type) {
+	case map[string]any:
+		center, ok := toGeoPoint(c)
+		radius, ok2 := toFloat(c["radius_km"])
+		if !ok || !ok2 {
+			return false, fmt.Errorf("rule: geo_within circle needs lat, lng and radius_km")
+		}
+		p, ok := toGeoPoint(fieldVal)
+		if !ok {
+			return false, nil
+		}
+		return haversineKm(center, p) <= radius, nil
+	case []any:
+		area = c
+	case [][]float64:
+		for _, v := range c {
+			area = append(area, v)
+		}
+	default:
+		return false, fmt.Errorf("rule: geo_within expects a circle or a polygon, got %T", condVal)
+	}
+	if len(area) < 3 {
+		return false, fmt.Errorf("rule: geo_within polygon needs at least 3 vertices")
+	}
+	polygon := make([]geoPoint, len(area))
+	for i, v := range area {
+		p, ok := toGeoPoint(v)
+		if !ok {
+			return false, fmt.Errorf("rule: geo_within vertex %d is not a point", i)
+		}
+		polygon[i] = p
+	}
+	p, ok := toGeoPoint(fieldVal)
+	if !ok {
+		return false, nil
+	}
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.lat > p.lat) != (b.lat > p.lat) &&
+			p.lng < (b.lng-a.lng)*(p.lat-a.lat)/(b.lat-a.lat)+a.lng {
+			inside = !inside
+		}
+	}
+	return inside, nil
+}
+
+type semver struct {
+	parts      [3]int
+	prerelease []string
+}
+
+func parseSemver(s string) (semver, bool) {
+	var v semver
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	core, pre, hasPre := strings.Cut(s, "-")
+	nums := strings.Split(core, ".")
+	if len(nums) == 0 || len(nums) > 3 {
+		return v, false
+	}
+	for i, n := range nums {
+		p, err := strconv.Atoi(n)
+		if err != nil || p < 0 {
+			return v, false
+		}
+		v.parts[i] = p
+	}
+	if hasPre {
+		if pre == "" {
+			return v, false
+		}
+		v.prerelease = strings.Split(pre, ".")
+	}
+	return v, true
+}
+
+// compare orders versions following semver precedence rules.
+func (v semver) compare(o semver) int {
+	for i := range v.parts {
+		if v.parts[i] != o.parts[i] {
+			if v.parts[i] < o.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(v.prerelease) == 0 && len(o.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(o.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		a, b := v.prerelease[i], o.prerelease[i]
+		if a == b {
+			continue
+		}
+		an, aErr := strconv.Atoi(a)
+		bn, bErr := strconv.Atoi(b)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(v.prerelease) < len(o.prerelease):
+		return -1
+	case len(v.prerelease) > len(o.prerelease):
+		return 1
+	}
+	return 0
+}
+
+func semverGteOperator(fieldVal, condVal any) (bool, error) {
+	c, ok := condVal.(string)
+	if !ok {
+		return false, fmt.Errorf("rule: semver_gte expects a version string, got %T", condVal)
+	}
+	target, ok := parseSemver(c)
+	if !ok {
+		return false, fmt.Errorf("rule: %q is not a semantic version", c)
+	}
+	s, ok := fieldVal.(string)
+	if !ok {
+		return false, nil
+	}
+	v, ok := parseSemver(s)
+	if !ok {
+		return false, nil
+	}
+	return v.compare(target) >= 0, nil
+}
+
+func foldOperands(fieldVal, condVal any) (string, string, bool) {
+	s, ok := scalarString(fieldVal)
+	if !ok {
+		return "", "", false
+	}
+	c, ok := scalarString(condVal)
+	if !ok {
+		return "", "", false
+	}
+	return strings.ToLower(s), strings.ToLower(c), true
+}
+
+func eqFoldOperator(fieldVal, condVal any) (bool, error) {
+	s, c, ok := foldOperands(fieldVal, condVal)
+	return ok && s == c, nil
+}
+
+func containsFoldOperator(fieldVal, condVal any) (bool, error) {
+	s, c, ok := foldOperands(fieldVal, condVal)
+	return ok && strings.Contains(s, c), nil
+}
+
+func notContainsFoldOperator(fieldVal, condVal any) (bool, error) {
+	s, c, ok := foldOperands(fieldVal, condVal)
+	return ok && !strings.Contains(s, c), nil
+}
+
+func startsWithFoldOperator(fieldVal, condVal any) (bool, error) {
+	s, c, ok := foldOperands(fieldVal, condVal)
+	return ok && strings.HasPrefix(s, c), nil
+}
+
+func endsWithFoldOperator(fieldVal, condVal any) (bool, error) {
+	s, c, ok := foldOperands(fieldVal, condVal)
+	return ok && strings.HasSuffix(s, c), nil
+}
+
+func inFoldOperator(fieldVal, condVal any) (bool, error) {
+	var list []any
+	switch c := condVal.(type) {
+	case []any:
+		list = c
+	case []string:
+		for _, v := range c {
+			list = append(list, v)
+		}
+	default:
+		return false, fmt.Errorf("rule: in_ci expects a list, got %T", condVal)
+	}
+	for _, v := range list {
+		if ok, _ := eqFoldOperator(fieldVal, v); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,76 @@
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// OperatorFunc evaluates a custom condition operator. fieldVal is the
+// resolved field in its JSON form (string, float64, bool, nil, []any or
+// map[string]any) and condVal is the condition value as configured. An
+// error is treated as a failed condition.
+type OperatorFunc func(fieldVal, condVal any) (bool, error)
+
+var builtinOperators = map[ConditionOperator]struct{}{
+	EQ: {}, NEQ: {}, GT: {}, LT: {}, GTE: {}, LTE: {},
+	EqCount: {}, NeqCount: {}, GtCount: {}, LtCount: {}, GteCount: {}, LteCount: {},
+	BETWEEN: {}, IN: {}, NotIn: {}, CONTAINS: {}, NotContains: {},
+	StartsWith: {}, EndsWith: {}, NotZero: {}, IsZero: {}, IsNull: {}, NotNull: {},
+}
+
+var registry = struct {
+	sync.RWMutex
+	operators map[ConditionOperator]OperatorFunc
+}{operators: make(map[ConditionOperator]OperatorFunc)}
+
+// RegisterOperator makes a custom operator available to conditions, whether
+// they are built in code or decoded from JSON. Registering a name again
+// replaces the previous function; the built-in operators cannot be replaced.
+func RegisterOperator(name ConditionOperator, fn OperatorFunc) error {
+	if name == "" {
+		return fmt.Errorf("rule: operator name is empty")
+	}
+	if fn == nil {
+		return fmt.Errorf("rule: operator %s has no function", name)
+	}
+	if _, ok := builtinOperators[name]; ok {
+		return fmt.Errorf("rule: operator %s is built in", name)
+	}
+	registry.Lock()
+	registry.operators[name] = fn
+	registry.Unlock()
+	return nil
+}
+
+// LookupOperator returns the function registered for name.
+func LookupOperator(name ConditionOperator) (OperatorFunc, bool) {
+	registry.RLock()
+	fn, ok := registry.operators[name]
+	registry.RUnlock()
+	return fn, ok
+}
+
+// Operators lists every operator a condition can use, sorted by name.
+func Operators() []ConditionOperator {
+	registry.RLock()
+	names := make([]ConditionOperator, 0, len(builtinOperators)+len(registry.operators))
+	for name := range registry.operators {
+		names = append(names, name)
+	}
+	registry.RUnlock()
+	for name := range builtinOperators {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func (condition *Condition) checkCustom(val any) bool {
+	fn, ok := LookupOperator(condition.Operator)
+	if !ok {
+		return false
+	}
+	result, err := fn(val, condition.Value)
+	return err == nil && result
+}
@@ -0,0 +1,72 @@
+package rule_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/pkg/rule"
+)
+
+func TestRegisteredOperators(t *testing.T) {
+	data := map[string]any{
+		"email":    "John.Doe@Example.com",
+		"joined":   "2024-03-01",
+		"seen":     time.Now().Add(-36 * time.Hour).Format(time.RFC3339),
+		"location": map[string]any{"lat": 27.7172, "lng": 85.3240},
+		"version":  "v1.10.0-rc.1",
+	}
+	tests := []struct {
+		condition *rule.Condition
+		want      bool
+	}{
+		{rule.NewCondition("email", rule.Matches, `^[^@]+@example\.com$`), false},
+		{rule.NewCondition("email", rule.Matches, `(?i)^[^@]+@example\.com$`), true},
+		{rule.NewCondition("email", rule.Matches, `(`), false},
+		{rule.NewCondition("joined", rule.Before, "2024-04-01"), true},
+		{rule.NewCondition("joined", rule.After, "03/02/2024"), false},
+		{rule.NewCondition("seen", rule.WithinDays, 2), true},
+		{rule.NewCondition("seen", rule.WithinDays, 1), false},
+		{rule.NewCondition("location", rule.GeoWithin, map[string]any{"lat": 27.7, "lng": 85.3, "radius_km": 5}), true},
+		{rule.NewCondition("location", rule.GeoWithin, []any{[]any{27, 85}, []any{28, 85}, []any{28, 86}, []any{27, 86}}), true},
+		{rule.NewCondition("location", rule.GeoWithin, []any{[]any{26, 80}, []any{26, 81}, []any{27, 81}}), false},
+		{rule.NewCondition("version", rule.SemverGte, "1.9.9"), true},
+		{rule.NewCondition("version", rule.SemverGte, "1.10.0"), false},
+		{rule.NewCondition("version", rule.SemverGte, "1.10.0-beta.2"), true},
+		{rule.NewCondition("email", rule.ContainsFold, "doe@"), true},
+		{rule.NewCondition("email", rule.StartsWithFold, "JOHN"), true},
+		{rule.NewCondition("email", rule.EndsWithFold, ".ORG"), false},
+		{rule.NewCondition("email", rule.NotContainsFold, "jane"), true},
+		{rule.NewCondition("email", rule.InFold, []string{"john.doe@example.com"}), true},
+	}
+	for _, tt := range tests {
+		r := rule.New()
+		r.And(tt.condition)
+		if got := r.Validate(data); got != tt.want {
+			t.Errorf("%s %s %v: got %v, want %v", tt.condition.Field, tt.condition.Operator, tt.condition.Value, got, tt.want)
+		}
+		if got := rule.MustCompile(r).Validate(data); got != tt.want {
+			t.Errorf("compiled %s %s %v: got %v, want %v", tt.condition.Field, tt.condition.Operator, tt.condition.Value, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterOperatorFromJSON(t *testing.T) {
+	if err := rule.RegisterOperator(rule.EQ, func(any, any) (bool, error) { return true, nil }); err == nil {
+		t.Fatal("expected built-in operator to be protected")
+	}
+	err := rule.RegisterOperator("even", func(fieldVal, _ any) (bool, error) {
+		f, ok := fieldVal.(float64)
+		return ok && int(f)%2 == 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r rule.Rule
+	if err := json.Unmarshal([]byte(`{"conditions":[{"operator":"AND","condition":[{"field":"n","operator":"even"}]}]}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Validate(map[string]any{"n": 4}) || r.Validate(map[string]any{"n": 3}) {
+		t.Error("custom operator decoded from JSON was not applied")
+	}
+}
@@ -81,19 +81,31 @@ type Response struct {
 	Result    bool
 }
 
+// conditionCheck evaluates a single condition; compiled rules swap in their
+// precomputed accessors while sharing the AND/OR wiring below.
+type conditionCheck func(condition *Condition, d Data) bool
+
+func validateCondition(condition *Condition, d Data) bool {
+	return condition.Validate(d)
+}
+
 func (node *Conditions) Apply(d Data) Response {
+	return node.apply(d, validateCondition)
+}
+
+func (node *Conditions) apply(d Data, check conditionCheck) Response {
 	var nodeResult bool
 	switch node.Operator {
 	case AND:
 		nodeResult = true
 		for _, condition := range node.Condition {
-			nodeResult = nodeResult && condition.Validate(d)
+			nodeResult = nodeResult && check(condition, d)
 		}
 		break
 	case OR:
 		nodeResult = false
 		for _, condition := range node.Condition {
-			nodeResult = nodeResult || condition.Validate(d)
+			nodeResult = nodeResult || check(condition, d)
 		}
 		break
 	}
@@ -118,8 +130,12 @@ type Join struct {
 }
 
 func (join *Join) Apply(d Data) Response {
-	leftResponse := join.Left.Apply(d)
-	rightResponse := join.Right.Apply(d)
+	return join.apply(d, validateCondition)
+}
+
+func (join *Join) apply(d Data, check conditionCheck) Response {
+	leftResponse := join.Left.apply(d, check)
+	rightResponse := join.Right.apply(d, check)
 	var joinResult bool
 	switch join.Operator {
 	case AND:
@@ -147,8 +163,12 @@ type Group struct {
 }
 
 func (group *Group) Apply(d Data) Response {
-	resultLeft := group.Left.Apply(d)
-	resultRight := group.Right.Apply(d)
+	return group.apply(d, validateCondition)
+}
+
+func (group *Group) apply(d Data, check conditionCheck) Response {
+	resultLeft := group.Left.apply(d, check)
+	resultRight := group.Right.apply(d, check)
 	var groupResult bool
 	switch group.Operator {
 	case AND:
@@ -186,6 +206,7 @@ func (e *ErrorResponse) Error() string {
 
 type Rule struct {
 	successHandler CallbackFn
+	actions        []Action
 	ID             string        `json:"id,omitempty"`
 	ErrorMsg       string        `json:"error_msg"`
 	ErrorAction    string        `json:"error_action"`
@@ -248,12 +269,34 @@ func (r *Rule) Join(left *Group, operator JoinOperator, right *Group) *Join {
 	return join
 }
 
+// conditionNodes lists every condition node of the rule, including the
+// ones referenced by groups and joins.
+func (r *Rule) conditionNodes() []*Conditions {
+	nodes := append([]*Conditions{}, r.Conditions...)
+	for _, group := range r.Groups {
+		if group != nil {
+			nodes = append(nodes, group.Left, group.Right)
+		}
+	}
+	for _, join := range r.Joins {
+		if join == nil {
+			continue
+		}
+		for _, group := range []*Group{join.Left, join.Right} {
+			if group != nil {
+				nodes = append(nodes, group.Left, group.Right)
+			}
+		}
+	}
+	return nodes
+}
+
 func (r *Rule) OnSuccess(handler CallbackFn) {
 	r.successHandler = handler
 }
 
-func (r *Rule) apply(d Data) Data {
-	result := r.Validate(d)
+func (r *Rule) apply(d Data, check conditionCheck) Data {
+	result := r.validate(d, check)
 	if !result {
 		return nil
 	}
@@ -261,6 +304,10 @@ func (r *Rule) apply(d Data) Data {
 }
 
 func (r *Rule) Validate(d Data) bool {
+	return r.validate(d, validateCondition)
+}
+
+func (r *Rule) validate(d Data, check conditionCheck) bool {
 	var result, n, g, j bool
 	for i, node := range r.Conditions {
 		if len(node.Condition) == 0 {
@@ -271,7 +318,7 @@ func (r *Rule) Validate(d Data) bool {
 		} else if i == 0 && node.Operator == OR {
 			n = false
 		}
-		response := node.Apply(d)
+		response := node.apply(d, check)
 		switch node.Operator {
 		case AND:
 			n = n && response.Result
@@ -290,7 +337,7 @@ func (r *Rule) Validate(d Data) bool {
 		} else if i == 0 && group.Operator == OR {
 			g = false
 		}
-		response := group.Apply(d)
+		response := group.apply(d, check)
 		switch group.Operator {
 		case AND:
 			g = g && response.Result
@@ -309,7 +356,7 @@ func (r *Rule) Validate(d Data) bool {
 		} else if i == 0 && join.Operator == OR {
 			j = false
 		}
-		response := join.Apply(d)
+		response := join.apply(d, check)
 		switch join.Operator {
 		case AND:
 			j = j && response.Result
@@ -326,6 +373,13 @@ func (r *Rule) Validate(d Data) bool {
 }
 
 func (r *Rule) Apply(d Data, callback ...CallbackFn) (any, error) {
+	return r.applyWith(validateCondition, true, d, callback...)
+}
+
+// applyWith runs the rule over a record or a list of records. Records are
+// copied before validation only when copyData is set, since lookup filters
+// may rewrite them in place.
+func (r *Rule) applyWith(check conditionCheck, copyData bool, d Data, callback ...CallbackFn) (any, error) {
 	defaultCallbackFn := func(data Data) any {
 		return data
 	}
@@ -335,8 +389,11 @@ func (r *Rule) Apply(d Data, callback ...CallbackFn) (any, error) {
 	}
 	switch d := d.(type) {
 	case map[string]any:
-		dt := maputil.CopyMap(d)
-		rt := r.apply(dt)
+		dt := d
+		if copyData {
+			dt = maputil.CopyMap(d)
+		}
+		rt := r.apply(dt, check)
 		if rt == nil && r.ErrorAction != "" {
 			errorMsg, _ := jet.Parse(r.ErrorMsg, d)
 			return nil, &ErrorResponse{
@@ -351,8 +408,11 @@ func (r *Rule) Apply(d Data, callback ...CallbackFn) (any, error) {
 	case []map[string]any:
 		var data []map[string]any
 		for _, line := range d {
-			l := maputil.CopyMap(line)
-			result := r.apply(l)
+			l := line
+			if copyData {
+				l = maputil.CopyMap(line)
+			}
+			result := r.apply(l, check)
 			if result != nil {
 				data = append(data, line)
 			}
@@ -373,8 +433,11 @@ func (r *Rule) Apply(d Data, callback ...CallbackFn) (any, error) {
 		for _, line := range d {
 			switch line := line.(type) {
 			case map[string]any:
-				l := maputil.CopyMap(line)
-				result := r.apply(l)
+				l := line
+				if copyData {
+					l = maputil.CopyMap(line)
+				}
+				result := r.apply(l, check)
 				if result != nil {
 					data = append(data, line)
 				}
@@ -1,6 +1,7 @@
 package rule
 
 import (
+	"fmt"
 	"sort"
 	"sync"
 )
@@ -12,6 +13,21 @@ const (
 	LowestPriority  Priority = 0
 )
 
+// MatchPolicy decides how many matching rules Apply returns.
+type MatchPolicy int
+
+const (
+	// MatchFirst returns the response of the first matching rule in
+	// priority order.
+	MatchFirst MatchPolicy = iota
+	// MatchAll returns the responses of every matching rule, in priority
+	// order, as a []any.
+	MatchAll
+	// MatchUnique returns the response of the only matching rule, and
+	// ErrNotUnique when more than one matches.
+	MatchUnique
+)
+
 type PriorityRule struct {
 	Rule     *Rule
 	Priority int
@@ -20,6 +36,11 @@ type PriorityRule struct {
 type Config struct {
 	Rules    []*PriorityRule
 	Priority Priority
+	// Match configures Apply.
+	Match MatchPolicy
+	// Strategy and MaxCycles configure Run.
+	Strategy  ConflictStrategy
+	MaxCycles int
 }
 
 type GroupRule struct {
@@ -66,13 +87,46 @@ func (r *GroupRule) Apply(data Data, fn ...CallbackFn) (any, error) {
 }
 
 func (r *GroupRule) apply(sortedRules []*Rule, data Data, fn ...CallbackFn) (any, error) {
+	var responses []any
+	var first *Rule
 	for _, rule := range sortedRules {
 		response, err := rule.Apply(data, fn...)
-		if response != nil {
+		if noMatch(response) {
+			continue
+		}
+		if r.config.Match == MatchFirst || err != nil {
 			return response, err
 		}
+		if r.config.Match == MatchUnique {
+			if first != nil {
+				return nil, fmt.Errorf("%w: %s and %s", ErrNotUnique, first.ID, rule.ID)
+			}
+			first = rule
+		}
+		responses = append(responses, response)
+	}
+	switch {
+	case len(responses) == 0:
+		return nil, nil
+	case r.config.Match == MatchUnique:
+		return responses[0], nil
+	}
+	return responses, nil
+}
+
+// noMatch reports if a rule response means the rule matched nothing. Rules
+// applied to a list respond with the matching records, which is empty when
+// none matched.
+func noMatch(response any) bool {
+	switch response := response.(type) {
+	case nil:
+		return true
+	case []map[string]any:
+		return len(response) == 0
+	case []any:
+		return len(response) == 0
 	}
-	return nil, nil
+	return false
 }
 
 func (r *GroupRule) SortByPriority(direction ...string) []*Rule {